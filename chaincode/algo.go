@@ -23,7 +23,7 @@ import (
 func (algo *Algo) Set(db LedgerDB, inp inputAlgo) (algoKey string, err error) {
 	algoKey = inp.Hash
 	// find associated owner
-	owner, err := GetTxCreator(db.cc)
+	owner, err := GetTxCreator(db)
 	if err != nil {
 		return
 	}
@@ -52,7 +52,7 @@ func (algo *Algo) Set(db LedgerDB, inp inputAlgo) (algoKey string, err error) {
 // If the key exists, it will override the value with the new one
 func registerAlgo(db LedgerDB, args []string) (resp map[string]string, err error) {
 	inp := inputAlgo{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -78,7 +78,7 @@ func registerAlgo(db LedgerDB, args []string) (resp map[string]string, err error
 // queryAlgo returns an algo of the ledger given its key
 func queryAlgo(db LedgerDB, args []string) (out outputAlgo, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -91,12 +91,17 @@ func queryAlgo(db LedgerDB, args []string) (out outputAlgo, err error) {
 }
 
 // queryAlgos returns all algos of the ledger
+// The storage address of algos the requester cannot process is redacted
 func queryAlgos(db LedgerDB, args []string) (outAlgos []outputAlgo, err error) {
 	outAlgos = []outputAlgo{}
 	if len(args) != 0 {
 		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
 		return
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
 	elementsKeys, err := db.GetIndexKeys("algo~owner~key", []string{"algo"})
 	if err != nil {
 		return
@@ -108,6 +113,9 @@ func queryAlgos(db LedgerDB, args []string) (outAlgos []outputAlgo, err error) {
 		}
 		var out outputAlgo
 		out.Fill(key, algo)
+		if !algo.Permissions.CanProcess(algo.Owner, requester) {
+			out.redact()
+		}
 		outAlgos = append(outAlgos, out)
 	}
 	return
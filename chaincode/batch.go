@@ -0,0 +1,48 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+)
+
+// batch calls in order the smart contracts of the entries on the same ledger.
+// The first failing entry fails the whole transaction, so that either all the entries
+// are committed or none of them. It returns the result of each entry.
+func batch(db LedgerDB, args []string) (results []interface{}, err error) {
+	inp := inputBatch{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	for i, entry := range inp.Entries {
+		if entry.Fn == "batch" {
+			return nil, errors.BadRequest("batch entry %d: a batch cannot contain another batch", i)
+		}
+	}
+
+	results = []interface{}{}
+	for i, entry := range inp.Entries {
+		// Updated assets are reported with the smart contract of the entry
+		db.setEventFunction(entry.Fn)
+		result, err := dispatch(db, entry.Fn, entry.Args)
+		if err != nil {
+			e := errors.Wrap(err)
+			return nil, errors.E(e.Kind, e, "batch entry %d (%s) failed:", i, entry.Fn)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchEntry(args [][]byte) inputBatchEntry {
+	entry := inputBatchEntry{Fn: string(args[0])}
+	for _, arg := range args[1:] {
+		entry.Args = append(entry.Args, string(arg))
+	}
+	return entry
+}
+
+func TestBatch(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+
+	inpDataManager := inputDataManager{}
+	inpTestDataSample := inputDataSample{
+		Hashes:          []string{testDataSampleHash1, testDataSampleHash2},
+		DataManagerKeys: []string{dataManagerOpenerHash},
+		TestOnly:        "true",
+	}
+	inpObjective := inputObjective{}
+	inpTrainDataSample := inputDataSample{}
+	inpAlgo := inputAlgo{}
+	inpTraintuple := inputTraintuple{}
+	inp := inputBatch{Entries: []inputBatchEntry{
+		batchEntry(inpDataManager.createDefault()),
+		batchEntry(inpTestDataSample.createDefault()),
+		batchEntry(inpObjective.createDefault()),
+		batchEntry(inpTrainDataSample.createDefault()),
+		batchEntry(inpAlgo.createDefault()),
+		batchEntry(inpTraintuple.createDefault()),
+		batchEntry(methodToByte("queryAlgos")),
+	}}
+	resp := mockStub.MockInvoke("42", methodAndAssetToByte("batch", inp))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	results := []json.RawMessage{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &results))
+	require.Len(t, results, len(inp.Entries))
+	assert.JSONEq(t, `{"key": "`+algoHash+`"}`, string(results[4]))
+	assert.JSONEq(t, `{"key": "`+traintupleKey+`"}`, string(results[5]))
+	algos := []outputAlgo{}
+	require.NoError(t, json.Unmarshal(results[6], &algos))
+	assert.Len(t, algos, 1)
+
+	// All the updates are sent in a single event
+	event := getLastEvent(t, mockStub)
+	assert.Len(t, event.Assets.DataManagers, 1)
+	assert.Len(t, event.Assets.DataSamples, 4)
+	assert.Equal(t, []EventAsset{{AssetType: "algo", Key: algoHash, Function: "registerAlgo"}}, event.Assets.Algos)
+	assert.Equal(t, []EventAsset{{AssetType: "traintuple", Key: traintupleKey, NewStatus: StatusTodo, Function: "createTraintuple"}}, event.Assets.Traintuples)
+	require.Len(t, event.TuplesEvent.Traintuples, 1)
+	assert.Equal(t, traintupleKey, event.TuplesEvent.Traintuples[0].Key)
+}
+
+func TestBatchFailure(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	inpAlgo := inputAlgo{}
+	testCases := []struct {
+		desc    string
+		entries []inputBatchEntry
+		status  int32
+		message string
+	}{
+		{
+			desc:    "failing entry",
+			entries: []inputBatchEntry{batchEntry(methodToByte("queryAlgos")), batchEntry(inpAlgo.createDefault())},
+			status:  409,
+			message: "batch entry 1 (registerAlgo) failed:",
+		},
+		{
+			desc:    "nested batch",
+			entries: []inputBatchEntry{{Fn: "batch", Args: []string{"{}"}}},
+			status:  400,
+			message: "a batch cannot contain another batch",
+		},
+		{
+			desc:    "empty batch",
+			entries: []inputBatchEntry{},
+			status:  400,
+			message: "inputs validation failed",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			resp := mockStub.MockInvoke("42", methodAndAssetToByte("batch", inputBatch{Entries: tC.entries}))
+			assert.EqualValues(t, tC.status, resp.Status, resp.Message)
+			assert.Contains(t, resp.Message, tC.message)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+#!/bin/bash
+# Copyright 2018 Owkin, inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Generates the private data collections of the chaincode for the MSP IDs of the
+# organisations of the channel, each collection being readable by its members only:
+#
+#   ./collections_config.sh Org1MSP Org2MSP > collections_config.json
+#
+# A collection can be restricted to some of the organisations with collection=MSPID,...:
+#
+#   ./collections_config.sh Org1MSP Org2MSP modelAddresses=Org1MSP > collections_config.json
+set -e
+
+collections="tupleLogs modelAddresses openerAddresses predictionAddresses privatePerfs"
+
+orgs=()
+declare -A members
+for arg in "$@"; do
+    if [[ "$arg" == *=* ]]; then
+        members[${arg%%=*}]=${arg#*=}
+    else
+        orgs+=("$arg")
+    fi
+done
+if [ ${#orgs[@]} -eq 0 ]; then
+    echo "usage: $0 MSPID... [collection=MSPID,...]..." >&2
+    exit 1
+fi
+
+# policy returns the signature policy of a comma-separated list of MSP IDs
+policy() {
+    local principals=()
+    IFS=, read -ra ids <<< "$1"
+    for id in "${ids[@]}"; do
+        principals+=("'$id.member'")
+    done
+    local IFS=,
+    echo "OR(${principals[*]})"
+}
+
+all=$(IFS=,; echo "${orgs[*]}")
+separator=""
+echo "["
+for collection in $collections; do
+    ids=${members[$collection]:-$all}
+    printf '%s  {\n' "$separator"
+    printf '    "name": "%s",\n' "$collection"
+    printf '    "policy": "%s",\n' "$(policy "$ids")"
+    printf '    "requiredPeerCount": 0,\n'
+    printf '    "maxPeerCount": 3,\n'
+    printf '    "blockToLive": 0,\n'
+    printf '    "memberOnlyRead": true\n'
+    printf '  }'
+    separator=$',\n'
+done
+printf '\n]\n'
@@ -23,7 +23,7 @@ import (
 // For now, ok for everything. Later returns if the requester has permission to see it
 func queryFilter(db LedgerDB, args []string) (elements interface{}, err error) {
 	inp := inputQueryFilter{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -32,7 +32,9 @@ func queryFilter(db LedgerDB, args []string) (elements interface{}, err error) {
 		"traintuple~worker~status",
 		"testtuple~worker~status",
 		"testtuple~tag",
-		"traintuple~tag"}
+		"traintuple~tag",
+		"predicttuple~worker~status",
+		"predicttuple~tag"}
 	if !stringInSlice(inp.IndexName, validIndexNames) {
 		err = fmt.Errorf("invalid indexName filter query: %s", inp.IndexName)
 		return
@@ -51,6 +53,8 @@ func queryFilter(db LedgerDB, args []string) (elements interface{}, err error) {
 		elements, err = getOutputTesttuples(db, filteredKeys)
 	case "traintuple~worker~status~key", "traintuple~tag~key":
 		elements, err = getOutputTraintuples(db, filteredKeys)
+	case "predicttuple~worker~status~key", "predicttuple~tag~key":
+		elements, err = getOutputPredicttuples(db, filteredKeys)
 	}
 	return
 }
@@ -0,0 +1,197 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// configKey is the key of the configuration of the channel in the ledger
+const configKey = "config"
+
+// Config holds the limits of the channel, it is seeded by Init and can then
+// only be updated by the admin nodes
+type Config struct {
+	// MaxLogLength is the maximum length of the logs of the tuples
+	MaxLogLength int `validate:"gt=0" json:"maxLogLength"`
+	// MaxSamplesPerTuple is the maximum number of data samples of a tuple, 0 for no limit
+	MaxSamplesPerTuple int `validate:"gte=0" json:"maxSamplesPerTuple"`
+	// StorageURLSchemes are the allowed schemes of the storage addresses, any if empty
+	StorageURLSchemes []string `validate:"omitempty,dive,required" json:"storageURLSchemes"`
+	// DataManagerTypes are the allowed types of data managers, any if empty
+	DataManagerTypes []string `validate:"omitempty,dive,required" json:"dataManagerTypes"`
+	// Admins are the nodes allowed to update the configuration
+	Admins []string `validate:"omitempty,dive,required" json:"admins"`
+	// MaxAttempts is the number of times a tuple can be started before a reclaim makes it fail, 0 for no limit
+	MaxAttempts int `validate:"gte=0" json:"maxAttempts"`
+}
+
+// defaultConfig is the configuration used until one is stored in the ledger
+var defaultConfig = Config{
+	MaxLogLength:       200,
+	MaxSamplesPerTuple: 0,
+	StorageURLSchemes:  []string{},
+	DataManagerTypes:   []string{},
+	Admins:             []string{},
+	MaxAttempts:        3,
+}
+
+// GetConfig returns the configuration stored in the ledger, the default one if there is none
+func (db *ledgerDB) GetConfig() (Config, error) {
+	exists, err := db.KeyExists(configKey)
+	if err != nil || !exists {
+		return defaultConfig, err
+	}
+	config := Config{}
+	err = db.Get(configKey, &config)
+	return config, err
+}
+
+// seedConfig stores the configuration passed to Init. The configuration is only stored if
+// there is none yet, an upgrade can only set the admins of a configuration stored without any.
+func seedConfig(db LedgerDB, args []string) error {
+	exists, err := db.KeyExists(configKey)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return seedConfigAdmins(db, args)
+	}
+	config := defaultConfig
+	if len(args) > 0 {
+		if err := parseConfig(args, &config); err != nil {
+			return err
+		}
+	}
+	if len(config.Admins) == 0 {
+		logger.Warning("the configuration has no admins, they can be set by the next upgrade")
+	}
+	return db.Put(configKey, config)
+}
+
+// seedConfigAdmins sets the admins passed to an upgrade if the stored configuration has none,
+// so that it can be updated by them. The other values passed to Init are ignored.
+func seedConfigAdmins(db LedgerDB, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	config, err := db.GetConfig()
+	if err != nil {
+		return err
+	}
+	upgrade := defaultConfig
+	if err := parseConfig(args, &upgrade); err != nil {
+		return err
+	}
+	if len(config.Admins) > 0 || len(upgrade.Admins) == 0 {
+		logger.Warning("the configuration passed to Init is ignored, use updateConfig instead")
+		return nil
+	}
+	config.Admins = upgrade.Admins
+	return db.Put(configKey, config)
+}
+
+// parseConfig overrides the values of the configuration with the ones of the json arg
+func parseConfig(args []string, config *Config) error {
+	if len(args) != 1 {
+		return errors.BadRequest(errors.CodeInvalidInput, "arguments should only contains 1 json string, received: %s", args)
+	}
+	if err := json.Unmarshal([]byte(args[0]), config); err != nil {
+		return errors.BadRequest(err, errors.CodeInvalidJSON, "problem when reading json arg: %s, error is:", args[0])
+	}
+	if err := validate.Struct(config); err != nil {
+		return errors.BadRequest(err, errors.CodeInvalidInput, "config validation failed: %s, error is:", args[0])
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------
+// Validation of the inputs depending on the configuration
+// -------------------------------------------------------------------------------------------
+
+// configContextKey is the key of the configuration in the validation context
+type configContextKey struct{}
+
+// getContextConfig returns the configuration of the validation context, the default one if none
+func getContextConfig(ctx context.Context) Config {
+	if config, ok := ctx.Value(configContextKey{}).(Config); ok {
+		return config
+	}
+	return defaultConfig
+}
+
+// configValidations are the validation rules depending on the configuration
+var configValidations = map[string]validator.FuncCtx{
+	// storage_url checks that the scheme of an url is allowed
+	"storage_url": func(ctx context.Context, fl validator.FieldLevel) bool {
+		schemes := getContextConfig(ctx).StorageURLSchemes
+		if len(schemes) == 0 {
+			return true
+		}
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && stringInSlice(u.Scheme, schemes)
+	},
+	// data_manager_type checks that a data manager type is allowed
+	"data_manager_type": func(ctx context.Context, fl validator.FieldLevel) bool {
+		types := getContextConfig(ctx).DataManagerTypes
+		return len(types) == 0 || stringInSlice(fl.Field().String(), types)
+	},
+	// max_samples checks the number of data samples of a tuple
+	"max_samples": func(ctx context.Context, fl validator.FieldLevel) bool {
+		maxSamples := getContextConfig(ctx).MaxSamplesPerTuple
+		return maxSamples == 0 || fl.Field().Len() <= maxSamples
+	},
+	// max_log checks the length of a tuple log
+	"max_log": func(ctx context.Context, fl validator.FieldLevel) bool {
+		return fl.Field().Len() <= getContextConfig(ctx).MaxLogLength
+	},
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the configuration
+// -------------------------------------------------------------------------------------------
+
+// queryConfig returns the configuration of the channel
+func queryConfig(db LedgerDB, args []string) (Config, error) {
+	if len(args) != 0 {
+		return Config{}, errors.BadRequest("incorrect number of arguments, expecting nothing")
+	}
+	return db.GetConfig()
+}
+
+// updateConfig overrides the configuration with the values of the input,
+// only the admin nodes can update it
+func updateConfig(db LedgerDB, args []string) (Config, error) {
+	config, err := db.GetConfig()
+	if err != nil {
+		return config, err
+	}
+	txCreator, err := GetTxCreator(db)
+	if err != nil {
+		return config, err
+	}
+	if !stringInSlice(txCreator, config.Admins) {
+		return config, errors.Forbidden(errors.CodePermissionDeniedConfig, "%s is not allowed to update the configuration", txCreator)
+	}
+	if err := parseConfig(args, &config); err != nil {
+		return config, err
+	}
+	return config, db.Put(configKey, config)
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+
+	// Without configuration, the default one is used
+	resp := mockStub.MockInvoke("42", methodToByte("queryConfig"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	config := Config{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, defaultConfig, config)
+
+	// The configuration is seeded by Init
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), []byte(`{"admins": ["` + worker + `"], "maxLogLength": 10}`)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", methodToByte("queryConfig"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, []string{worker}, config.Admins)
+	assert.Equal(t, 10, config.MaxLogLength)
+
+	// but cannot be changed by an upgrade
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), []byte(`{"admins": ["OtherOrg"]}`)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", methodToByte("queryConfig"))
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, []string{worker}, config.Admins)
+
+	// Only admins can update it
+	update := [][]byte{[]byte("updateConfig"), []byte(`{"maxLogLength": 100, "maxSamplesPerTuple": 1, "storageURLSchemes": ["https"], "dataManagerTypes": ["images"]}`)}
+	mockStub.Creator = "OtherOrg"
+	resp = mockStub.MockInvoke("42", update)
+	assert.EqualValues(t, 403, resp.Status, resp.Message)
+	mockStub.Creator = ""
+	resp = mockStub.MockInvoke("42", update)
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, Config{
+		MaxLogLength:       100,
+		MaxSamplesPerTuple: 1,
+		StorageURLSchemes:  []string{"https"},
+		DataManagerTypes:   []string{"images"},
+		Admins:             []string{worker},
+		MaxAttempts:        3,
+	}, config)
+
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("updateConfig"), []byte(`{"maxLogLength": 0}`)})
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+}
+
+func TestConfigAdminsUpgrade(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	update := [][]byte{[]byte("updateConfig"), []byte(`{"maxLogLength": 100}`)}
+
+	// A configuration seeded without admins cannot be updated
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", update)
+	assert.EqualValues(t, 403, resp.Status, resp.Message)
+
+	// until an upgrade sets its admins, the other values being ignored
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), []byte(`{"admins": ["` + worker + `"], "maxLogLength": 10}`)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", methodToByte("queryConfig"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	config := Config{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, []string{worker}, config.Admins)
+	assert.Equal(t, defaultConfig.MaxLogLength, config.MaxLogLength)
+	resp = mockStub.MockInvoke("42", update)
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// The admins cannot be changed by the next upgrades
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), []byte(`{"admins": ["OtherOrg"]}`)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", methodToByte("queryConfig"))
+	require.NoError(t, json.Unmarshal(resp.Payload, &config))
+	assert.Equal(t, []string{worker}, config.Admins)
+}
+
+func TestConfigValidation(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init"), []byte(`{"maxLogLength": 5, "maxSamplesPerTuple": 1, "storageURLSchemes": ["https"], "dataManagerTypes": ["images"]}`)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	inpDataManager := inputDataManager{Type: "csv"}
+	resp = mockStub.MockInvoke("42", inpDataManager.createDefault())
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "type must be an allowed data manager type")
+
+	inpAlgo := inputAlgo{StorageAddress: "ftp://toto/algo"}
+	resp = mockStub.MockInvoke("42", inpAlgo.createDefault())
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "storageAddress must be a valid URL with an allowed scheme")
+
+	registerItem(t, *mockStub, "algo")
+	inpTraintuple := inputTraintuple{}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "dataSampleKeys contains too many data samples")
+
+	inpTraintuple = inputTraintuple{DataSampleKeys: []string{trainDataSampleHash1}}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	res := map[string]string{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &res))
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("logStartTrain", inputHash{res["key"]}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpFail := inputLogFailTrain{}
+	inpFail.Key = res["key"]
+	inpFail.Log = "too long log"
+	resp = mockStub.MockInvoke("42", inpFail.createDefault())
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "log is too long")
+}
@@ -28,13 +28,17 @@ func (dataManager *DataManager) Set(db LedgerDB, inp inputDataManager) (string,
 	dataManager.ObjectiveKey = inp.ObjectiveKey
 	dataManager.AssetType = DataManagerType
 	dataManager.Name = inp.Name
-	dataManager.OpenerStorageAddress = inp.OpenerStorageAddress
+	openerStorageAddressHash, err := db.PutPrivateField(openerAddressesCollection, dataManagerKey, inp.OpenerStorageAddress)
+	if err != nil {
+		return "", "", err
+	}
+	dataManager.OpenerStorageAddressHash = openerStorageAddressHash
 	dataManager.Type = inp.Type
 	dataManager.Description = &HashDress{
 		Hash:           inp.DescriptionHash,
 		StorageAddress: inp.DescriptionStorageAddress,
 	}
-	owner, err := GetTxCreator(db.cc)
+	owner, err := GetTxCreator(db)
 	if err != nil {
 		return "", "", err
 	}
@@ -64,7 +68,7 @@ func setDataSample(db LedgerDB, inp inputDataSample) (dataSampleHashes []string,
 	}
 
 	// get transaction owner
-	owner, err := GetTxCreator(db.cc)
+	owner, err := GetTxCreator(db)
 	if err != nil {
 		return
 	}
@@ -111,7 +115,7 @@ func validateUpdateDataSample(db LedgerDB, inp inputUpdateDataSample) (dataSampl
 // registerDataManager stores a new dataManager in the ledger.
 func registerDataManager(db LedgerDB, args []string) (resp map[string]string, err error) {
 	inp := inputDataManager{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -150,7 +154,7 @@ func registerDataManager(db LedgerDB, args []string) (resp map[string]string, er
 func registerDataSample(db LedgerDB, args []string) (dataSampleKeys map[string][]string, err error) {
 	// convert input strings args to input struct inputDataSample
 	inp := inputDataSample{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -184,7 +188,7 @@ func registerDataSample(db LedgerDB, args []string) (dataSampleKeys map[string][
 // updateDataSample associates one or more dataManagerKeys to one or more dataSample
 func updateDataSample(db LedgerDB, args []string) (resp map[string]string, err error) {
 	inp := inputUpdateDataSample{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -234,7 +238,7 @@ func updateDataSample(db LedgerDB, args []string) (resp map[string]string, err e
 // updateDataManager associates a objectiveKey to an existing dataManager
 func updateDataManager(db LedgerDB, args []string) (resp map[string]string, err error) {
 	inp := inputUpdateDataManager{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -249,7 +253,7 @@ func updateDataManager(db LedgerDB, args []string) (resp map[string]string, err
 // queryDataManager returns dataManager and its key
 func queryDataManager(db LedgerDB, args []string) (out outputDataManager, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -262,10 +266,12 @@ func queryDataManager(db LedgerDB, args []string) (out outputDataManager, err er
 		return
 	}
 	out.Fill(inp.Key, dataManager)
+	err = out.fillPrivateFields(db, dataManager)
 	return
 }
 
 // queryDataManagers returns all DataManagers of the ledger
+// The opener storage address of dataManagers the requester cannot process is redacted
 func queryDataManagers(db LedgerDB, args []string) ([]outputDataManager, error) {
 	var err error
 	outDataManagers := []outputDataManager{}
@@ -273,6 +279,10 @@ func queryDataManagers(db LedgerDB, args []string) ([]outputDataManager, error)
 		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
 		return outDataManagers, err
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outDataManagers, err
+	}
 	var indexName = "dataManager~owner~key"
 	elementsKeys, err := db.GetIndexKeys(indexName, []string{"dataManager"})
 	if err != nil {
@@ -285,6 +295,12 @@ func queryDataManagers(db LedgerDB, args []string) ([]outputDataManager, error)
 		}
 		var out outputDataManager
 		out.Fill(key, dataManager)
+		if err := out.fillPrivateFields(db, dataManager); err != nil {
+			return outDataManagers, err
+		}
+		if !dataManager.Permissions.CanProcess(dataManager.Owner, requester) {
+			out.redact()
+		}
 		outDataManagers = append(outDataManagers, out)
 	}
 	return outDataManagers, nil
@@ -294,7 +310,7 @@ func queryDataManagers(db LedgerDB, args []string) ([]outputDataManager, error)
 func queryDataset(db LedgerDB, args []string) (outputDataset, error) {
 	inp := inputHash{}
 	out := outputDataset{}
-	err := AssetFromJSON(args, &inp)
+	err := AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return out, err
 	}
@@ -317,7 +333,8 @@ func queryDataset(db LedgerDB, args []string) (outputDataset, error) {
 	}
 
 	out.Fill(inp.Key, dataManager, trainDataSampleKeys, testDataSampleKeys)
-	return out, nil
+	err = out.fillPrivateFields(db, dataManager)
+	return out, err
 }
 
 func queryDataSamples(db LedgerDB, args []string) ([]outputDataSample, error) {
@@ -353,7 +370,7 @@ func queryDataSamples(db LedgerDB, args []string) ([]outputDataSample, error) {
 // specified by their keys in a slice
 func checkDataManagerOwner(db LedgerDB, dataManagerKeys []string) error {
 	// get transaction requester
-	txCreator, err := GetTxCreator(db.cc)
+	txCreator, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
@@ -364,7 +381,7 @@ func checkDataManagerOwner(db LedgerDB, dataManagerKeys []string) error {
 		}
 		// check transaction requester is the dataManager owner
 		if txCreator != dataManager.Owner {
-			return errors.Forbidden("%s is not the owner of the dataManager %s", txCreator, dataManagerKey)
+			return errors.Forbidden(errors.CodePermissionDeniedDataManager, "%s is not the owner of the dataManager %s", txCreator, dataManagerKey)
 		}
 	}
 	return nil
@@ -372,12 +389,12 @@ func checkDataManagerOwner(db LedgerDB, dataManagerKeys []string) error {
 
 //  checkDataSampleOwner checks if the transaction requester is the owner of the dataSample
 func checkDataSampleOwner(db LedgerDB, dataSample DataSample) error {
-	txRequester, err := GetTxCreator(db.cc)
+	txRequester, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
 	if txRequester != dataSample.Owner {
-		return errors.Forbidden("%s is not the dataSample's owner", txRequester)
+		return errors.Forbidden(errors.CodePermissionDeniedDataSample, "%s is not the dataSample's owner", txRequester)
 	}
 	return nil
 }
@@ -393,8 +410,10 @@ func checkSameDataManager(db LedgerDB, dataManagerKey string, dataSampleKeys []s
 			return testOnly, trainOnly, err
 		}
 		if !stringInSlice(dataManagerKey, dataSample.DataManagerKeys) {
-			err = fmt.Errorf("dataSample do not belong to the same dataManager")
-			return testOnly, trainOnly, err
+			err = db.Check(errors.BadRequest("dataSample %s do not belong to the dataManager %s", dataSampleKey, dataManagerKey).WithKey(dataSampleKey))
+			if err != nil {
+				return testOnly, trainOnly, err
+			}
 		}
 		testOnly = testOnly && dataSample.TestOnly
 		trainOnly = trainOnly && !dataSample.TestOnly
@@ -27,9 +27,12 @@ func TestJsonInputsDataManager(t *testing.T) {
 
 	inpDataManager := inputDataManager{}
 	inpDataManager.createDefault()
-	payload, err := json.Marshal(inpDataManager)
+	// The opener storage address is passed in the transient map
+	inpArgs, private := splitPrivateInput(inpDataManager)
+	payload, err := json.Marshal(inpArgs)
 	assert.NoError(t, err)
 	args := [][]byte{[]byte("registerDataManager"), payload}
+	mockStub.Transient = map[string][]byte{privateInputTransientKey: private}
 	resp := mockStub.MockInvoke("42", args)
 	assert.EqualValues(t, 200, resp.Status)
 }
@@ -81,8 +84,9 @@ func TestDataManager(t *testing.T) {
 			Process: Permission{Public: true, AuthorizedIDs: []string{}},
 		},
 		Opener: HashDress{
-			Hash:           dataManagerKey,
-			StorageAddress: inpDataManager.OpenerStorageAddress,
+			Hash:               dataManagerKey,
+			StorageAddress:     inpDataManager.OpenerStorageAddress,
+			StorageAddressHash: hashPrivateField(inpDataManager.OpenerStorageAddress),
 		},
 		Type: inpDataManager.Type,
 	}
@@ -0,0 +1,229 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// bufferedContext discards the writes of a transaction outside of the storage: the
+// endorsement policies, the private data and the event
+type bufferedContext struct {
+	TransactionContext
+}
+
+// PutPrivateData discards the private data of a key, the db reading its own writes
+func (ctx *bufferedContext) PutPrivateData(collection string, key string, value []byte) error {
+	return nil
+}
+
+// DelPrivateData discards the deletion of the private data of a key
+func (ctx *bufferedContext) DelPrivateData(collection string, key string) error {
+	return nil
+}
+
+// SetStateValidationParameter discards the endorsement policy of a key
+func (ctx *bufferedContext) SetStateValidationParameter(key string, ep []byte) error {
+	return nil
+}
+
+// SetEvent discards the event of the transaction
+func (ctx *bufferedContext) SetEvent(name string, payload []byte) error {
+	return nil
+}
+
+// bufferedStorage keeps the writes of a transaction in memory instead of sending them
+// to the storage it reads. The pending index updates are merged in the index queries.
+type bufferedStorage struct {
+	base Storage
+	// states stores the buffered writes, a nil value being a deleted key
+	states map[string][]byte
+}
+
+// newBufferedStorage returns a storage buffering the writes to another one
+func newBufferedStorage(base Storage) *bufferedStorage {
+	return &bufferedStorage{
+		base:   base,
+		states: make(map[string][]byte),
+	}
+}
+
+// Get returns the buffered value of a key if it has been written, the stored one otherwise
+func (s *bufferedStorage) Get(key string, object interface{}) error {
+	buff, ok := s.states[key]
+	if !ok {
+		return s.base.Get(key, object)
+	}
+	if buff == nil {
+		return errors.NotFound()
+	}
+	return json.Unmarshal(buff, &object)
+}
+
+// KeyExists checks if a key has been written, or if it is stored otherwise
+func (s *bufferedStorage) KeyExists(key string) (bool, error) {
+	if buff, ok := s.states[key]; ok {
+		return buff != nil, nil
+	}
+	return s.base.KeyExists(key)
+}
+
+// Put buffers an object
+func (s *bufferedStorage) Put(key string, object interface{}) error {
+	buff, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	s.states[key] = buff
+	return nil
+}
+
+// Add buffers an object, it fails if the object already exists
+func (s *bufferedStorage) Add(key string, object interface{}) error {
+	return addObject(s, key, object)
+}
+
+// Delete buffers the deletion of an object
+func (s *bufferedStorage) Delete(key string) error {
+	s.states[key] = nil
+	return nil
+}
+
+// CreateIndex buffers a new composite key
+func (s *bufferedStorage) CreateIndex(index string, attributes []string) error {
+	s.states[createIndexKey(index, attributes)] = []byte{0x00}
+	return nil
+}
+
+// DeleteIndex buffers the deletion of a composite key
+func (s *bufferedStorage) DeleteIndex(index string, attributes []string) error {
+	return s.Delete(createIndexKey(index, attributes))
+}
+
+// UpdateIndex buffers the update of a composite key
+func (s *bufferedStorage) UpdateIndex(index string, oldAttributes []string, newAttributes []string) error {
+	return updateIndex(s, index, oldAttributes, newAttributes)
+}
+
+// compositeKeys returns the sorted composite keys matching the first attributes of an index,
+// the stored ones with the buffered updates
+func (s *bufferedStorage) compositeKeys(index string, attributes []string) ([]string, error) {
+	exists := map[string]bool{}
+	_, _, err := s.base.ForEachIndexKey(index, attributes, "", -1, func(attributes []string) error {
+		exists[createIndexKey(index, attributes)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	partialKey := createIndexKey(index, attributes)
+	for key, buff := range s.states {
+		if strings.HasPrefix(key, partialKey) {
+			exists[key] = buff != nil
+		}
+	}
+	compositeKeys := []string{}
+	for key, ok := range exists {
+		if ok {
+			compositeKeys = append(compositeKeys, key)
+		}
+	}
+	sort.Strings(compositeKeys)
+	return compositeKeys, nil
+}
+
+// GetIndexKeys returns the keys matching composite key values, including the buffered ones
+func (s *bufferedStorage) GetIndexKeys(index string, attributes []string) ([]string, error) {
+	compositeKeys, err := s.compositeKeys(index, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return indexKeys(index, compositeKeys)
+}
+
+// ForEachIndexKey calls fn on at most limit composite keys after the cursor, including the buffered ones
+func (s *bufferedStorage) ForEachIndexKey(index string, attributes []string, cursor string, limit int, fn func(attributes []string) error) (string, int, error) {
+	compositeKeys, err := s.compositeKeys(index, attributes)
+	if err != nil {
+		return "", 0, err
+	}
+	return forEachCompositeKey(compositeKeys, cursor, limit, fn)
+}
+
+// dryRunReport gathers the errors found during a dry run
+type dryRunReport struct {
+	errors []error
+}
+
+// Check returns the error unless the smart contract is dry run, in which case the error
+// is recorded so that the validation can go on and report all the errors at once.
+// It must only be used for errors which do not prevent further checks.
+func (db *ledgerDB) Check(err error) error {
+	if err == nil || db.dryRun == nil {
+		return err
+	}
+	db.dryRun.errors = append(db.dryRun.errors, err)
+	return nil
+}
+
+// IsDryRun checks if the writes of the smart contract are discarded
+func (db *ledgerDB) IsDryRun() bool {
+	return db.dryRun != nil
+}
+
+// newDryRunLedgerDB returns a db discarding the writes of the smart contract fn
+func newDryRunLedgerDB(db LedgerDB, fn string) *ledgerDB {
+	dryRunDB := newLedgerDB(&bufferedContext{TransactionContext: db}, newBufferedStorage(db))
+	dryRunDB.event = NewEvent(fn)
+	dryRunDB.dryRun = &dryRunReport{}
+	return dryRunDB
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contract to pre-flight the other smart contracts
+// -------------------------------------------------------------------------------------------
+
+// dryRun calls a smart contract without updating the ledger. It returns the result of the
+// smart contract, the assets it would have created or updated with their status and all
+// the errors found, the checks going on after the errors which do not prevent them.
+func dryRun(db LedgerDB, args []string) (out outputDryRun, err error) {
+	inp := inputDryRun{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	if inp.Fn == "dryRun" {
+		return out, errors.BadRequest("a dry run cannot contain another dry run")
+	}
+
+	dryRunDB := newDryRunLedgerDB(db, inp.Fn)
+	result, fnErr := dispatch(dryRunDB, inp.Fn, inp.Args)
+	locale := getLocale(db)
+	out.Errors = []map[string]interface{}{}
+	for _, e := range append(dryRunDB.dryRun.errors, fnErr) {
+		if e != nil {
+			out.Errors = append(out.Errors, formatError(e, locale))
+		}
+	}
+	out.Valid = len(out.Errors) == 0
+	if fnErr == nil {
+		out.Result = result
+	}
+	out.Assets = dryRunDB.event.Assets
+	return out, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dryRunResponse struct {
+	Valid  bool                     `json:"valid"`
+	Result json.RawMessage          `json:"result"`
+	Assets EventAssets              `json:"assets"`
+	Errors []map[string]interface{} `json:"errors"`
+}
+
+func invokeDryRun(t *testing.T, mockStub *MockStub, args [][]byte) dryRunResponse {
+	inp := inputDryRun{Fn: string(args[0])}
+	for _, arg := range args[1:] {
+		inp.Args = append(inp.Args, string(arg))
+	}
+	resp := mockStub.MockInvoke("42", methodAndAssetToByte("dryRun", inp))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out := dryRunResponse{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &out))
+	return out
+}
+
+func TestDryRun(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	inpTraintuple := inputTraintuple{}
+	out := invokeDryRun(t, mockStub, inpTraintuple.createDefault())
+	assert.True(t, out.Valid)
+	assert.Empty(t, out.Errors)
+	assert.JSONEq(t, `{"key": "`+traintupleKey+`"}`, string(out.Result))
+	assert.Equal(t, []EventAsset{{AssetType: "traintuple", Key: traintupleKey, NewStatus: StatusTodo, Function: "createTraintuple"}}, out.Assets.Traintuples)
+
+	// Nothing is written in the ledger and no event is sent
+	assert.Nil(t, mockStub.LastEvent)
+	resp := mockStub.MockInvoke("42", methodToByte("queryTraintuples"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.Equal(t, "[]", string(resp.Payload))
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("queryEventsSince", inputEventsSince{}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.Equal(t, "[]", string(resp.Payload))
+
+	// The traintuple can still be created
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out = invokeDryRun(t, mockStub, inpTraintuple.createDefault())
+	assert.False(t, out.Valid)
+	require.Len(t, out.Errors, 1)
+	assert.EqualValues(t, 409, out.Errors[0]["status"])
+}
+
+func TestDryRunAllErrors(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "trainDataset")
+	inpAlgo := inputAlgo{}
+	inpAlgo.createDefault()
+	inpAlgo.Permissions = inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}}
+	resp := mockStub.MockInvoke("42", methodAndAssetToByte("registerAlgo", inpAlgo))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// Another node uses the private algo, and test only data in the second traintuple
+	mockStub.Creator = "OtherOrg"
+	inpCP := defaultComputePlan
+	inpCP.Traintuples = []inputComputePlanTraintuple{inpCP.Traintuples[0], inpCP.Traintuples[1]}
+	inpCP.Traintuples[1].DataSampleKeys = []string{testDataSampleHash1}
+	out := invokeDryRun(t, mockStub, methodAndAssetToByte("createComputePlan", inpCP))
+	assert.False(t, out.Valid)
+	codes := []interface{}{}
+	for _, e := range out.Errors {
+		codes = append(codes, e["code"])
+	}
+	assert.Equal(t, []interface{}{
+		string(errors.CodePermissionDeniedAlgo),
+		string(errors.CodePermissionDeniedAlgo),
+		string(errors.CodeBadRequest),
+	}, codes)
+
+	// The keys and statuses are computed anyway
+	outCP := outputComputePlan{}
+	require.NoError(t, json.Unmarshal(out.Result, &outCP))
+	assert.Len(t, outCP.TraintupleKeys, 2)
+	assert.Len(t, outCP.TesttupleKeys, 1)
+	require.Len(t, out.Assets.Traintuples, 2)
+	assert.Equal(t, StatusTodo, out.Assets.Traintuples[0].NewStatus)
+	assert.Equal(t, StatusWaiting, out.Assets.Traintuples[1].NewStatus)
+	require.Len(t, out.Assets.Testtuples, 1)
+	assert.Equal(t, StatusWaiting, out.Assets.Testtuples[0].NewStatus)
+}
+
+func TestDryRunComputePlanFailedTraintuple(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	// The first traintuple fails, the tuples depending on it do not report any error
+	// while an independent traintuple is still checked
+	inpCP := defaultComputePlan
+	inpCP.Traintuples = []inputComputePlanTraintuple{inpCP.Traintuples[0], inpCP.Traintuples[1], inpCP.Traintuples[0]}
+	inpCP.Traintuples[0].DataManagerKey = objectiveDescriptionHash
+	inpCP.Traintuples[2].ID = "independent"
+	inpCP.Traintuples[2].DataSampleKeys = []string{testDataSampleHash1}
+	out := invokeDryRun(t, mockStub, methodAndAssetToByte("createComputePlan", inpCP))
+	assert.False(t, out.Valid)
+	messages := []interface{}{}
+	for _, e := range out.Errors {
+		messages = append(messages, e["error"])
+	}
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[1], "dataManager "+objectiveDescriptionHash+" not found")
+	assert.Equal(t, "not possible to create a traintuple with test only data", messages[2])
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"reflect"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+)
+
+// The assets only updated by their owner are stored with a key-level endorsement policy,
+// so that their updates must be endorsed by a peer of this organisation whatever the
+// endorsement policy of the chaincode. The data managers, updated when an objective of
+// another organisation uses them, and the tuples, updated by their creator and by the
+// workers of their parents, only depend on the endorsement policy of the chaincode.
+
+// assetEndorser returns the organisation which must endorse the updates of an asset,
+// its owner. ok is false for the other objects.
+func assetEndorser(object interface{}) (org string, ok bool) {
+	switch asset := object.(type) {
+	case Objective:
+		return asset.Owner, true
+	case DataSample:
+		return asset.Owner, true
+	case Algo:
+		return asset.Owner, true
+	case Node:
+		return asset.ID, true
+	case ExternalModel:
+		return asset.Owner, true
+	}
+	if value := reflect.ValueOf(object); value.Kind() == reflect.Ptr && !value.IsNil() {
+		return assetEndorser(value.Elem().Interface())
+	}
+	return "", false
+}
+
+// setEndorsementPolicy requires the updates of an asset to be endorsed by its owner,
+// if it is owned by a single organisation
+func setEndorsementPolicy(ctx TransactionContext, key string, object interface{}) error {
+	org, ok := assetEndorser(object)
+	if !ok || org == "" {
+		return nil
+	}
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return errors.Internal(err, "cannot create the endorsement policy of %s:", key)
+	}
+	if err := ep.AddOrgs(statebased.RoleTypeMember, org); err != nil {
+		return errors.Internal(err, "cannot create the endorsement policy of %s:", key)
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return errors.Internal(err, "cannot create the endorsement policy of %s:", key)
+	}
+	if err := ctx.SetStateValidationParameter(key, policy); err != nil {
+		return errors.Internal(err, "cannot set the endorsement policy of %s:", key)
+	}
+	return nil
+}
+
+// GetEndorsementPolicy returns the organisations which must endorse the updates of a key,
+// none if only the endorsement policy of the chaincode applies
+func (db *ledgerDB) GetEndorsementPolicy(key string) ([]string, error) {
+	policy, err := db.GetStateValidationParameter(key)
+	if err != nil {
+		return nil, errors.Internal(err, "cannot read the endorsement policy of %s:", key)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, errors.Internal(err, "cannot read the endorsement policy of %s:", key)
+	}
+	return ep.ListOrgs(), nil
+}
+
+// migrateEndorsementPolicies returns a migration setting the endorsement policy of the
+// assets of an index, which were stored without one. newAsset returns the asset to read.
+func migrateEndorsementPolicies(index string, objectType string, newAsset func() interface{}) func(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return func(db LedgerDB, cursor string, limit int) (string, int, error) {
+		return db.ForEachIndexKey(index, []string{objectType}, cursor, limit, func(attributes []string) error {
+			key := attributes[len(attributes)-1]
+			asset := newAsset()
+			if err := db.Get(key, asset); err != nil {
+				return err
+			}
+			return setEndorsementPolicy(db, key, asset)
+		})
+	}
+}
+
+// migrateRemoveEndorsementPolicies returns a migration removing the endorsement policy of the
+// assets of an index, which were stored with one before being updated by several organisations
+func migrateRemoveEndorsementPolicies(index string, objectType string) func(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return func(db LedgerDB, cursor string, limit int) (string, int, error) {
+		return db.ForEachIndexKey(index, []string{objectType}, cursor, limit, func(attributes []string) error {
+			key := attributes[len(attributes)-1]
+			if err := db.SetStateValidationParameter(key, nil); err != nil {
+				return errors.Internal(err, "cannot remove the endorsement policy of %s:", key)
+			}
+			return nil
+		})
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the endorsement policies
+// -------------------------------------------------------------------------------------------
+
+// queryEndorsementPolicy returns the organisations which must endorse the updates of an asset
+func queryEndorsementPolicy(db LedgerDB, args []string) (outputEndorsementPolicy, error) {
+	inp := inputEndorsementPolicy{}
+	if err := AssetFromJSON(db, args, &inp); err != nil {
+		return outputEndorsementPolicy{}, err
+	}
+	return getOutputEndorsementPolicy(db, inp.Key)
+}
+
+// queryEndorsementPolicies returns the organisations which must endorse the updates of several assets
+func queryEndorsementPolicies(db LedgerDB, args []string) ([]outputEndorsementPolicy, error) {
+	inp := inputEndorsementPolicies{}
+	if err := AssetFromJSON(db, args, &inp); err != nil {
+		return nil, err
+	}
+	outputs := []outputEndorsementPolicy{}
+	for _, key := range inp.Keys {
+		out, err := getOutputEndorsementPolicy(db, key)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+// getOutputEndorsementPolicy returns the endorsement policy of a stored asset
+func getOutputEndorsementPolicy(db LedgerDB, key string) (outputEndorsementPolicy, error) {
+	exists, err := db.KeyExists(key)
+	if err != nil {
+		return outputEndorsementPolicy{}, err
+	}
+	if !exists {
+		return outputEndorsementPolicy{}, errors.NotFound("asset %s not found", key).WithKey(key)
+	}
+	orgs, err := db.GetEndorsementPolicy(key)
+	if err != nil {
+		return outputEndorsementPolicy{}, err
+	}
+	return outputEndorsementPolicy{Key: key, Orgs: orgs}, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryEndorsementPolicyOutput(t *testing.T, mockStub *MockStub, key string) outputEndorsementPolicy {
+	args := [][]byte{[]byte("queryEndorsementPolicy"), assetToJSON(inputEndorsementPolicy{Key: key})}
+	resp := mockStub.MockInvoke("42", args)
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out := outputEndorsementPolicy{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &out))
+	return out
+}
+
+func TestEndorsementPolicy(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	registerItem(t, *mockStub, "testDataset")
+
+	// The updates of the assets must be endorsed by their owner
+	for _, key := range []string{worker, testDataSampleHash1} {
+		assert.Equal(t, outputEndorsementPolicy{Key: key, Orgs: []string{worker}}, queryEndorsementPolicyOutput(t, mockStub, key))
+	}
+
+	// Registering an objective on the test dataset of another organisation updates its data manager,
+	// which has no policy
+	assert.Empty(t, queryEndorsementPolicyOutput(t, mockStub, dataManagerOpenerHash).Orgs)
+	mockStub.Creator = "OtherOrg"
+	inpObjective := inputObjective{}
+	resp = mockStub.MockInvoke("42", inpObjective.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.Equal(t, []string{"OtherOrg"}, queryEndorsementPolicyOutput(t, mockStub, objectiveDescriptionHash).Orgs)
+
+	// The policies of several assets can be queried at once
+	inp := inputEndorsementPolicies{Keys: []string{testDataSampleHash1, objectiveDescriptionHash}}
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("queryEndorsementPolicies", inp))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	outs := []outputEndorsementPolicy{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &outs))
+	assert.Equal(t, []outputEndorsementPolicy{
+		{Key: testDataSampleHash1, Orgs: []string{worker}},
+		{Key: objectiveDescriptionHash, Orgs: []string{"OtherOrg"}},
+	}, outs)
+
+	// The other keys have no policy, and the unknown ones are not found
+	assert.Empty(t, queryEndorsementPolicyOutput(t, mockStub, schemaVersionKey).Orgs)
+	args := [][]byte{[]byte("queryEndorsementPolicy"), assetToJSON(inputEndorsementPolicy{Key: "unknown"})}
+	resp = mockStub.MockInvoke("42", args)
+	assert.EqualValues(t, 404, resp.Status, resp.Message)
+}
+
+func TestEndorsementPolicyAcrossOrganisations(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(creator string, fn string, inp interface{}) {
+		mockStub.Creator = creator
+		defer func() { mockStub.Creator = "" }()
+		resp := mockStub.MockInvoke("42", methodAndAssetToByte(fn, inp))
+		require.EqualValues(t, 200, resp.Status, "%s by %s: %s", fn, creator, resp.Message)
+	}
+
+	// The child traintuple of a compute plan of OtherOrg runs on the dataset of ThirdOrg
+	for _, org := range []string{"OtherOrg", "ThirdOrg"} {
+		mockStub.Creator = org
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte("registerNode")})
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+	}
+	thirdDataManagerKey := "ab1a20b2a67c8003cc748d6666534f2b01f3f08d175440537a5bf86b7d08d5ee"
+	thirdDataSampleKey := "ab1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"
+	inpDataManager := inputDataManager{OpenerHash: thirdDataManagerKey}
+	resp := mockStub.MockInvoke("42", inpDataManager.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpDataSample := inputDataSample{Hashes: []string{thirdDataSampleKey}, DataManagerKeys: []string{thirdDataManagerKey}}
+	resp = mockStub.MockInvoke("42", inpDataSample.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	mockStub.Creator = "OtherOrg"
+	inpComputePlan := defaultComputePlan
+	inpComputePlan.Timeout = 60
+	inpComputePlan.Traintuples = []inputComputePlanTraintuple{
+		defaultComputePlan.Traintuples[0],
+		{
+			DataManagerKey: thirdDataManagerKey,
+			DataSampleKeys: []string{thirdDataSampleKey},
+			ID:             traintupleID2,
+			InModelsIDs:    []string{traintupleID1},
+		},
+	}
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("createComputePlan", inpComputePlan))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	mockStub.Creator = ""
+	plan := outputComputePlan{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &plan))
+	parentKey, childKey := plan.TraintupleKeys[0], plan.TraintupleKeys[1]
+
+	// None of the transactions needs the endorsement of another organisation than its creator:
+	// the worker of the parent updates the child, the creator updates and reclaims it, and the
+	// worker of the child fails the testtuple of the worker of the parent
+	invoke(worker, "logStartTrain", inputHash{parentKey})
+	success := inputLogSuccessTrain{}
+	success.Key = parentKey
+	success.createDefault()
+	invoke(worker, "logSuccessTrain", success)
+	invoke("OtherOrg", "updateTuplePriority", inputUpdatePriority{Key: childKey, Priority: 2})
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp}
+	invoke("ThirdOrg", "logStartTrain", inputHash{childKey})
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp + 61}
+	invoke("OtherOrg", "reclaimStaleTuples", inputReclaimStaleTuples{TraintupleKeys: []string{childKey}})
+	invoke("ThirdOrg", "logStartTrain", inputHash{childKey})
+	fail := inputLogFailTrain{}
+	fail.Key = childKey
+	fail.createDefault()
+	invoke("ThirdOrg", "logFailTrain", fail)
+
+	db := NewLedgerDB(mockStub)
+	child, err := db.GetTraintuple(childKey)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, child.Status)
+	testtuple, err := db.GetTesttuple(plan.TesttupleKeys[0])
+	require.NoError(t, err)
+	assert.Equal(t, worker, testtuple.Dataset.Worker)
+	assert.Equal(t, StatusFailed, testtuple.Status)
+	for _, key := range []string{thirdDataManagerKey, parentKey, childKey, plan.TesttupleKeys[0]} {
+		assert.Empty(t, queryEndorsementPolicyOutput(t, mockStub, key).Orgs, key)
+	}
+}
@@ -17,6 +17,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 )
 
 // Error implements the error interface
@@ -26,6 +27,10 @@ type Error struct {
 	Kind Kind
 	// The underlying error if any
 	Err error
+	// Stable identifier of the error, the default one of its kind if empty
+	code Code
+	// The first error wrapped, returned by Unwrap
+	cause error
 	// Associated interface through errors methods
 	context map[string]interface{}
 }
@@ -46,6 +51,8 @@ func (e Error) Error() string {
 // The possible arg type are:
 //	errors.Kind
 //		The class of error, such as a key conflict
+//	errors.Code
+//		The stable identifier of the error, such as CodePermissionDeniedAlgo
 //	error
 //		The underlying error
 //	string
@@ -59,14 +66,25 @@ func E(args ...interface{}) Error {
 		switch arg := arg.(type) {
 		case Kind:
 			e.Kind = arg
+		case Code:
+			e.code = arg
 		case Error:
 			e.context = arg.context
+			if e.code == "" {
+				e.code = arg.code
+			}
+			if e.cause == nil {
+				e.cause = arg
+			}
 			if e.Err == nil {
 				e.Err = arg.Err
 			} else {
 				e.Err = fmt.Errorf("%s %s", arg.Error(), e.Error())
 			}
 		case error:
+			if e.cause == nil {
+				e.cause = arg
+			}
 			if e.Err == nil {
 				e.Err = arg
 			} else {
@@ -92,7 +110,7 @@ func Wrap(err error) Error {
 	if e, ok := err.(Error); ok {
 		return e
 	}
-	return Error{Err: err}
+	return Error{Err: err, cause: err}
 }
 
 // Internal returns an Error of a this specific type
@@ -139,6 +157,13 @@ func (e Error) WithKeys(keys []string) Error {
 	return e
 }
 
+// WithFields associate the given invalid fields to the error context
+// It overwrites previous fields' list if any.
+func (e Error) WithFields(fields []FieldError) Error {
+	e.context["fields"] = fields
+	return e
+}
+
 // GetContext return the associated key if there is any
 func (e Error) GetContext() map[string]interface{} {
 	return e.context
@@ -149,6 +174,46 @@ func (e Error) HTTPStatusCode() int {
 	return e.Kind.HTTPStatusCode()
 }
 
+// Code returns the stable identifier of the error, the one of its kind by default
+func (e Error) Code() Code {
+	if e.code != "" {
+		return e.code
+	}
+	return e.Kind.Code()
+}
+
+// Unwrap returns the first error wrapped in this one, if any
+func (e Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the error matches the target: errors with the same code if
+// the target has its own code, errors of the same kind otherwise.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	if t.code != "" {
+		return e.Code() == t.code
+	}
+	return e.Kind == t.Kind
+}
+
+// FieldError describes an input field which failed validation
+type FieldError struct {
+	// Field is the path of the field in the input json
+	Field string `json:"field"`
+	// Tag is the validation rule which failed
+	Tag string `json:"tag"`
+	// Param is the parameter of the validation rule, if any
+	Param string `json:"param,omitempty"`
+	// Value is the value which failed validation
+	Value interface{} `json:"value"`
+	// Message is the human readable description of the failure
+	Message string `json:"message"`
+}
+
 // Kind is the type use to discriminate between errors type
 // It's not intended for user print but to handle errors correctly
 type Kind uint8
@@ -162,6 +227,21 @@ const (
 	forbidden              // Forbidden request
 )
 
+// Code returns for an error kind its default code
+func (k Kind) Code() Code {
+	switch k {
+	case notFound:
+		return CodeNotFound
+	case conflict:
+		return CodeConflict
+	case badRequest:
+		return CodeBadRequest
+	case forbidden:
+		return CodeForbidden
+	}
+	return CodeInternal
+}
+
 // HTTPStatusCode returns for an error kind the associated http status
 func (k Kind) HTTPStatusCode() int {
 	switch k {
@@ -176,3 +256,98 @@ func (k Kind) HTTPStatusCode() int {
 	}
 	return http.StatusInternalServerError
 }
+
+// Code is a stable machine-readable identifier of an error, intended for
+// clients to handle errors without parsing their messages
+type Code string
+
+// Possible error codes. Beware, their values are part of the chaincode API.
+const (
+	// Default code of each kind
+	CodeInternal   Code = "INTERNAL"
+	CodeNotFound   Code = "NOT_FOUND"
+	CodeConflict   Code = "CONFLICT"
+	CodeBadRequest Code = "BAD_REQUEST"
+	CodeForbidden  Code = "FORBIDDEN"
+
+	// Invalid inputs
+	CodeInvalidJSON  Code = "INVALID_JSON"
+	CodeInvalidInput Code = "INVALID_INPUT"
+
+	// Tuples
+	CodeTupleInvalidStatusTransition Code = "TUPLE_INVALID_STATUS_TRANSITION"
+
+	// Challenges
+	CodeChallengeClosed        Code = "CHALLENGE_CLOSED"
+	CodeChallengeQuotaExceeded Code = "CHALLENGE_QUOTA_EXCEEDED"
+
+	// Permissions
+	CodePermissionDeniedAlgo        Code = "PERMISSION_DENIED_ALGO"
+	CodePermissionDeniedConfig      Code = "PERMISSION_DENIED_CONFIG"
+	CodePermissionDeniedDataManager Code = "PERMISSION_DENIED_DATA_MANAGER"
+	CodePermissionDeniedDataSample  Code = "PERMISSION_DENIED_DATA_SAMPLE"
+	CodePermissionDeniedModel       Code = "PERMISSION_DENIED_MODEL"
+	CodePermissionDeniedObjective   Code = "PERMISSION_DENIED_OBJECTIVE"
+	CodePermissionDeniedTraintuple  Code = "PERMISSION_DENIED_TRAINTUPLE"
+	CodePermissionDeniedTupleUpdate Code = "PERMISSION_DENIED_TUPLE_UPDATE"
+)
+
+// Unwrap returns the result of calling the Unwrap method on err, if any.
+// It mirrors the errors package of the standard library from go 1.13.
+func Unwrap(err error) error {
+	u, ok := err.(interface{ Unwrap() error })
+	if !ok {
+		return nil
+	}
+	return u.Unwrap()
+}
+
+// Is reports whether any error in err's chain matches target.
+// It mirrors the errors package of the standard library from go 1.13.
+func Is(err, target error) bool {
+	if target == nil {
+		return err == target
+	}
+	isComparable := reflect.TypeOf(target).Comparable()
+	for err != nil {
+		if isComparable && err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+		err = Unwrap(err)
+	}
+	return false
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// As finds the first error in err's chain that matches target, and if so, sets
+// target to that error value and returns true.
+// It mirrors the errors package of the standard library from go 1.13.
+func As(err error, target interface{}) bool {
+	if target == nil {
+		panic("errors: target cannot be nil")
+	}
+	val := reflect.ValueOf(target)
+	typ := val.Type()
+	if typ.Kind() != reflect.Ptr || val.IsNil() {
+		panic("errors: target must be a non-nil pointer")
+	}
+	targetType := typ.Elem()
+	if targetType.Kind() != reflect.Interface && !targetType.Implements(errorType) {
+		panic("errors: *target must be interface or implement error")
+	}
+	for err != nil {
+		if reflect.TypeOf(err).AssignableTo(targetType) {
+			val.Elem().Set(reflect.ValueOf(err))
+			return true
+		}
+		if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(target) {
+			return true
+		}
+		err = Unwrap(err)
+	}
+	return false
+}
@@ -17,9 +17,11 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestErrorCases(t *testing.T) {
@@ -124,3 +126,50 @@ func TestErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorCodes(t *testing.T) {
+	assert.Equal(t, CodeInternal, E("msg").Code())
+	assert.Equal(t, CodeNotFound, NotFound("msg").Code())
+	assert.Equal(t, CodeForbidden, Forbidden().Code())
+	assert.Equal(t, CodeInternal, Wrap(fmt.Errorf("msg")).Code())
+
+	e := Forbidden(CodePermissionDeniedAlgo, "not authorized to process algo %s", "key")
+	assert.Equal(t, CodePermissionDeniedAlgo, e.Code())
+	assert.Equal(t, "not authorized to process algo key", e.Error())
+
+	// The code is kept when wrapping, unless overridden
+	assert.Equal(t, CodePermissionDeniedAlgo, BadRequest(e, "wrapped").Code())
+	assert.Equal(t, CodeInvalidInput, BadRequest(e, CodeInvalidInput, "wrapped").Code())
+}
+
+func TestErrorChain(t *testing.T) {
+	base := fmt.Errorf("base error")
+	e := BadRequest(base, CodeInvalidJSON, "reading failed:")
+	wrapped := E(e, "outer")
+
+	assert.Equal(t, e, Unwrap(wrapped))
+	assert.Equal(t, base, Unwrap(e))
+	assert.Nil(t, Unwrap(base))
+
+	assert.True(t, Is(wrapped, base))
+	assert.True(t, Is(wrapped, E(CodeInvalidJSON)))
+	assert.False(t, Is(wrapped, E(CodeInvalidInput)))
+	assert.True(t, Is(e, BadRequest()))
+	assert.False(t, Is(e, NotFound()))
+
+	var target Error
+	assert.False(t, As(fmt.Errorf("other"), &target))
+	require.True(t, As(wrapped, &target))
+	assert.Equal(t, wrapped, target)
+
+	var pathErr *os.PathError
+	_, osErr := os.Open("/does/not/exist")
+	assert.True(t, As(NotFound(osErr, "file"), &pathErr))
+	assert.Equal(t, "/does/not/exist", pathErr.Path)
+}
+
+func TestErrorFields(t *testing.T) {
+	fields := []FieldError{{Field: "name", Tag: "required", Value: ""}}
+	e := BadRequest(CodeInvalidInput, "validation failed").WithFields(fields)
+	assert.Equal(t, fields, e.GetContext()["fields"])
+}
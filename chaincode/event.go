@@ -0,0 +1,157 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Event is the envelope of the unique event sent at the end of a transaction,
+// since Fabric only keeps one event per transaction.
+// Besides the tuples workers have to process, it lists every asset created
+// or updated during the transaction so that indexers can follow the ledger.
+type Event struct {
+	TuplesEvent
+	Assets EventAssets `json:"assets"`
+
+	// function is the smart contract currently writing in the ledger
+	function string
+	// positions stores for each asset key its position in its section
+	positions map[string]int
+}
+
+// EventAssets stores, by asset type, the assets created or updated during a transaction
+type EventAssets struct {
+	Objectives    []EventAsset `json:"objective"`
+	DataManagers  []EventAsset `json:"dataManager"`
+	DataSamples   []EventAsset `json:"dataSample"`
+	Algos         []EventAsset `json:"algo"`
+	Traintuples   []EventAsset `json:"traintuple"`
+	Testtuples    []EventAsset `json:"testtuple"`
+	Nodes         []EventAsset `json:"node"`
+	Predicttuples []EventAsset `json:"predicttuple"`
+	Models        []EventAsset `json:"model"`
+}
+
+// EventAsset describes an asset created or updated during a transaction.
+// OldStatus is empty for a new asset, both statuses are empty for assets without status.
+type EventAsset struct {
+	AssetType string `json:"assetType"`
+	Key       string `json:"key"`
+	OldStatus string `json:"oldStatus"`
+	NewStatus string `json:"newStatus"`
+	Function  string `json:"function"`
+}
+
+// NewEvent returns an empty event for assets updated by the given smart contract
+func NewEvent(function string) *Event {
+	return &Event{
+		function:  function,
+		positions: make(map[string]int),
+	}
+}
+
+// AddTuplesEvent adds the todo tuples to the ones already in the event
+func (event *Event) AddTuplesEvent(tuplesEvent TuplesEvent) {
+	event.Traintuples = append(event.Traintuples, tuplesEvent.Traintuples...)
+	event.Testtuples = append(event.Testtuples, tuplesEvent.Testtuples...)
+	event.Predicttuples = append(event.Predicttuples, tuplesEvent.Predicttuples...)
+}
+
+// AddAsset records the update of an asset. If the asset has already been updated during
+// the transaction, its original status is kept and only its new status is changed.
+func (event *Event) AddAsset(assetType AssetType, key string, oldStatus string, newStatus string) {
+	section := event.Assets.section(assetType)
+	if section == nil {
+		return
+	}
+	if position, ok := event.positions[key]; ok {
+		(*section)[position].NewStatus = newStatus
+		(*section)[position].Function = event.function
+		return
+	}
+	event.positions[key] = len(*section)
+	*section = append(*section, EventAsset{
+		AssetType: assetType.String(),
+		Key:       key,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Function:  event.function,
+	})
+}
+
+// HasAsset checks if the asset has already been updated during the transaction
+func (event *Event) HasAsset(key string) bool {
+	_, ok := event.positions[key]
+	return ok
+}
+
+// IsEmpty checks if there is anything to send
+func (event *Event) IsEmpty() bool {
+	return len(event.positions) == 0 && len(event.Traintuples) == 0 && len(event.Testtuples) == 0 && len(event.Predicttuples) == 0
+}
+
+// section returns the list of updated assets of a given type
+func (assets *EventAssets) section(assetType AssetType) *[]EventAsset {
+	switch assetType {
+	case ObjectiveType:
+		return &assets.Objectives
+	case DataManagerType:
+		return &assets.DataManagers
+	case DataSampleType:
+		return &assets.DataSamples
+	case AlgoType:
+		return &assets.Algos
+	case TraintupleType:
+		return &assets.Traintuples
+	case TesttupleType:
+		return &assets.Testtuples
+	case NodeType:
+		return &assets.Nodes
+	case PredicttupleType:
+		return &assets.Predicttuples
+	case ModelType:
+		return &assets.Models
+	}
+	return nil
+}
+
+// eventAssetInfo returns the type and status of an object stored in the ledger,
+// ok is false if the object is not an asset (an index for instance)
+func eventAssetInfo(object interface{}) (assetType AssetType, status string, ok bool) {
+	switch asset := object.(type) {
+	case Objective, *Objective:
+		return ObjectiveType, "", true
+	case DataManager, *DataManager:
+		return DataManagerType, "", true
+	case DataSample, *DataSample:
+		return DataSampleType, "", true
+	case Algo, *Algo:
+		return AlgoType, "", true
+	case Traintuple:
+		return TraintupleType, asset.Status, true
+	case *Traintuple:
+		return TraintupleType, asset.Status, true
+	case Testtuple:
+		return TesttupleType, asset.Status, true
+	case *Testtuple:
+		return TesttupleType, asset.Status, true
+	case Node, *Node:
+		return NodeType, "", true
+	case Predicttuple:
+		return PredicttupleType, asset.Status, true
+	case *Predicttuple:
+		return PredicttupleType, asset.Status, true
+	case ExternalModel, *ExternalModel:
+		return ModelType, "", true
+	}
+	return 0, "", false
+}
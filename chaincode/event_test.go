@@ -0,0 +1,83 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getLastEvent(t *testing.T, mockStub *MockStub) Event {
+	require.NotNil(t, mockStub.LastEvent, "no event sent during the transaction")
+	event := Event{}
+	err := json.Unmarshal(mockStub.LastEvent.Payload, &event)
+	require.NoError(t, err)
+	return event
+}
+
+func TestEventAssets(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	event := getLastEvent(t, mockStub)
+	assert.Equal(t, []EventAsset{{AssetType: "node", Key: worker, Function: "registerNode"}}, event.Assets.Nodes)
+
+	registerItem(t, *mockStub, "trainDataset")
+	inpAlgo := inputAlgo{}
+	resp := mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	event = getLastEvent(t, mockStub)
+	assert.Equal(t, []EventAsset{{AssetType: "algo", Key: algoHash, Function: "registerAlgo"}}, event.Assets.Algos)
+	assert.Empty(t, event.Assets.Traintuples)
+
+	// Creating a traintuple sends it to the worker
+	inpTraintuple := inputTraintuple{}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	event = getLastEvent(t, mockStub)
+	assert.Equal(t, []EventAsset{{AssetType: "traintuple", Key: traintupleKey, NewStatus: StatusTodo, Function: "createTraintuple"}}, event.Assets.Traintuples)
+	require.Len(t, event.TuplesEvent.Traintuples, 1)
+	assert.Equal(t, traintupleKey, event.TuplesEvent.Traintuples[0].Key)
+
+	inpTraintuple = inputTraintuple{InModels: []string{traintupleKey}}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	childKey := getLastEvent(t, mockStub).Assets.Traintuples[0].Key
+
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("logStartTrain", inputHash{traintupleKey}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	event = getLastEvent(t, mockStub)
+	assert.Equal(t, []EventAsset{{AssetType: "traintuple", Key: traintupleKey, OldStatus: StatusTodo, NewStatus: StatusDoing, Function: "logStartTrain"}}, event.Assets.Traintuples)
+	assert.Empty(t, event.TuplesEvent.Traintuples)
+
+	// The children updates are part of the same event
+	success := inputLogSuccessTrain{}
+	resp = mockStub.MockInvoke("42", success.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	event = getLastEvent(t, mockStub)
+	assert.Equal(t, []EventAsset{
+		{AssetType: "traintuple", Key: traintupleKey, OldStatus: StatusDoing, NewStatus: StatusDone, Function: "logSuccessTrain"},
+		{AssetType: "traintuple", Key: childKey, OldStatus: StatusWaiting, NewStatus: StatusTodo, Function: "logSuccessTrain"},
+	}, event.Assets.Traintuples)
+	require.Len(t, event.TuplesEvent.Traintuples, 1)
+	assert.Equal(t, childKey, event.TuplesEvent.Traintuples[0].Key)
+
+	// Queries do not send any event
+	resp = mockStub.MockInvoke("42", methodToByte("queryTraintuples"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.Nil(t, mockStub.LastEvent)
+}
@@ -17,44 +17,72 @@ package main
 // -------------------------------------------------------------------------------------------
 // Struct used to represent inputs for smart contracts. In Hyperledger Fabric, we get as input
 // arg  [][]byte or []string, and it is not possible to input a string looking like a json
+// The sensitive fields, tagged transient, are passed in the transient map instead of the args
 // -------------------------------------------------------------------------------------------
 
-// inputObjective is the representation of input args to register a Objective
+// inputObjective is the representation of input args to register a Objective.
+// An objective evaluated on several data managers lists its TestDatasets, instead of or
+// in addition to its TestDataset. With AutoEvaluate, its traintuples are evaluated once done, except
+// the ones tagged with one of the SkipEvaluationTags. An objective run as a competition has a Challenge.
 type inputObjective struct {
 	Name                      string           `validate:"required,gte=1,lte=100" json:"name"`
 	DescriptionHash           string           `validate:"required,len=64,hexadecimal" json:"descriptionHash"`
-	DescriptionStorageAddress string           `validate:"required,url" json:"descriptionStorageAddress"`
+	DescriptionStorageAddress string           `validate:"required,url,storage_url" json:"descriptionStorageAddress"`
 	MetricsName               string           `validate:"required,gte=1,lte=100" json:"metricsName"`
 	MetricsHash               string           `validate:"required,len=64,hexadecimal" json:"metricsHash"`
-	MetricsStorageAddress     string           `validate:"required,url" json:"metricsStorageAddress"`
+	MetricsStorageAddress     string           `validate:"required,url,storage_url" json:"metricsStorageAddress"`
 	TestDataset               inputDataset     `validate:"omitempty" json:"testDataset"`
+	TestDatasets              []inputDataset   `validate:"omitempty,dive" json:"testDatasets"`
 	Permissions               inputPermissions `validate:"required" json:"permissions"`
+	Timeout                   int              `validate:"gte=0" json:"timeout"`
+	AutoEvaluate              bool             `json:"autoEvaluate"`
+	SkipEvaluationTags        []string         `validate:"omitempty,dive,lte=64" json:"skipEvaluationTags"`
+	Challenge                 inputChallenge   `validate:"omitempty" json:"challenge"`
 }
 
-// inputDataset is the representation in input args to register a dataset
+// inputChallenge is the representation of input args to set the rules of a challenge, its dates being
+// in RFC 3339 format
+type inputChallenge struct {
+	StartDate           string `validate:"omitempty" json:"startDate"`
+	EndDate             string `validate:"omitempty" json:"endDate"`
+	MaxDailySubmissions int    `validate:"gte=0" json:"maxDailySubmissions"`
+}
+
+// inputDataset is the representation in input args to register a dataset. The PrivateDataSampleKeys
+// of a test dataset split it in a public and a private partition.
 type inputDataset struct {
-	DataManagerKey string   `validate:"omitempty,len=64,hexadecimal" json:"dataManagerKey"`
-	DataSampleKeys []string `validate:"omitempty,dive,len=64,hexadecimal" json:"dataSampleKeys"`
+	DataManagerKey        string   `validate:"omitempty,len=64,hexadecimal" json:"dataManagerKey"`
+	DataSampleKeys        []string `validate:"omitempty,dive,len=64,hexadecimal" json:"dataSampleKeys"`
+	PrivateDataSampleKeys []string `validate:"omitempty,unique,dive,len=64,hexadecimal" json:"privateDataSampleKeys"`
 }
 
 // inputAlgo is the representation of input args to register an Algo
 type inputAlgo struct {
 	Name                      string           `validate:"required,gte=1,lte=100" json:"name"`
 	Hash                      string           `validate:"required,len=64,hexadecimal" json:"hash"`
-	StorageAddress            string           `validate:"required,url" json:"storageAddress"`
+	StorageAddress            string           `validate:"required,url,storage_url" json:"storageAddress"`
 	DescriptionHash           string           `validate:"required,len=64,hexadecimal" json:"descriptionHash"`
-	DescriptionStorageAddress string           `validate:"required,url" json:"descriptionStorageAddress"`
+	DescriptionStorageAddress string           `validate:"required,url,storage_url" json:"descriptionStorageAddress"`
 	Permissions               inputPermissions `validate:"required" json:"permissions"`
 }
 
+// inputModel is the representation of input args to register a model trained outside the platform
+type inputModel struct {
+	Name           string           `validate:"required,gte=1,lte=100" json:"name"`
+	Hash           string           `validate:"required,len=64,hexadecimal" json:"hash"`
+	StorageAddress string           `validate:"required,url,storage_url" json:"storageAddress" transient:"true"`
+	AlgoKey        string           `validate:"required,len=64,hexadecimal" json:"algoKey"`
+	Permissions    inputPermissions `validate:"required" json:"permissions"`
+}
+
 // inputDataManager is the representation of input args to register a DataManager
 type inputDataManager struct {
 	Name                      string           `validate:"required,gte=1,lte=100" json:"name"`
 	OpenerHash                string           `validate:"required,len=64,hexadecimal" json:"openerHash"`
-	OpenerStorageAddress      string           `validate:"required,url" json:"openerStorageAddress"`
-	Type                      string           `validate:"required,gte=1,lte=30" json:"type"`
+	OpenerStorageAddress      string           `validate:"required,url,storage_url" json:"openerStorageAddress" transient:"true"`
+	Type                      string           `validate:"required,gte=1,lte=30,data_manager_type" json:"type"`
 	DescriptionHash           string           `validate:"required,len=64,hexadecimal" json:"descriptionHash"`
-	DescriptionStorageAddress string           `validate:"required,url" json:"descriptionStorageAddress"`
+	DescriptionStorageAddress string           `validate:"required,url,storage_url" json:"descriptionStorageAddress"`
 	ObjectiveKey              string           `validate:"omitempty" json:"objectiveKey"` //`validate:"required"`
 	Permissions               inputPermissions `validate:"required" json:"permissions"`
 }
@@ -84,24 +112,43 @@ type inputTraintuple struct {
 	ObjectiveKey   string   `validate:"required,len=64,hexadecimal" json:"objectiveKey"`
 	InModels       []string `validate:"omitempty,dive,len=64,hexadecimal" json:"inModels"`
 	DataManagerKey string   `validate:"required,len=64,hexadecimal" json:"dataManagerKey"`
-	DataSampleKeys []string `validate:"required,unique,gt=0,dive,len=64,hexadecimal" json:"dataSampleKeys"`
+	DataSampleKeys []string `validate:"required,unique,gt=0,max_samples,dive,len=64,hexadecimal" json:"dataSampleKeys"`
 	ComputePlanID  string   `validate:"omitempty" json:"computePlanID"`
 	Rank           string   `validate:"omitempty" json:"rank"`
 	Tag            string   `validate:"omitempty,lte=64" json:"tag"`
+	Priority       int      `json:"priority"`
 }
 
-// inputTestuple is the representation of input args to register a Testtuple
+// inputTestuple is the representation of input args to register a Testtuple.
+// It evaluates the model of a traintuple, or a registered model given by ModelKey, or by
+// TraintupleKey, whose objective is then given by ObjectiveKey. The algo of a registered model
+// is the one it is compatible with, unless AlgoKey is set. An ensemble of models sharing the
+// same algo and objective is evaluated together with TraintupleKeys.
 type inputTesttuple struct {
-	TraintupleKey  string   `validate:"required,len=64,hexadecimal" json:"traintupleKey"`
+	TraintupleKey  string   `validate:"omitempty,len=64,hexadecimal" json:"traintupleKey"`
+	TraintupleKeys []string `validate:"omitempty,gt=1,unique,dive,len=64,hexadecimal" json:"traintupleKeys"`
+	ModelKey       string   `validate:"omitempty,len=64,hexadecimal" json:"modelKey"`
+	AlgoKey        string   `validate:"omitempty,len=64,hexadecimal" json:"algoKey"`
+	ObjectiveKey   string   `validate:"omitempty,len=64,hexadecimal" json:"objectiveKey"`
 	DataManagerKey string   `validate:"omitempty,len=64,hexadecimal" json:"dataManagerKey"`
 	DataSampleKeys []string `validate:"omitempty,dive,len=64,hexadecimal" json:"dataSampleKeys"`
 	Tag            string   `validate:"omitempty,lte=64" json:"tag"`
+	Priority       int      `json:"priority"`
 }
 
 type inputHash struct {
 	Key string `validate:"required,len=64,hexadecimal" json:"key"`
 }
 
+// inputEndorsementPolicy is the key of an asset, which is not always a hash
+type inputEndorsementPolicy struct {
+	Key string `validate:"required" json:"key"`
+}
+
+type inputEndorsementPolicies struct {
+	Keys []string `validate:"required,gt=0,dive,required" json:"keys"`
+}
+
 type inputLogSuccessTrain struct {
 	inputLog
 	OutModel inputHashDress `validate:"required" json:"outModel"`
@@ -109,22 +156,62 @@ type inputLogSuccessTrain struct {
 }
 type inputLogSuccessTest struct {
 	inputLog
-	Perf float32 `validate:"omitempty" json:"perf"`
+	Perf        float32 `validate:"omitempty" json:"perf"`
+	PrivatePerf float32 `validate:"omitempty" json:"privatePerf" transient:"true"`
+	// PrivatePerfSalt is the random salt hashed with the private perf, required with private test data samples
+	PrivatePerfSalt string `validate:"omitempty,min=16" json:"privatePerfSalt" transient:"true"`
 }
 type inputLogFailTrain struct {
 	inputLog
+	FailureReport *inputFailureReport `validate:"omitempty" json:"failureReport"`
 }
 type inputLogFailTest struct {
 	inputLog
+	FailureReport *inputFailureReport `validate:"omitempty" json:"failureReport"`
 }
 type inputLog struct {
-	Key string `validate:"required,len=64,hexadecimal" json:"key"`
-	Log string `validate:"lte=200" json:"log"`
+	Key     string          `validate:"required,len=64,hexadecimal" json:"key"`
+	Log     string          `validate:"max_log" json:"log" transient:"true"`
+	FullLog *inputHashDress `validate:"omitempty" json:"fullLog"`
+}
+
+// inputFailureReport describes why a tuple failed
+type inputFailureReport struct {
+	ErrorType string `validate:"required,oneof=algo data model metrics resources timeout internal" json:"errorType"`
+	Stage     string `validate:"required,oneof=setup download build train predict evaluate upload" json:"stage"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// inputPredicttuple is the representation of input args to register a Predicttuple.
+// TraintupleKey can be the key of a registered model.
+type inputPredicttuple struct {
+	TraintupleKey  string   `validate:"required,len=64,hexadecimal" json:"traintupleKey"`
+	DataManagerKey string   `validate:"required,len=64,hexadecimal" json:"dataManagerKey"`
+	DataSampleKeys []string `validate:"required,unique,gt=0,max_samples,dive,len=64,hexadecimal" json:"dataSampleKeys"`
+	Tag            string   `validate:"omitempty,lte=64" json:"tag"`
+	Priority       int      `json:"priority"`
+}
+
+type inputLogSuccessPredict struct {
+	inputLog
+	Predictions inputHashDress `validate:"required" json:"predictions"`
+}
+type inputLogFailPredict struct {
+	inputLog
+	FailureReport *inputFailureReport `validate:"omitempty" json:"failureReport"`
+}
+
+// inputLogProgress is a progress report of a running traintuple
+type inputLogProgress struct {
+	Key        string             `validate:"required,len=64,hexadecimal" json:"key"`
+	Step       int                `validate:"gte=0" json:"step"`
+	Percentage float32            `validate:"gte=0,lte=100" json:"percentage"`
+	Metrics    map[string]float32 `validate:"omitempty" json:"metrics"`
 }
 
 type inputHashDress struct {
 	Hash           string `validate:"required,len=64,hexadecimal" json:"hash"`
-	StorageAddress string `validate:"required" json:"storageAddress"`
+	StorageAddress string `validate:"required" json:"storageAddress" transient:"true"`
 }
 
 type inputQueryFilter struct {
@@ -138,16 +225,20 @@ type inputQueryFilter struct {
 // Traintuples is the list of all the traintuples planed by the compute plan
 // Beware, it's order sensitive since the `InModelsIDs` can only be interpreted
 // if the traintuples matching those IDs have already been created.
+// SkipEvaluation opts the traintuples out of the automatic evaluation of the objective.
 type inputComputePlan struct {
-	AlgoKey      string                       `validate:"required,len=64,hexadecimal" json:"algoKey"`
-	ObjectiveKey string                       `validate:"required,len=64,hexadecimal" json:"objectiveKey"`
-	Traintuples  []inputComputePlanTraintuple `validate:"required,gt=0" json:"traintuples"`
-	Testtuples   []inputComputePlanTesttuple  `validate:"omitempty" json:"testtuples"`
+	AlgoKey        string                       `validate:"required,len=64,hexadecimal" json:"algoKey"`
+	ObjectiveKey   string                       `validate:"required,len=64,hexadecimal" json:"objectiveKey"`
+	Traintuples    []inputComputePlanTraintuple `validate:"required,gt=0" json:"traintuples"`
+	Testtuples     []inputComputePlanTesttuple  `validate:"omitempty" json:"testtuples"`
+	Timeout        int                          `validate:"gte=0" json:"timeout"`
+	Priority       int                          `json:"priority"`
+	SkipEvaluation bool                         `json:"skipEvaluation"`
 }
 
 type inputComputePlanTraintuple struct {
 	DataManagerKey string   `validate:"required,len=64,hexadecimal" json:"dataManagerKey"`
-	DataSampleKeys []string `validate:"required,dive,len=64,hexadecimal" json:"dataSampleKeys"`
+	DataSampleKeys []string `validate:"required,max_samples,dive,len=64,hexadecimal" json:"dataSampleKeys"`
 	ID             string   `validate:"required,lte=64" json:"id"`
 	InModelsIDs    []string `validate:"omitempty,dive,lte=64" json:"inModelsIDs"`
 	Tag            string   `validate:"omitempty,lte=64" json:"tag"`
@@ -160,9 +251,25 @@ type inputComputePlanTesttuple struct {
 	TraintupleID   string   `validate:"required,lte=64" json:"traintupleID"`
 }
 
+// inputReclaimStaleTuples lists the doing tuples to reclaim once their timeout has passed
+type inputReclaimStaleTuples struct {
+	TraintupleKeys   []string `validate:"omitempty,unique,dive,len=64,hexadecimal" json:"traintupleKeys"`
+	TesttupleKeys    []string `validate:"omitempty,unique,dive,len=64,hexadecimal" json:"testtupleKeys"`
+	PredicttupleKeys []string `validate:"omitempty,unique,dive,len=64,hexadecimal" json:"predicttupleKeys"`
+}
+
+// inputUpdatePriority is the new priority of a traintuple, a testtuple or a predicttuple
+type inputUpdatePriority struct {
+	Key      string `validate:"required,len=64,hexadecimal" json:"key"`
+	Priority int    `json:"priority"`
+}
+
+// inputLeaderboard is the representation of input args to query the leaderboard of an objective,
+// or the final one of its challenge
 type inputLeaderboard struct {
 	ObjectiveKey   string `validate:"omitempty,len=64,hexadecimal" json:"objectiveKey"`
 	AscendingOrder bool   `json:"ascendingOrder,required"`
+	Final          bool   `json:"final"`
 }
 
 type inputPermissions struct {
@@ -173,3 +280,27 @@ type inputPermission struct {
 	Public        bool     `json:"public,required"`
 	AuthorizedIDs []string `validate:"required" json:"authorizedIDs"`
 }
+
+type inputEventsSince struct {
+	Sequence int `validate:"gte=0" json:"sequence"`
+}
+
+type inputAckEvents struct {
+	Sequence int `validate:"gte=1" json:"sequence"`
+}
+
+// inputBatch is the representation of input args to call several smart contracts in a single transaction
+type inputBatch struct {
+	Entries []inputBatchEntry `validate:"required,gt=0,dive" json:"entries"`
+}
+
+type inputBatchEntry struct {
+	Fn   string   `validate:"required" json:"fn"`
+	Args []string `validate:"omitempty" json:"args"`
+}
+
+// inputDryRun is the representation of input args to call a smart contract without updating the ledger
+type inputDryRun struct {
+	Fn   string   `validate:"required" json:"fn"`
+	Args []string `validate:"omitempty" json:"args"`
+}
@@ -29,8 +29,36 @@ const (
 	AlgoType
 	TraintupleType
 	TesttupleType
+	NodeType
+	PredicttupleType
+	ModelType
 )
 
+// String returns the name of the asset type, as used in indexes and events
+func (assetType AssetType) String() string {
+	switch assetType {
+	case ObjectiveType:
+		return "objective"
+	case DataManagerType:
+		return "dataManager"
+	case DataSampleType:
+		return "dataSample"
+	case AlgoType:
+		return "algo"
+	case TraintupleType:
+		return "traintuple"
+	case TesttupleType:
+		return "testtuple"
+	case NodeType:
+		return "node"
+	case PredicttupleType:
+		return "predicttuple"
+	case ModelType:
+		return "model"
+	}
+	return "unknown"
+}
+
 // Objective is the representation of one of the element type stored in the ledger
 type Objective struct {
 	Name                      string         `json:"name"`
@@ -38,20 +66,51 @@ type Objective struct {
 	DescriptionStorageAddress string         `json:"descriptionStorageAddress"`
 	Metrics                   *HashDressName `json:"metrics"`
 	Owner                     string         `json:"owner"`
-	TestDataset               *Dataset       `json:"testDataset"`
-	Permissions               Permissions    `json:"permissions"`
+	// TestDataset is the first of the TestDatasets
+	TestDataset *Dataset `json:"testDataset"`
+	// TestDatasets lists the test datasets of an objective evaluated on several data managers,
+	// it is empty for an objective with a single test dataset
+	TestDatasets []*Dataset  `json:"testDatasets,omitempty"`
+	Permissions  Permissions `json:"permissions"`
+	// Timeout is the number of seconds the tuples of the objective can stay doing, 0 for no limit
+	Timeout int `json:"timeout"`
+	// AutoEvaluate evaluates the traintuples of the objective once done, except the ones of a
+	// compute plan which opted out and the ones tagged with one of the SkipEvaluationTags
+	AutoEvaluate       bool     `json:"autoEvaluate"`
+	SkipEvaluationTags []string `json:"skipEvaluationTags"`
+	// Challenge is set for an objective run as a competition
+	Challenge *Challenge `json:"challenge"`
+	// PrivateLeaderboardRevealed shows the private perfs of the certified testtuples
+	PrivateLeaderboardRevealed bool `json:"privateLeaderboardRevealed"`
 }
 
-// DataManager is the representation of one of the elements type stored in the ledger
+// Challenge describes the rules of an objective run as a competition. Its certified testtuples can only be
+// created from StartDate until EndDate, optional RFC 3339 times, and by each node at most MaxDailySubmissions
+// times per UTC day, 0 for no limit. FinalLeaderboard is the snapshot of the ranked entries of the
+// certified evaluations created until EndDate, with their perfs and creator, taken at FrozenDate once
+// the challenge ended.
+type Challenge struct {
+	StartDate           string            `json:"startDate"`
+	EndDate             string            `json:"endDate"`
+	MaxDailySubmissions int               `json:"maxDailySubmissions"`
+	FinalLeaderboard    outputBoardTuples `json:"finalLeaderboard"`
+	FrozenDate          string            `json:"frozenDate"`
+}
+
+// DataManager is the representation of one of the elements type stored in the ledger.
+// The opener storage address is stored in the openerAddresses private data collection,
+// only its hash being stored in the public state. LegacyOpenerStorageAddress is only set
+// on the dataManagers stored before, until they are migrated.
 type DataManager struct {
-	Name                 string      `json:"name"`
-	AssetType            AssetType   `json:"assetType"`
-	OpenerStorageAddress string      `json:"openerStorageAddress"`
-	Type                 string      `json:"type"`
-	Description          *HashDress  `json:"description"`
-	Owner                string      `json:"owner"`
-	ObjectiveKey         string      `json:"objectiveKey"`
-	Permissions          Permissions `json:"permissions"`
+	Name                       string      `json:"name"`
+	AssetType                  AssetType   `json:"assetType"`
+	OpenerStorageAddressHash   string      `json:"openerStorageAddressHash"`
+	Type                       string      `json:"type"`
+	Description                *HashDress  `json:"description"`
+	Owner                      string      `json:"owner"`
+	ObjectiveKey               string      `json:"objectiveKey"`
+	Permissions                Permissions `json:"permissions"`
+	LegacyOpenerStorageAddress string      `json:"openerStorageAddress,omitempty"`
 }
 
 // DataSample is the representation of one of the element type stored in the ledger
@@ -72,47 +131,160 @@ type Algo struct {
 	Permissions    Permissions `json:"permissions"`
 }
 
+// ExternalModel is the representation of one of the element type stored in the ledger, the model
+// asset. It describes a model trained outside the platform, stored under its hash, which can be used
+// in place of the out-model of a traintuple. AlgoKey is the algo the model is compatible with. The
+// storage address is stored in the modelAddresses private data collection, only its hash being
+// stored in the public state.
+type ExternalModel struct {
+	Name               string      `json:"name"`
+	AssetType          AssetType   `json:"assetType"`
+	AlgoKey            string      `json:"algoKey"`
+	StorageAddressHash string      `json:"storageAddressHash"`
+	Owner              string      `json:"owner"`
+	Permissions        Permissions `json:"permissions"`
+}
+
 // Traintuple is the representation of one the element type stored in the ledger. It describes a training task occuring on the platform
 type Traintuple struct {
-	AssetType     AssetType   `json:"assetType"`
-	AlgoKey       string      `json:"algoKey"`
-	Creator       string      `json:"creator"`
-	Dataset       *Dataset    `json:"dataset"`
-	ComputePlanID string      `json:"computePlanID"`
-	InModelKeys   []string    `json:"inModels"`
-	Log           string      `json:"log"`
-	ObjectiveKey  string      `json:"objectiveKey"`
-	OutModel      *HashDress  `json:"outModel"`
-	Perf          float32     `json:"perf"`
-	Permissions   Permissions `json:"permissions"`
-	Rank          int         `json:"rank"`
-	Status        string      `json:"status"`
-	Tag           string      `json:"tag"`
+	AssetType AssetType `json:"assetType"`
+	AlgoKey   string    `json:"algoKey"`
+	// Attempts is the number of times the traintuple has been started
+	Attempts      int      `json:"attempts"`
+	CreationDate  string   `json:"creationDate"`
+	Creator       string   `json:"creator"`
+	Dataset       *Dataset `json:"dataset"`
+	ComputePlanID string   `json:"computePlanID"`
+	// FailureReport describes why the traintuple failed
+	FailureReport *FailureReport `json:"failureReport"`
+	// FullLog references the complete log file, the log being a summary
+	FullLog     *HashDress `json:"fullLog"`
+	InModelKeys []string   `json:"inModels"`
+	// LogHash is the hash of the log stored in the tupleLogs private data collection
+	LogHash      string `json:"logHash"`
+	ObjectiveKey string `json:"objectiveKey"`
+	// OutModel only stores the hash of the storage address, which is stored in the
+	// modelAddresses private data collection
+	OutModel    *HashDress  `json:"outModel"`
+	Perf        float32     `json:"perf"`
+	Permissions Permissions `json:"permissions"`
+	// Priority sorts the queue of the worker, the highest first
+	Priority int `json:"priority"`
+	// Progress is the last progress report of the worker
+	Progress *Progress `json:"progress"`
+	Rank     int       `json:"rank"`
+	// SkipEvaluation is set on the traintuples of a compute plan which opted out of the
+	// automatic evaluation of the objective
+	SkipEvaluation bool `json:"skipEvaluation"`
+	// StartDate is the time of the last start or progress report, from which the timeout runs
+	StartDate string `json:"startDate"`
+	Status    string `json:"status"`
+	Tag       string `json:"tag"`
+	// Timeout is the number of seconds the traintuple can stay doing before it can be
+	// reclaimed, 0 for no limit
+	Timeout int `json:"timeout"`
+	// LegacyLog is only set on the traintuples stored before the tupleLogs collection,
+	// until they are migrated
+	LegacyLog string `json:"log,omitempty"`
 }
 
 // Testtuple is the representation of one the element type stored in the ledger. It describes a training task occuring on the platform
 type Testtuple struct {
-	AssetType    AssetType   `json:"assetType"`
-	AlgoKey      string      `json:"algo"`
-	Certified    bool        `json:"certified"`
-	Creator      string      `json:"creator"`
-	Dataset      *TtDataset  `json:"dataset"`
-	Log          string      `json:"log"`
-	Model        *Model      `json:"model"`
+	AssetType AssetType `json:"assetType"`
+	AlgoKey   string    `json:"algo"`
+	// Attempts, StartDate, Timeout and Priority are the same as the ones of the traintuples,
+	// StartDate only being set by the start of the testtuple
+	Attempts     int        `json:"attempts"`
+	Certified    bool       `json:"certified"`
+	CreationDate string     `json:"creationDate"`
+	Creator      string     `json:"creator"`
+	Dataset      *TtDataset `json:"dataset"`
+	// FailureReport describes why the testtuple failed
+	FailureReport *FailureReport `json:"failureReport"`
+	// FullLog references the complete log file, the log being a summary
+	FullLog *HashDress `json:"fullLog"`
+	// LogHash is the hash of the log stored in the tupleLogs private data collection
+	LogHash string `json:"logHash"`
+	// Model is the first of the Models, its storage address is only stored with the traintuple
+	Model *Model `json:"model"`
+	// Models lists the models of an ensemble testtuple, evaluated together, it is empty for a
+	// testtuple evaluating a single model
+	Models       []*Model    `json:"models,omitempty"`
 	ObjectiveKey string      `json:"objective"`
 	Permissions  Permissions `json:"permissions"`
+	Priority     int         `json:"priority"`
+	StartDate    string      `json:"startDate"`
 	Status       string      `json:"status"`
 	Tag          string      `json:"tag"`
+	Timeout      int         `json:"timeout"`
+	// LegacyLog is only set on the testtuples stored before the tupleLogs collection,
+	// until they are migrated
+	LegacyLog string `json:"log,omitempty"`
+}
+
+// Predicttuple is the representation of one the element type stored in the ledger. It describes a prediction
+// task running the model of a traintuple on data samples, without computing a perf. The log and the
+// predictions storage address are stored in the tupleLogs and predictionAddresses private data collections,
+// only their hashes being stored in the public state. The other fields are the same as the ones of the testtuples.
+type Predicttuple struct {
+	AssetType     AssetType      `json:"assetType"`
+	AlgoKey       string         `json:"algoKey"`
+	Attempts      int            `json:"attempts"`
+	CreationDate  string         `json:"creationDate"`
+	Creator       string         `json:"creator"`
+	Dataset       *TtDataset     `json:"dataset"`
+	FailureReport *FailureReport `json:"failureReport"`
+	FullLog       *HashDress     `json:"fullLog"`
+	LogHash       string         `json:"logHash"`
+	Model         *Model         `json:"model"`
+	Permissions   Permissions    `json:"permissions"`
+	Predictions   *HashDress     `json:"predictions"`
+	Priority      int            `json:"priority"`
+	StartDate     string         `json:"startDate"`
+	Status        string         `json:"status"`
+	Tag           string         `json:"tag"`
+	Timeout       int            `json:"timeout"`
 }
 
 // ---------------------------------------------------------------------------------
 // Struct used in the representation of elements stored in the ledger
 // ---------------------------------------------------------------------------------
 
-// HashDress stores a hash and a Storage Address
+// HashDress stores a hash and a Storage Address. A sensitive storage address is stored
+// in a private data collection, only its hash being stored in the public state.
 type HashDress struct {
-	Hash           string `json:"hash"`
-	StorageAddress string `json:"storageAddress"`
+	Hash               string `json:"hash"`
+	StorageAddress     string `json:"storageAddress"`
+	StorageAddressHash string `json:"storageAddressHash,omitempty"`
+}
+
+// FailureReport describes the failure of a tuple
+type FailureReport struct {
+	// ErrorType is the category of the error
+	ErrorType string `json:"errorType"`
+	// Stage is the step of the tuple which failed
+	Stage string `json:"stage"`
+	// Retryable tells if the tuple could succeed if it was run again
+	Retryable bool `json:"retryable"`
+}
+
+// Progress is a progress report sent by the worker of a running traintuple
+type Progress struct {
+	// Sequence is the number of the report in the history of the traintuple
+	Sequence int `json:"sequence"`
+	// Step is the current epoch or step of the training
+	Step       int                `json:"step"`
+	Percentage float32            `json:"percentage"`
+	Metrics    map[string]float32 `json:"metrics"`
+	// Timestamp is the time of the transaction, in RFC 3339 format
+	Timestamp string `json:"timestamp"`
+	TxID      string `json:"txID"`
+}
+
+// ProgressReport stores a progress report in the history of a traintuple
+type ProgressReport struct {
+	Progress
+	TraintupleKey string `json:"traintupleKey"`
 }
 
 // HashDressName stores a hash, storage address and a name
@@ -122,30 +294,39 @@ type HashDressName struct {
 	StorageAddress string `json:"storageAddress"`
 }
 
-// Model stores the traintupleKey leading to the model, its hash and storage addressl
+// Model stores the traintupleKey leading to the model, or the key of a registered model, its hash and storage addressl
 type Model struct {
 	TraintupleKey  string `json:"traintupleKey"`
 	Hash           string `json:"hash"`
 	StorageAddress string `json:"storageAddress"`
 }
 
-// Dataset stores info about a dataManagerKey and a list of associated dataSample
+// Dataset stores info about a dataManagerKey and a list of associated dataSample. The PrivateDataSampleKeys
+// of a test dataset are the samples of its private partition, the other ones being public.
 type Dataset struct {
-	DataManagerKey string   `json:"dataManagerKey"`
-	DataSampleKeys []string `json:"dataSampleKeys"`
-	Worker         string   `json:"worker"`
+	DataManagerKey        string   `json:"dataManagerKey"`
+	DataSampleKeys        []string `json:"dataSampleKeys"`
+	PrivateDataSampleKeys []string `json:"privateDataSampleKeys,omitempty"`
+	Worker                string   `json:"worker"`
 }
 
 // ---------------------------------------------------------------------------------
 // Struct used in the representation of outputs when querying some elements
 // ---------------------------------------------------------------------------------
 
-// TtDataset stores info about dataset in a Traintyple (train or test data) and in a PredTuple (later)
+// TtDataset stores info about dataset in a Traintyple (train or test data) and in a Predicttuple.
+// A certified testtuple on a test dataset split in two partitions evaluates both: Perf is the perf on
+// the public samples and PrivatePerf the one on the PrivateDataSampleKeys.
 type TtDataset struct {
-	Worker         string   `json:"worker"`
-	DataSampleKeys []string `json:"keys"`
-	OpenerHash     string   `json:"openerHash"`
-	Perf           float32  `json:"perf"`
+	Worker                string   `json:"worker"`
+	DataSampleKeys        []string `json:"keys"`
+	PrivateDataSampleKeys []string `json:"privateKeys,omitempty"`
+	OpenerHash            string   `json:"openerHash"`
+	Perf                  float32  `json:"perf"`
+	// PrivatePerf is only copied from the privatePerfs private data collection once the
+	// private leaderboard is revealed, the public state storing its salted hash until then
+	PrivatePerf     float32 `json:"privatePerf,omitempty"`
+	PrivatePerfHash string  `json:"privatePerfHash,omitempty"`
 }
 
 // TtObjective stores info about a objective in a Traintuple
@@ -154,6 +335,21 @@ type TtObjective struct {
 	Metrics *HashDress `json:"metrics"`
 }
 
+// OutboxEvent stores the tuples sent to a worker in a transaction event, so that
+// the worker can fetch them again if it missed the event
+type OutboxEvent struct {
+	TuplesEvent
+	Sequence int    `json:"sequence"`
+	TxID     string `json:"txID"`
+	Worker   string `json:"worker"`
+}
+
+// OutboxSequence stores the sequence number of the last event stored for a worker
+type OutboxSequence struct {
+	Sequence int    `json:"sequence"`
+	Worker   string `json:"worker"`
+}
+
 // Node stores informations about node registered into the network,
 // would be used to list authorized nodes for permissions
 type Node struct {
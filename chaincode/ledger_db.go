@@ -18,26 +18,31 @@ import (
 	"chaincode/errors"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
 
-// State is a in-memory representation of the db state
+// State is a in-memory representation of the db state.
+// Since Fabric does not read the writes of a transaction, it stores the keys read, written
+// or deleted during the transaction, a deleted key being stored with a nil value.
 type State struct {
 	items map[string]([]byte)
 }
 
-// LedgerDB to access the chaincode database during the lifetime of a SmartContract
-type LedgerDB struct {
+// stubStorage stores the assets in the ledger through the chaincode stub
+type stubStorage struct {
 	cc               shim.ChaincodeStubInterface
 	transactionState State
 	mutex            *sync.RWMutex
 }
 
-// NewLedgerDB create a new db to access the chaincode during a SmartContract
-func NewLedgerDB(stub shim.ChaincodeStubInterface) LedgerDB {
-	return LedgerDB{
+// newStubStorage returns a storage backed by the ledger
+func newStubStorage(stub shim.ChaincodeStubInterface) *stubStorage {
+	return &stubStorage{
 		cc: stub,
 		transactionState: State{
 			items: make(map[string]([]byte)),
@@ -46,42 +51,59 @@ func NewLedgerDB(stub shim.ChaincodeStubInterface) LedgerDB {
 	}
 }
 
-// ----------------------------------------------
-// Low-level functions to handle asset structs
-// ----------------------------------------------
-
-// gettransactionState returns a copy of an object that has been updated or created during the transaction
-func (db *LedgerDB) getTransactionState(key string) ([]byte, bool) {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
-	transactionState, ok := db.transactionState.items[key]
+// gettransactionState returns a copy of an object that has been updated or created during the transaction.
+// The state of a deleted object is nil.
+func (s *stubStorage) getTransactionState(key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	transactionState, ok := s.transactionState.items[key]
 	if !ok {
 		return nil, false
 	}
+	if transactionState == nil {
+		return nil, true
+	}
 	state := make([]byte, len(transactionState))
 	copy(state, transactionState)
 	return state, true
 }
 
+// getTransactionKeys returns the keys with a given prefix stored during the transaction,
+// with true for the existing ones and false for the deleted ones
+func (s *stubStorage) getTransactionKeys(prefix string) map[string]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys := make(map[string]bool)
+	for key, state := range s.transactionState.items {
+		if strings.HasPrefix(key, prefix) {
+			keys[key] = state != nil
+		}
+	}
+	return keys
+}
+
 // putTransactionState stores an object during a transaction lifetime
-func (db *LedgerDB) putTransactionState(key string, state []byte) {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
-	db.transactionState.items[key] = state
+func (s *stubStorage) putTransactionState(key string, state []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.transactionState.items[key] = state
 }
 
 // Get retrieves an object stored in the chaincode db and set the input object value
-func (db *LedgerDB) Get(key string, object interface{}) error {
+func (s *stubStorage) Get(key string, object interface{}) error {
 	var buff []byte
 	var err error
 
-	buff, ok := db.getTransactionState(key)
+	buff, ok := s.getTransactionState(key)
 	if !ok {
-		buff, err = db.cc.GetState(key)
+		buff, err = s.cc.GetState(key)
 		if err != nil || buff == nil {
 			return errors.NotFound(err)
 		}
-		db.putTransactionState(key, buff)
+		s.putTransactionState(key, buff)
+	} else if buff == nil {
+		// deleted during the transaction
+		return errors.NotFound()
 	}
 
 	if err = json.Unmarshal(buff, &object); err != nil {
@@ -90,83 +112,86 @@ func (db *LedgerDB) Get(key string, object interface{}) error {
 	return nil
 }
 
-// KeyExists checks if a key is stored in the chaincode db
-func (db *LedgerDB) KeyExists(key string) (bool, error) {
-	buff, err := db.cc.GetState(key)
+// KeyExists checks if a key is stored in the chaincode db, including the writes of the transaction
+func (s *stubStorage) KeyExists(key string) (bool, error) {
+	if buff, ok := s.getTransactionState(key); ok {
+		return buff != nil, nil
+	}
+	buff, err := s.cc.GetState(key)
 	return buff != nil, err
 }
 
 // Put stores an object in the chaincode db, if the object already exists it is replaced
-func (db *LedgerDB) Put(key string, object interface{}) error {
+func (s *stubStorage) Put(key string, object interface{}) error {
 	buff, _ := json.Marshal(object)
 
-	if err := db.cc.PutState(key, buff); err != nil {
+	if err := s.cc.PutState(key, buff); err != nil {
 		return err
 	}
 	// TransactionState is updated to ensure that even if the data is not committed, a further
 	// call to get this struct will returned the updated one (and not the original one).
-	// This is currently required when setting the statuses of the traintuple children.
-	db.putTransactionState(key, buff)
+	s.putTransactionState(key, buff)
 
 	return nil
 }
 
 // Add stores an object in the chaincode db, it fails if the object already exists
-func (db *LedgerDB) Add(key string, object interface{}) error {
-	ok, err := db.KeyExists(key)
-	if err != nil {
+func (s *stubStorage) Add(key string, object interface{}) error {
+	return addObject(s, key, object)
+}
+
+// Delete removes an object from the chaincode db
+func (s *stubStorage) Delete(key string) error {
+	if err := s.cc.DelState(key); err != nil {
 		return err
 	}
-	if ok {
-		return errors.Conflict("struct already exists (tkey: %s)", key).WithKey(key)
-	}
-	return db.Put(key, object)
+	s.putTransactionState(key, nil)
+	return nil
 }
 
-// ----------------------------------------------
-// Low-level functions to handle indexes
-// ----------------------------------------------
-
 // CreateIndex adds a new composite key to the chaincode db
-func (db *LedgerDB) CreateIndex(index string, attributes []string) error {
-	compositeKey, err := db.cc.CreateCompositeKey(index, attributes)
+func (s *stubStorage) CreateIndex(index string, attributes []string) error {
+	compositeKey, err := s.cc.CreateCompositeKey(index, attributes)
 	if err != nil {
 		return fmt.Errorf("cannot create index %s: %s", index, err.Error())
 	}
 	value := []byte{0x00}
-	if err = db.cc.PutState(compositeKey, value); err != nil {
+	if err = s.cc.PutState(compositeKey, value); err != nil {
 		return fmt.Errorf("cannot create index %s: %s", index, err.Error())
 	}
+	s.putTransactionState(compositeKey, value)
 	return nil
 }
 
 // DeleteIndex deletes a composite key in the chaincode db
-func (db *LedgerDB) DeleteIndex(index string, attributes []string) error {
-	compositeKey, err := db.cc.CreateCompositeKey(index, attributes)
+func (s *stubStorage) DeleteIndex(index string, attributes []string) error {
+	compositeKey, err := s.cc.CreateCompositeKey(index, attributes)
 	if err != nil {
 		return err
 	}
-	if err = db.cc.DelState(compositeKey); err != nil {
+	if err = s.cc.DelState(compositeKey); err != nil {
 		return err
 	}
+	s.putTransactionState(compositeKey, nil)
 	return nil
 }
 
 // UpdateIndex updates an existing composite key in the chaincode db
-func (db *LedgerDB) UpdateIndex(index string, oldAttributes []string, newAttribues []string) error {
-	if err := db.DeleteIndex(index, oldAttributes); err != nil {
-		return err
-	}
-	if err := db.CreateIndex(index, newAttribues); err != nil {
-		return err
-	}
-	return nil
+func (s *stubStorage) UpdateIndex(index string, oldAttributes []string, newAttributes []string) error {
+	return updateIndex(s, index, oldAttributes, newAttributes)
 }
 
-// GetIndexKeys returns keys matching composite key values from the chaincode db
-func (db *LedgerDB) GetIndexKeys(index string, attributes []string) ([]string, error) {
-	keys := make([]string, 0)
-	iterator, err := db.cc.GetStateByPartialCompositeKey(index, attributes)
+// compositeKeys returns the sorted composite keys matching the first attributes of an index.
+// The composite keys created or deleted during the transaction are taken into account.
+func (s *stubStorage) compositeKeys(index string, attributes []string) ([]string, error) {
+	partialKey, err := s.cc.CreateCompositeKey(index, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("get index %s failed: %s", index, err.Error())
+	}
+	transactionKeys := s.getTransactionKeys(partialKey)
+
+	compositeKeys := []string{}
+	iterator, err := s.cc.GetStateByPartialCompositeKey(index, attributes)
 	if err != nil {
 		return nil, fmt.Errorf("get index %s failed: %s", index, err.Error())
 	}
@@ -176,13 +201,250 @@ func (db *LedgerDB) GetIndexKeys(index string, attributes []string) ([]string, e
 		if err != nil {
 			return nil, err
 		}
-		_, keyParts, err := db.cc.SplitCompositeKey(compositeKey.Key)
-		if err != nil {
-			return nil, fmt.Errorf("get index %s failed: cannot split key %s: %s", index, compositeKey.Key, err.Error())
+		if _, ok := transactionKeys[compositeKey.Key]; !ok {
+			compositeKeys = append(compositeKeys, compositeKey.Key)
+		}
+	}
+	for compositeKey, exists := range transactionKeys {
+		if exists {
+			compositeKeys = append(compositeKeys, compositeKey)
 		}
-		keys = append(keys, keyParts[len(keyParts)-1])
 	}
-	return keys, nil
+	// Keep the order of the ledger
+	sort.Strings(compositeKeys)
+	return compositeKeys, nil
+}
+
+// GetIndexKeys returns keys matching composite key values from the chaincode db
+func (s *stubStorage) GetIndexKeys(index string, attributes []string) ([]string, error) {
+	compositeKeys, err := s.compositeKeys(index, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return indexKeys(index, compositeKeys)
+}
+
+// ForEachIndexKey calls fn on at most limit composite keys of the chaincode db after the cursor
+func (s *stubStorage) ForEachIndexKey(index string, attributes []string, cursor string, limit int, fn func(attributes []string) error) (string, int, error) {
+	compositeKeys, err := s.compositeKeys(index, attributes)
+	if err != nil {
+		return "", 0, err
+	}
+	return forEachCompositeKey(compositeKeys, cursor, limit, fn)
+}
+
+// TransactionContext is the context of the transaction a smart contract is run in. The
+// chaincode stub implements it on a peer, and MemoryContext outside of a peer.
+type TransactionContext interface {
+	// GetFunctionAndParameters returns the smart contract called by the transaction and its args
+	GetFunctionAndParameters() (string, []string)
+	// GetCreator returns the serialized identity of the creator of the transaction
+	GetCreator() ([]byte, error)
+	// GetTxID returns the id of the transaction
+	GetTxID() string
+	// GetTxTimestamp returns the timestamp set by the creator of the transaction
+	GetTxTimestamp() (*timestamp.Timestamp, error)
+	// GetTransient returns the transient map of the transaction, which is not recorded in the ledger
+	GetTransient() (map[string][]byte, error)
+	// GetStateValidationParameter returns the endorsement policy of a key
+	GetStateValidationParameter(key string) ([]byte, error)
+	// SetStateValidationParameter sets the endorsement policy of a key
+	SetStateValidationParameter(key string, ep []byte) error
+	// GetPrivateData returns the value of a key in a private data collection
+	GetPrivateData(collection string, key string) ([]byte, error)
+	// PutPrivateData stores the value of a key in a private data collection
+	PutPrivateData(collection string, key string, value []byte) error
+	// DelPrivateData removes a key from a private data collection
+	DelPrivateData(collection string, key string) error
+	// SetEvent sets the event of the transaction
+	SetEvent(name string, payload []byte) error
+}
+
+// LedgerDB to access the chaincode database during the lifetime of a SmartContract.
+// It stores the assets and gives the context of the transaction.
+type LedgerDB interface {
+	Storage
+	TransactionContext
+
+	GetAlgo(key string) (Algo, error)
+	GetObjective(key string) (Objective, error)
+	GetDataManager(key string) (DataManager, error)
+	GetDataSample(key string) (DataSample, error)
+	GetTraintuple(key string) (Traintuple, error)
+	GetTesttuple(key string) (Testtuple, error)
+	GetPredicttuple(key string) (Predicttuple, error)
+	GetExternalModel(key string) (ExternalModel, error)
+	GetNode(key string) (Node, error)
+	GetConfig() (Config, error)
+	GetEndorsementPolicy(key string) ([]string, error)
+
+	PutPrivateField(collection string, key string, value string) (string, error)
+	AppendPrivateField(collection string, key string, hash string, value string) (string, error)
+	GetPrivateField(collection string, key string, hash string) (string, error)
+	IsCollectionMember(collection string) (bool, error)
+
+	AddTuplesEvent(event TuplesEvent)
+	SendEvent() error
+	Check(err error) error
+	IsDryRun() bool
+
+	// readPrivateData returns the value of a key in a private data collection, including the
+	// writes of the transaction
+	readPrivateData(collection string, key string) ([]byte, error)
+	// setEventFunction sets the smart contract reported with the assets updated afterwards
+	setEventFunction(fn string)
+}
+
+// ledgerDB is the LedgerDB storing the assets in a Storage, the ledger by default,
+// and recording them in the transaction event
+type ledgerDB struct {
+	TransactionContext
+	storage Storage
+	// private caches the sensitive fields of the assets stored in private data collections
+	private privateDataCache
+	event   *Event
+	// dryRun gathers the errors of a dry run, nil if the writes are committed
+	dryRun *dryRunReport
+}
+
+// NewLedgerDB create a new db to access the chaincode during a SmartContract
+func NewLedgerDB(stub shim.ChaincodeStubInterface) LedgerDB {
+	return NewLedgerDBWithStorage(stub, newStorage(stub))
+}
+
+// NewLedgerDBWithStorage create a new db storing the assets in the given storage,
+// in the context of a transaction
+func NewLedgerDBWithStorage(ctx TransactionContext, storage Storage) LedgerDB {
+	return newLedgerDB(ctx, storage)
+}
+
+// newLedgerDB returns the ledgerDB of a transaction
+func newLedgerDB(ctx TransactionContext, storage Storage) *ledgerDB {
+	fn, _ := ctx.GetFunctionAndParameters()
+	return &ledgerDB{
+		TransactionContext: ctx,
+		storage:            storage,
+		private:            newPrivateDataCache(),
+		event:              NewEvent(fn),
+	}
+}
+
+// newStorage returns the storage of the assets of a transaction: the ledger, with the
+// asset types of privateCollections routed to their private data collection
+func newStorage(stub shim.ChaincodeStubInterface) Storage {
+	var storage Storage = newStubStorage(stub)
+	if len(privateCollections) > 0 {
+		storage = newPrivateDataStorage(stub, storage, privateCollections)
+	}
+	return storage
+}
+
+// ----------------------------------------------
+// Low-level functions to handle asset structs
+// ----------------------------------------------
+
+// Get retrieves an object stored in the chaincode db and set the input object value
+func (db *ledgerDB) Get(key string, object interface{}) error {
+	return db.storage.Get(key, object)
+}
+
+// KeyExists checks if a key is stored in the chaincode db, including the writes of the transaction
+func (db *ledgerDB) KeyExists(key string) (bool, error) {
+	return db.storage.KeyExists(key)
+}
+
+// Put stores an object in the chaincode db, if the object already exists it is replaced
+func (db *ledgerDB) Put(key string, object interface{}) error {
+	db.addEventAsset(key, object)
+	if err := db.storage.Put(key, object); err != nil {
+		return err
+	}
+	return setEndorsementPolicy(db, key, object)
+}
+
+// Delete removes an object from the chaincode db
+func (db *ledgerDB) Delete(key string) error {
+	return db.storage.Delete(key)
+}
+
+// Add stores an object in the chaincode db, it fails if the object already exists
+func (db *ledgerDB) Add(key string, object interface{}) error {
+	return addObject(db, key, object)
+}
+
+// addEventAsset adds an asset about to be stored to the transaction event,
+// with the status it had before the transaction
+func (db *ledgerDB) addEventAsset(key string, object interface{}) {
+	assetType, newStatus, ok := eventAssetInfo(object)
+	if !ok {
+		return
+	}
+	oldStatus := ""
+	if !db.event.HasAsset(key) {
+		stored := struct {
+			Status string `json:"status"`
+		}{}
+		if db.storage.Get(key, &stored) == nil {
+			oldStatus = stored.Status
+		}
+	}
+	db.event.AddAsset(assetType, key, oldStatus, newStatus)
+}
+
+// ----------------------------------------------
+// Low-level functions to handle events
+// ----------------------------------------------
+
+// setEventFunction sets the smart contract reported with the assets updated afterwards
+func (db *ledgerDB) setEventFunction(fn string) {
+	db.event.function = fn
+}
+
+// AddTuplesEvent adds tuples to be processed by the workers to the transaction event
+func (db *ledgerDB) AddTuplesEvent(event TuplesEvent) {
+	db.event.AddTuplesEvent(event)
+}
+
+// SendEvent sends the transaction event if any tuple or asset has been updated.
+// The tuples are also stored in the outbox of their workers.
+func (db *ledgerDB) SendEvent() error {
+	if db.event.IsEmpty() {
+		return nil
+	}
+	if err := storeOutboxEvents(db, db.event.TuplesEvent); err != nil {
+		return err
+	}
+	return SendTuplesEvent(db, db.event)
+}
+
+// ----------------------------------------------
+// Low-level functions to handle indexes
+// ----------------------------------------------
+
+// CreateIndex adds a new composite key to the chaincode db
+func (db *ledgerDB) CreateIndex(index string, attributes []string) error {
+	return db.storage.CreateIndex(index, attributes)
+}
+
+// DeleteIndex deletes a composite key in the chaincode db
+func (db *ledgerDB) DeleteIndex(index string, attributes []string) error {
+	return db.storage.DeleteIndex(index, attributes)
+}
+
+// UpdateIndex updates an existing composite key in the chaincode db
+func (db *ledgerDB) UpdateIndex(index string, oldAttributes []string, newAttributes []string) error {
+	return db.storage.UpdateIndex(index, oldAttributes, newAttributes)
+}
+
+// GetIndexKeys returns keys matching composite key values from the chaincode db.
+// The composite keys created or deleted during the transaction are taken into account.
+func (db *ledgerDB) GetIndexKeys(index string, attributes []string) ([]string, error) {
+	return db.storage.GetIndexKeys(index, attributes)
+}
+
+// ForEachIndexKey calls fn on at most limit composite keys of an index after the cursor
+func (db *ledgerDB) ForEachIndexKey(index string, attributes []string, cursor string, limit int, fn func(attributes []string) error) (string, int, error) {
+	return db.storage.ForEachIndexKey(index, attributes, cursor, limit, fn)
 }
 
 // ----------------------------------------------
@@ -190,7 +452,7 @@ func (db *LedgerDB) GetIndexKeys(index string, attributes []string) ([]string, e
 // ----------------------------------------------
 
 // GetAlgo fetches an Algo from the ledger using its unique key
-func (db *LedgerDB) GetAlgo(key string) (Algo, error) {
+func (db *ledgerDB) GetAlgo(key string) (Algo, error) {
 	algo := Algo{}
 	if err := db.Get(key, &algo); err != nil {
 		return algo, err
@@ -202,7 +464,7 @@ func (db *LedgerDB) GetAlgo(key string) (Algo, error) {
 }
 
 // GetObjective fetches an Objective from the ledger using its unique key
-func (db *LedgerDB) GetObjective(key string) (Objective, error) {
+func (db *ledgerDB) GetObjective(key string) (Objective, error) {
 	objective := Objective{}
 	if err := db.Get(key, &objective); err != nil {
 		return objective, err
@@ -214,7 +476,7 @@ func (db *LedgerDB) GetObjective(key string) (Objective, error) {
 }
 
 // GetDataManager fetches a DataManager from the ledger using its unique key
-func (db *LedgerDB) GetDataManager(key string) (DataManager, error) {
+func (db *ledgerDB) GetDataManager(key string) (DataManager, error) {
 	dataManager := DataManager{}
 	if err := db.Get(key, &dataManager); err != nil {
 		return dataManager, err
@@ -226,7 +488,7 @@ func (db *LedgerDB) GetDataManager(key string) (DataManager, error) {
 }
 
 // GetDataSample fetches a DataSample from the ledger using its unique key
-func (db *LedgerDB) GetDataSample(key string) (DataSample, error) {
+func (db *ledgerDB) GetDataSample(key string) (DataSample, error) {
 	dataSample := DataSample{}
 	if err := db.Get(key, &dataSample); err != nil {
 		return dataSample, err
@@ -238,7 +500,7 @@ func (db *LedgerDB) GetDataSample(key string) (DataSample, error) {
 }
 
 // GetTraintuple fetches a Traintuple from the ledger using its unique key
-func (db *LedgerDB) GetTraintuple(key string) (Traintuple, error) {
+func (db *ledgerDB) GetTraintuple(key string) (Traintuple, error) {
 	traintuple := Traintuple{}
 	if err := db.Get(key, &traintuple); err != nil {
 		return traintuple, err
@@ -250,7 +512,7 @@ func (db *LedgerDB) GetTraintuple(key string) (Traintuple, error) {
 }
 
 // GetTesttuple fetches a Testtuple from the ledger using its unique key
-func (db *LedgerDB) GetTesttuple(key string) (Testtuple, error) {
+func (db *ledgerDB) GetTesttuple(key string) (Testtuple, error) {
 	testtuple := Testtuple{}
 	if err := db.Get(key, &testtuple); err != nil {
 		return testtuple, err
@@ -261,8 +523,32 @@ func (db *LedgerDB) GetTesttuple(key string) (Testtuple, error) {
 	return testtuple, nil
 }
 
+// GetPredicttuple fetches a Predicttuple from the ledger using its unique key
+func (db *ledgerDB) GetPredicttuple(key string) (Predicttuple, error) {
+	predicttuple := Predicttuple{}
+	if err := db.Get(key, &predicttuple); err != nil {
+		return predicttuple, err
+	}
+	if predicttuple.AssetType != PredicttupleType {
+		return predicttuple, errors.NotFound("predicttuple %s not found", key)
+	}
+	return predicttuple, nil
+}
+
+// GetExternalModel fetches a model registered with registerModel from the ledger using its unique key
+func (db *ledgerDB) GetExternalModel(key string) (ExternalModel, error) {
+	model := ExternalModel{}
+	if err := db.Get(key, &model); err != nil {
+		return model, err
+	}
+	if model.AssetType != ModelType {
+		return model, errors.NotFound("model %s not found", key)
+	}
+	return model, nil
+}
+
 // GetNode fetches a Node from the ledger based on its unique key
-func (db *LedgerDB) GetNode(key string) (Node, error) {
+func (db *ledgerDB) GetNode(key string) (Node, error) {
 	node := Node{}
 
 	err := db.Get(key, &node)
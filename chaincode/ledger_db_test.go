@@ -0,0 +1,77 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerDBReadYourWrites(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	myStub := myMockStub{MockStub: mockStub}
+
+	// Store an object and an index in the ledger
+	myStub.MockTransactionStart("committed")
+	db := NewLedgerDB(&myStub)
+	require.NoError(t, db.Put("committed", Node{ID: worker}))
+	require.NoError(t, db.CreateIndex("test~key", []string{"test", "committed"}))
+	myStub.saveWrittenState(t)
+	myStub.MockTransactionEnd("committed")
+
+	// A transaction which cannot read its writes from the ledger
+	myStub.MockTransactionStart("42")
+	db = NewLedgerDB(&myStub)
+	require.NoError(t, db.Add("new", Node{ID: worker}))
+	exists, err := db.KeyExists("new")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	err = db.Add("new", Node{ID: worker})
+	assert.Equal(t, errors.Conflict().Kind, errors.Wrap(err).Kind)
+
+	require.NoError(t, db.Delete("committed"))
+	exists, err = db.KeyExists("committed")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	err = db.Get("committed", &Node{})
+	assert.Equal(t, errors.NotFound().Kind, errors.Wrap(err).Kind)
+	// The object can be stored again
+	require.NoError(t, db.Add("committed", Node{ID: worker}))
+
+	keys, err := db.GetIndexKeys("test~key", []string{"test"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed"}, keys)
+	require.NoError(t, db.CreateIndex("test~key", []string{"test", "new"}))
+	require.NoError(t, db.DeleteIndex("test~key", []string{"test", "committed"}))
+	keys, err = db.GetIndexKeys("test~key", []string{"test"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, keys)
+	require.NoError(t, db.CreateIndex("test~key", []string{"test", "committed"}))
+	keys, err = db.GetIndexKeys("test~key", []string{"test"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed", "new"}, keys)
+
+	// Nothing has been written in the ledger yet
+	buff, _ := mockStub.GetState("new")
+	assert.Nil(t, buff)
+	myStub.saveWrittenState(t)
+	myStub.MockTransactionEnd("42")
+	buff, _ = mockStub.GetState("new")
+	assert.NotNil(t, buff)
+}
@@ -33,13 +33,24 @@ var logger = shim.NewLogger("substra-chaincode")
 // Init is called during chaincode instantiation to initialize any
 // data. Note that chaincode upgrade also calls this function to reset
 // or to migrate data.
-// TODO!!!!
+// The optional arg is the configuration of the channel, only used if none is stored yet.
+// It runs the first batch of the pending migrations, the next ones being run
+// by the migrate smart contract.
 func (t *SubstraChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
 	// Get the args from the transaction proposal
 	args := stub.GetStringArgs()
-	if len(args) != 1 {
-		return shim.Error("Incorrect arguments. Expecting nothing...")
+	if len(args) != 1 && len(args) != 2 {
+		return shim.Error("Incorrect arguments. Expecting nothing or the configuration...")
 	}
+	db := NewLedgerDB(stub)
+	if err := seedConfig(db, args[1:]); err != nil {
+		return formatErrorResponse(err, "")
+	}
+	schema, err := runMigrations(db, migrationBatchSize)
+	if err != nil {
+		return formatErrorResponse(errors.E(err, "migration failed:"), "")
+	}
+	logger.Infof("schema version %d/%d (cursor: %s)", schema.Version, len(migrations), schema.Cursor)
 	return shim.Success(nil)
 }
 
@@ -53,8 +64,27 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 
 	db := NewLedgerDB(stub)
 
-	var result interface{}
-	var err error
+	result, err := dispatch(db, fn, args)
+	// Send the event gathering all the updates of the transaction
+	if err == nil {
+		err = db.SendEvent()
+	}
+	logger.Infof("Response from chaincode: %#v, error: %s", result, err)
+	// Return the result as success payload
+	if err != nil {
+		return formatErrorResponse(err, getLocale(stub))
+	}
+	// Marshal to json the smartcontract result
+	resp, err := json.Marshal(result)
+	if err != nil {
+		return formatErrorResponse(fmt.Errorf("could not format response for unknown reason"), getLocale(stub))
+	}
+
+	return shim.Success(resp)
+}
+
+// dispatch calls the smart contract fn with its args
+func dispatch(db LedgerDB, fn string, args []string) (result interface{}, err error) {
 	switch fn {
 	case "createComputePlan":
 		result, err = createComputePlan(db, args)
@@ -122,43 +152,96 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		result, err = registerNode(db, args)
 	case "queryNodes":
 		result, err = queryNodes(db, args)
+	case "queryEventsSince":
+		result, err = queryEventsSince(db, args)
+	case "ackEvents":
+		result, err = ackEvents(db, args)
+	case "batch":
+		result, err = batch(db, args)
+	case "dryRun":
+		result, err = dryRun(db, args)
+	case "migrate":
+		result, err = migrate(db, args)
+	case "queryVersion":
+		result, err = queryVersion(db, args)
+	case "queryConfig":
+		result, err = queryConfig(db, args)
+	case "updateConfig":
+		result, err = updateConfig(db, args)
+	case "queryEndorsementPolicy":
+		result, err = queryEndorsementPolicy(db, args)
+	case "queryEndorsementPolicies":
+		result, err = queryEndorsementPolicies(db, args)
+	case "logProgress":
+		result, err = logProgress(db, args)
+	case "queryTraintupleProgress":
+		result, err = queryTraintupleProgress(db, args)
+	case "reclaimStaleTuples":
+		result, err = reclaimStaleTuples(db, args)
+	case "queryWorkerQueue":
+		result, err = queryWorkerQueue(db, args)
+	case "updateTuplePriority":
+		result, err = updateTuplePriority(db, args)
+	case "createPredicttuple":
+		result, err = createPredicttuple(db, args)
+	case "logStartPredict":
+		result, err = logStartPredict(db, args)
+	case "logSuccessPredict":
+		result, err = logSuccessPredict(db, args)
+	case "logFailPredict":
+		result, err = logFailPredict(db, args)
+	case "queryPredicttuple":
+		result, err = queryPredicttuple(db, args)
+	case "queryPredicttuples":
+		result, err = queryPredicttuples(db, args)
+	case "registerModel":
+		result, err = registerModel(db, args)
+	case "queryModel":
+		result, err = queryModel(db, args)
+	case "freezeObjectiveLeaderboard":
+		result, err = freezeObjectiveLeaderboard(db, args)
+	case "revealPrivateLeaderboard":
+		result, err = revealPrivateLeaderboard(db, args)
 	default:
 		err = fmt.Errorf("function not implemented")
 	}
-	logger.Infof("Response from chaincode: %#v, error: %s", result, err)
-	// Return the result as success payload
-	if err != nil {
-		return formatErrorResponse(err)
-	}
-	// Marshal to json the smartcontract result
-	resp, err := json.Marshal(result)
-	if err != nil {
-		return formatErrorResponse(fmt.Errorf("could not format response for unknown reason"))
-	}
+	return
+}
 
-	return shim.Success(resp)
+// formatErrorResponse serializes an error, its validation messages being translated
+// in the given locale if any
+func formatErrorResponse(err error, locale string) peer.Response {
+	status := errors.Wrap(err).HTTPStatusCode()
+
+	payload, _ := json.Marshal(formatError(err, locale))
+	return peer.Response{
+		Message: string(payload),
+		Payload: payload,
+		Status:  int32(status),
+	}
 }
 
-func formatErrorResponse(err error) peer.Response {
+// formatError returns the description of an error with its status, code and context
+func formatError(err error, locale string) map[string]interface{} {
 	e := errors.Wrap(err)
-	status := e.HTTPStatusCode()
 
 	errStruct := map[string]interface{}{
 		"error": e.Error(),
+		"code":  e.Code(),
 		// Serialize status in the message until fabric-sdk-py allows subtrabac to
 		// access the status
-		"status": status,
+		"status": e.HTTPStatusCode(),
 	}
 	for k, v := range e.GetContext() {
 		errStruct[k] = v
 	}
-
-	payload, _ := json.Marshal(errStruct)
-	return peer.Response{
-		Message: string(payload),
-		Payload: payload,
-		Status:  int32(status),
+	if locale != "" {
+		if message, fields, ok := translateError(err, locale); ok {
+			errStruct["error"] = message
+			errStruct["fields"] = fields
+		}
 	}
+	return errStruct
 }
 
 // main function starts up the chaincode in the container during instantiate
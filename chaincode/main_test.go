@@ -15,6 +15,7 @@
 package main
 
 import (
+	"chaincode/errors"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	peer "github.com/hyperledger/fabric/protos/peer"
 	"github.com/stretchr/testify/assert"
@@ -46,7 +48,13 @@ const algoName = "hog + svm"
 const modelHash = "eedbb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482eed"
 const modelAddress = "https://substrabac/model/toto"
 const worker = "SampleOrg"
+const fullLogHash = "fa1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"
+const fullLogStorageAddress = "https://toto/logs/full.log"
 const traintupleKey = "9da043ddc233996d2e62c196471290de4726fc59d65dbbd2b32a920326e8adf3"
+const externalModelHash = "ee1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482eee"
+
+// pipelineTimestamp is the timestamp of the transactions of TestPipeline: 2019-10-14T08:00:00Z
+const pipelineTimestamp = 1571040000
 
 var (
 	pipeline = flag.Bool("pipeline", false, "Print out the pipeline test output")
@@ -79,11 +87,103 @@ func assetToArgs(asset interface{}) []string {
 	return []string{string(assetToJSON(asset))}
 }
 
+// privateInputs are the sensitive fields removed from the args by assetToJSON, by args.
+// The mock stub passes them in the transient map of the invocations with these args.
+var privateInputs = map[string][]byte{}
+
 func assetToJSON(asset interface{}) []byte {
-	assetjson, _ := json.Marshal(asset)
+	args, private := splitPrivateInput(asset)
+	assetjson, _ := json.Marshal(args)
+	if private != nil {
+		privateInputs[string(assetjson)] = private
+	}
 	return assetjson
 }
 
+// splitPrivateInput returns an input without its fields tagged transient, and the json
+// of the fields which are set, nil if there are none
+func splitPrivateInput(asset interface{}) (interface{}, []byte) {
+	value := reflect.Indirect(reflect.ValueOf(asset))
+	if value.Kind() != reflect.Struct {
+		return asset, nil
+	}
+	args := reflect.New(value.Type()).Elem()
+	args.Set(value)
+	private := reflect.New(value.Type()).Elem()
+	if len(moveTransientFields(private, args, "")) == 0 {
+		return asset, nil
+	}
+	privateJSON, _ := json.Marshal(private.Interface())
+	fields := map[string]interface{}{}
+	json.Unmarshal(privateJSON, &fields)
+	privateJSON, _ = json.Marshal(removeEmptyValues(fields))
+	return args.Interface(), privateJSON
+}
+
+// removeEmptyValues removes the empty values of a json object
+func removeEmptyValues(fields map[string]interface{}) map[string]interface{} {
+	for name, value := range fields {
+		empty := value == nil || value == "" || value == 0.0 || value == false
+		if object, ok := value.(map[string]interface{}); ok {
+			empty = len(removeEmptyValues(object)) == 0
+		}
+		if empty {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// findPrivateInput returns the private input of args built by assetToJSON, looking into
+// the nested args of the batches and dry runs
+func findPrivateInput(arg string) []byte {
+	if private := privateInputs[arg]; private != nil {
+		return private
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(arg), &value); err != nil {
+		return nil
+	}
+	return findNestedPrivateInput(value)
+}
+
+func findNestedPrivateInput(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return findPrivateInput(v)
+	case []interface{}:
+		for _, item := range v {
+			if private := findNestedPrivateInput(item); private != nil {
+				return private
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if private := findNestedPrivateInput(item); private != nil {
+				return private
+			}
+		}
+	}
+	return nil
+}
+
+// withPrivateInput returns the transient map of an invocation, with the private input of
+// its args if they have been built by assetToJSON and the map has none
+func withPrivateInput(transient map[string][]byte, args [][]byte) map[string][]byte {
+	if len(args) != 2 || transient[privateInputTransientKey] != nil {
+		return transient
+	}
+	private := findPrivateInput(string(args[1]))
+	if private == nil {
+		return transient
+	}
+	withPrivate := map[string][]byte{privateInputTransientKey: private}
+	for key, value := range transient {
+		withPrivate[key] = value
+	}
+	return withPrivate
+}
+
 func keyToJSON(key string) []byte {
 	return assetToJSON(inputHash{Key: key})
 }
@@ -94,7 +194,11 @@ func printArgs(buf io.Writer, args [][]byte, command string) {
 		escapedJSON, _ := json.Marshal(string(args[1]))
 		fmt.Fprintf(buf, ",%s", escapedJSON)
 	}
-	fmt.Fprint(buf, "]}' -C myc\n```\n")
+	fmt.Fprint(buf, "]}'")
+	if private := withPrivateInput(nil, args)[privateInputTransientKey]; private != nil {
+		fmt.Fprintf(buf, " --transient \"{\\\"%s\\\":\\\"$(echo -n '%s' | base64 | tr -d \\\\n)\\\"}\"", privateInputTransientKey, private)
+	}
+	fmt.Fprint(buf, " -C myc\n```\n")
 }
 
 func prettyPrintStruct(buf io.Writer, margin string, strucType reflect.Type) {
@@ -108,18 +212,22 @@ func prettyPrintStructElements(buf io.Writer, margin string, strucType reflect.T
 		fieldType := f.Type.Kind()
 		fieldStr := ""
 		switch fieldType {
-		case reflect.Struct:
+		case reflect.Ptr, reflect.Struct:
+			structType := f.Type
+			if fieldType == reflect.Ptr {
+				structType = f.Type.Elem()
+			}
 			if f.Anonymous {
-				prettyPrintStructElements(buf, margin, f.Type)
+				prettyPrintStructElements(buf, margin, structType)
 			} else {
 				fmt.Fprintf(buf, "%s\"%s\": (%s)", margin, f.Tag.Get("json"), f.Tag.Get("validate"))
-				prettyPrintStruct(buf, margin+" ", f.Type)
+				prettyPrintStruct(buf, margin+" ", structType)
 				fmt.Fprint(buf, ",\n")
 			}
 			continue
 		case reflect.Bool:
 			jsonTag := strings.Split(f.Tag.Get("json"), ",")
-			fmt.Fprintf(buf, "%s\"%s\": %s (%s),\n", margin, jsonTag[0], fieldType, jsonTag[1])
+			fmt.Fprintf(buf, "%s\"%s\": %s (%s),\n", margin, jsonTag[0], fieldType, strings.Join(jsonTag[1:], ","))
 			continue
 		case reflect.Slice:
 			if f.Type.Elem().Kind() == reflect.Struct {
@@ -218,6 +326,8 @@ func printResp(buf io.Writer, payload []byte) {
 func TestPipeline(t *testing.T) {
 	scc := new(SubstraChaincode)
 	mockStub := NewMockStub("substra", scc)
+	// pin the transactions timestamp so that the examples do not change between runs
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp}
 	var out strings.Builder
 	callAssertAndPrint := func(peerCmd, smartContract string, inputAsset interface{}) peer.Response {
 		var args [][]byte
@@ -228,7 +338,7 @@ func TestPipeline(t *testing.T) {
 			args = methodToByte(smartContract)
 		}
 		printArgs(&out, args, peerCmd)
-		resp := mockStub.MockInvoke("42", args)
+		resp := mockStub.MockInvoke(smartContract, args)
 		require.EqualValuesf(t, 200, resp.Status, "problem when calling %s, return status %d and message %s", smartContract, resp.Status, resp.Message)
 		printResp(&out, resp.Payload)
 		return resp
@@ -326,12 +436,28 @@ func TestPipeline(t *testing.T) {
 	}
 	callAssertAndPrint("invoke", "queryFilter", filter)
 
+	fmt.Fprintln(&out, "#### ------------ Query the queue of the worker ------------")
+	callAssertAndPrint("query", "queryWorkerQueue", nil)
+
 	fmt.Fprintln(&out, "#### ------------ Log Start Training ------------")
 	callAssertAndPrint("invoke", "logStartTrain", inputHash{traintupleKey})
 
+	fmt.Fprintln(&out, "#### ------------ Log Training Progress ------------")
+	inpProgress := inputLogProgress{
+		Key:        traintupleKey,
+		Step:       3,
+		Percentage: 30,
+		Metrics:    map[string]float32{"loss": 0.42},
+	}
+	callAssertAndPrint("invoke", "logProgress", inpProgress)
+
+	fmt.Fprintln(&out, "#### ------------ Query Traintuple Progress ------------")
+	callAssertAndPrint("query", "queryTraintupleProgress", inputHash{traintupleKey})
+
 	fmt.Fprintln(&out, "#### ------------ Log Success Training ------------")
 	inp := inputLogSuccessTrain{}
 	inp.Key = string(traintupleKey)
+	inp.FullLog = &inputHashDress{Hash: fullLogHash, StorageAddress: fullLogStorageAddress}
 	inp.createDefault()
 	callAssertAndPrint("invoke", "logSuccessTrain", inp)
 
@@ -373,7 +499,10 @@ func TestPipeline(t *testing.T) {
 	inpTesttuple = inputTesttuple{}
 	inpTesttuple.TraintupleKey = todoTraintupleKey
 	inpTesttuple.createDefault()
-	callAssertAndPrint("invoke", "createTesttuple", inpTesttuple)
+	resp = callAssertAndPrint("invoke", "createTesttuple", inpTesttuple)
+	res = map[string]string{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &res))
+	failedTesttupleKey := res["key"]
 
 	fmt.Fprintln(&out, "#### ------------ Query Testtuples of worker with todo status ------------")
 	filter = inputQueryFilter{
@@ -394,9 +523,41 @@ func TestPipeline(t *testing.T) {
 	fmt.Fprintln(&out, "#### ------------ Query Testtuple from its key ------------")
 	callAssertAndPrint("query", "queryTesttuple", inputHash{testtupleKey})
 
+	fmt.Fprintln(&out, "#### ------------ Log Fail Testing ------------")
+	fail := inputLogFailTest{FailureReport: &inputFailureReport{ErrorType: "data", Stage: "download"}}
+	fail.Key = failedTesttupleKey
+	fail.FullLog = &inputHashDress{Hash: fullLogHash, StorageAddress: fullLogStorageAddress}
+	fail.createDefault()
+	callAssertAndPrint("invoke", "logFailTest", fail)
+
 	fmt.Fprintln(&out, "#### ------------ Query all Testtuples ------------")
 	callAssertAndPrint("query", "queryTesttuples", nil)
 
+	fmt.Fprintln(&out, "#### ------------ Add Predicttuple ------------")
+	inpPredicttuple := inputPredicttuple{
+		TraintupleKey:  traintupleKey,
+		DataManagerKey: dataManagerOpenerHash,
+		DataSampleKeys: []string{trainDataSampleHash1, trainDataSampleHash2},
+	}
+	resp = callAssertAndPrint("invoke", "createPredicttuple", inpPredicttuple)
+	res = map[string]string{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &res))
+	predicttupleKey := res["key"]
+
+	fmt.Fprintln(&out, "#### ------------ Log Start Predicting ------------")
+	callAssertAndPrint("invoke", "logStartPredict", inputHash{predicttupleKey})
+
+	fmt.Fprintln(&out, "#### ------------ Log Success Predicting ------------")
+	successPredict := inputLogSuccessPredict{
+		Predictions: inputHashDress{Hash: fullLogHash, StorageAddress: "https://toto/predictions"},
+	}
+	successPredict.Key = predicttupleKey
+	successPredict.Log = "no error, ah ah ah"
+	callAssertAndPrint("invoke", "logSuccessPredict", successPredict)
+
+	fmt.Fprintln(&out, "#### ------------ Query Predicttuple from its key ------------")
+	callAssertAndPrint("query", "queryPredicttuple", inputHash{predicttupleKey})
+
 	fmt.Fprintln(&out, "#### ------------ Query details about a model ------------")
 	callAssertAndPrint("query", "queryModelDetails", inputHash{traintupleKey})
 
@@ -429,6 +590,22 @@ func TestPipeline(t *testing.T) {
 	fmt.Fprintln(&out, "#### ------------ Create a ComputePlan ------------")
 	callAssertAndPrint("invoke", "createComputePlan", defaultComputePlan)
 
+	fmt.Fprintln(&out, "#### ------------ Register a Model trained outside the platform ------------")
+	inpModel := inputModel{
+		Name:           "pre-trained svm",
+		Hash:           externalModelHash,
+		StorageAddress: "https://toto/model/42/model",
+		AlgoKey:        algoHash,
+		Permissions:    OpenPermissions,
+	}
+	callAssertAndPrint("invoke", "registerModel", inpModel)
+
+	fmt.Fprintln(&out, "#### ------------ Query the registered Model ------------")
+	callAssertAndPrint("query", "queryModel", inputHash{externalModelHash})
+
+	fmt.Fprintln(&out, "#### ------------ Evaluate an ensemble of Models ------------")
+	callAssertAndPrint("invoke", "createTesttuple", inputTesttuple{TraintupleKeys: []string{traintupleKey, externalModelHash}})
+
 	fmt.Fprintln(&out, "#### ------------ Query an ObjectiveLeaderboard ------------")
 	inpLeaderboard := inputLeaderboard{
 		ObjectiveKey:   objectiveDescriptionHash,
@@ -495,3 +672,62 @@ func TestQueryEmptyResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorResponseCode(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	t.Run("validation", func(t *testing.T) {
+		inpAlgo := inputAlgo{}
+		inpAlgo.createDefault()
+		inpAlgo.StorageAddress = "not an url"
+		resp := mockStub.MockInvoke("42", methodAndAssetToByte("registerAlgo", inpAlgo))
+		assert.EqualValues(t, 400, resp.Status, resp.Message)
+
+		errStruct := struct {
+			Code   string              `json:"code"`
+			Fields []errors.FieldError `json:"fields"`
+		}{}
+		require.NoError(t, json.Unmarshal(resp.Payload, &errStruct))
+		assert.Equal(t, string(errors.CodeInvalidInput), errStruct.Code)
+		require.Len(t, errStruct.Fields, 1)
+		assert.Equal(t, "storageAddress", errStruct.Fields[0].Field)
+		assert.Equal(t, "url", errStruct.Fields[0].Tag)
+		assert.Equal(t, "not an url", errStruct.Fields[0].Value)
+		assert.Equal(t, "storageAddress must be a valid URL", errStruct.Fields[0].Message)
+	})
+	t.Run("localized validation", func(t *testing.T) {
+		defer func() { mockStub.Transient = nil }()
+		inpAlgo := inputAlgo{}
+		inpAlgo.createDefault()
+		inpAlgo.StorageAddress = "not an url"
+		inpAlgo.Hash = "abc"
+		testCases := []struct {
+			locale   string
+			expected string
+		}{
+			{"fr", "la validation des entrées a échoué: hash doit faire une taille de 64 caractères; storageAddress doit être une URL valide"},
+			{"de-DE", "die Validierung der Eingaben ist fehlgeschlagen: hash muss genau 64 Zeichen lang sein; storageAddress muss eine gültige URL sein"},
+			{"it", "inputs validation failed: hash must be 64 characters in length; storageAddress must be a valid URL"},
+		}
+		for _, tC := range testCases {
+			mockStub.Transient = map[string][]byte{"locale": []byte(tC.locale)}
+			resp := mockStub.MockInvoke("42", methodAndAssetToByte("registerAlgo", inpAlgo))
+			assert.EqualValues(t, 400, resp.Status, resp.Message)
+			errStruct := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(resp.Payload, &errStruct))
+			assert.Equal(t, tC.expected, errStruct["error"], tC.locale)
+		}
+	})
+	t.Run("status transition", func(t *testing.T) {
+		inpSuccess := inputLogSuccessTrain{}
+		inpSuccess.Key = traintupleKey
+		resp := mockStub.MockInvoke("42", inpSuccess.createDefault())
+		assert.EqualValues(t, 400, resp.Status, resp.Message)
+
+		errStruct := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(resp.Payload, &errStruct))
+		assert.Equal(t, string(errors.CodeTupleInvalidStatusTransition), errStruct["code"])
+	})
+}
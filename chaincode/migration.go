@@ -0,0 +1,335 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"reflect"
+)
+
+// The schema version is the number of migrations applied to the assets stored in the ledger.
+// Migrations run during Init, when the chaincode is instantiated or upgraded, in batches of
+// at most migrationBatchSize assets. When a migration has more assets to update, its cursor
+// is stored and the next batches are run by the migrate smart contract.
+
+// chaincodeVersion is the version of the chaincode, to update for each release
+const chaincodeVersion = "0.1.0"
+
+// schemaVersionKey is the key of the schema version in the ledger
+const schemaVersionKey = "schemaVersion"
+
+// migrationBatchSize is the maximum number of assets updated by a transaction
+var migrationBatchSize = 500
+
+// migration updates the assets stored in the ledger following a change of their schema.
+// It processes at most limit assets after the cursor, and returns the number of assets
+// processed and the cursor of the next batch, empty once all the assets are processed.
+// It must be deterministic and idempotent.
+type migration struct {
+	description string
+	run         func(db LedgerDB, cursor string, limit int) (next string, count int, err error)
+}
+
+// migrations are all the migrations of the schema, in order. Never remove or reorder them.
+var migrations = []migration{
+	{
+		description: "store the testtuple~tag~key index under the testtuple object type",
+		run:         migrateTesttupleTagIndex,
+	},
+	{
+		description: "copy the traintuple permissions to the testtuples stored without them",
+		run:         migrateTesttuplePermissions,
+	},
+	{
+		description: "move the traintuple logs and model storage addresses to private data collections",
+		run:         migrateTraintuplePrivateFields,
+	},
+	{
+		description: "move the testtuple logs to a private data collection",
+		run:         migrateTesttuplePrivateFields,
+	},
+	{
+		description: "move the data manager opener storage addresses to a private data collection",
+		run:         migrateDataManagerPrivateFields,
+	},
+	{
+		description: "set the endorsement policy of the nodes",
+		run:         migrateEndorsementPolicies("node~key", "node", func() interface{} { return &Node{} }),
+	},
+	{
+		description: "set the endorsement policy of the objectives",
+		run:         migrateEndorsementPolicies("objective~owner~key", "objective", func() interface{} { return &Objective{} }),
+	},
+	{
+		description: "set the endorsement policy of the data managers",
+		run:         migrateEndorsementPolicies("dataManager~owner~key", "dataManager", func() interface{} { return &DataManager{} }),
+	},
+	{
+		description: "set the endorsement policy of the data samples",
+		run:         migrateEndorsementPolicies("dataSample~dataManager~key", "dataSample", func() interface{} { return &DataSample{} }),
+	},
+	{
+		description: "set the endorsement policy of the algos",
+		run:         migrateEndorsementPolicies("algo~owner~key", "algo", func() interface{} { return &Algo{} }),
+	},
+	{
+		description: "set the endorsement policy of the traintuples",
+		run:         migrateEndorsementPolicies("traintuple~algo~key", "traintuple", func() interface{} { return &Traintuple{} }),
+	},
+	{
+		description: "set the endorsement policy of the testtuples",
+		run:         migrateEndorsementPolicies("testtuple~algo~key", "testtuple", func() interface{} { return &Testtuple{} }),
+	},
+	{
+		description: "index the certified evaluations by creator and day of creation",
+		run:         migrateEvaluationIndex,
+	},
+	{
+		description: "remove the endorsement policy of the data managers",
+		run:         migrateRemoveEndorsementPolicies("dataManager~owner~key", "dataManager"),
+	},
+	{
+		description: "remove the endorsement policy of the traintuples",
+		run:         migrateRemoveEndorsementPolicies("traintuple~algo~key", "traintuple"),
+	},
+	{
+		description: "remove the endorsement policy of the testtuples",
+		run:         migrateRemoveEndorsementPolicies("testtuple~algo~key", "testtuple"),
+	},
+	{
+		description: "remove the endorsement policy of the predicttuples",
+		run:         migrateRemoveEndorsementPolicies("predicttuple~traintuple~key", "predicttuple"),
+	},
+}
+
+// SchemaVersion is the state of the schema of the assets stored in the ledger
+type SchemaVersion struct {
+	// Version is the number of migrations fully applied
+	Version int `json:"version"`
+	// Cursor is where the next migration has stopped, empty if it has not started
+	Cursor string `json:"cursor"`
+}
+
+// getSchemaVersion returns the schema version, 0 if none has been stored yet
+func getSchemaVersion(db LedgerDB) (SchemaVersion, error) {
+	schema := SchemaVersion{}
+	exists, err := db.KeyExists(schemaVersionKey)
+	if err != nil || !exists {
+		return schema, err
+	}
+	err = db.Get(schemaVersionKey, &schema)
+	return schema, err
+}
+
+// runMigrations applies the pending migrations updating at most limit assets.
+// It returns the updated schema version.
+func runMigrations(db LedgerDB, limit int) (SchemaVersion, error) {
+	schema, err := getSchemaVersion(db)
+	if err != nil {
+		return schema, err
+	}
+	initial := schema
+	for limit > 0 && schema.Version < len(migrations) {
+		m := migrations[schema.Version]
+		logger.Infof("running migration %d: %s (cursor: %s)", schema.Version+1, m.description, schema.Cursor)
+		next, count, err := m.run(db, schema.Cursor, limit)
+		if err != nil {
+			return schema, err
+		}
+		if next != "" {
+			// The batch is full, the migration will go on in the next transaction
+			schema.Cursor = next
+			break
+		}
+		schema.Version++
+		schema.Cursor = ""
+		limit -= count
+	}
+	if schema == initial {
+		exists, err := db.KeyExists(schemaVersionKey)
+		if err != nil || exists {
+			return schema, err
+		}
+	}
+	return schema, db.Put(schemaVersionKey, schema)
+}
+
+// migrateTesttupleTagIndex moves the testtuple~tag~key composite keys, which were created
+// with the traintuple object type, so that the testtuples can be filtered by tag
+func migrateTesttupleTagIndex(db LedgerDB, cursor string, limit int) (string, int, error) {
+	index := "testtuple~tag~key"
+	return db.ForEachIndexKey(index, []string{"traintuple"}, cursor, limit, func(attributes []string) error {
+		return db.UpdateIndex(index, attributes, append([]string{"testtuple"}, attributes[1:]...))
+	})
+}
+
+// migrateTesttuplePermissions sets the permissions of the testtuples, which were
+// stored empty, to the ones of their traintuple
+func migrateTesttuplePermissions(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return db.ForEachIndexKey("testtuple~traintuple~certified~key", []string{"testtuple"}, cursor, limit, func(attributes []string) error {
+		testtupleKey := attributes[len(attributes)-1]
+		testtuple, err := db.GetTesttuple(testtupleKey)
+		if err != nil {
+			return err
+		}
+		traintuple, err := db.GetTraintuple(testtuple.Model.TraintupleKey)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(testtuple.Permissions, traintuple.Permissions) {
+			return nil
+		}
+		testtuple.Permissions = traintuple.Permissions
+		return db.Put(testtupleKey, testtuple)
+	})
+}
+
+// movePrivateField moves the sensitive field of an asset stored in the public state to a
+// private data collection, before the value already stored there, whose hash is given.
+// It returns the hash of the new value.
+func movePrivateField(db LedgerDB, collection string, key string, legacy string, hash string) (string, error) {
+	if legacy == "" {
+		return hash, nil
+	}
+	private := ""
+	if hash != "" {
+		buff, err := db.readPrivateData(collection, key)
+		if err != nil {
+			return "", errors.Internal(err, "cannot read %s in the private data collection %s:", key, collection)
+		}
+		private = string(buff)
+	}
+	return db.PutPrivateField(collection, key, legacy+private)
+}
+
+// migrateTraintuplePrivateFields moves the logs and the model storage addresses of the
+// traintuples, which were stored in the public state, to private data collections
+func migrateTraintuplePrivateFields(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return db.ForEachIndexKey("traintuple~algo~key", []string{"traintuple"}, cursor, limit, func(attributes []string) error {
+		traintupleKey := attributes[len(attributes)-1]
+		traintuple, err := db.GetTraintuple(traintupleKey)
+		if err != nil {
+			return err
+		}
+		legacyModel := traintuple.OutModel != nil && traintuple.OutModel.StorageAddress != ""
+		if traintuple.LegacyLog == "" && !legacyModel {
+			return nil
+		}
+		traintuple.LogHash, err = movePrivateField(db, tupleLogsCollection, traintupleKey, traintuple.LegacyLog, traintuple.LogHash)
+		if err != nil {
+			return err
+		}
+		traintuple.LegacyLog = ""
+		if legacyModel {
+			traintuple.OutModel.StorageAddressHash, err = db.PutPrivateField(modelAddressesCollection, traintupleKey, traintuple.OutModel.StorageAddress)
+			if err != nil {
+				return err
+			}
+			traintuple.OutModel.StorageAddress = ""
+		}
+		return db.Put(traintupleKey, traintuple)
+	})
+}
+
+// migrateTesttuplePrivateFields moves the logs of the testtuples, which were stored in the
+// public state, to a private data collection. The model storage addresses they held are
+// dropped, the one of the traintuple being returned instead.
+func migrateTesttuplePrivateFields(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return db.ForEachIndexKey("testtuple~algo~key", []string{"testtuple"}, cursor, limit, func(attributes []string) error {
+		testtupleKey := attributes[len(attributes)-1]
+		testtuple, err := db.GetTesttuple(testtupleKey)
+		if err != nil {
+			return err
+		}
+		legacyModel := testtuple.Model != nil && testtuple.Model.StorageAddress != ""
+		if testtuple.LegacyLog == "" && !legacyModel {
+			return nil
+		}
+		testtuple.LogHash, err = movePrivateField(db, tupleLogsCollection, testtupleKey, testtuple.LegacyLog, testtuple.LogHash)
+		if err != nil {
+			return err
+		}
+		testtuple.LegacyLog = ""
+		if legacyModel {
+			testtuple.Model.StorageAddress = ""
+		}
+		return db.Put(testtupleKey, testtuple)
+	})
+}
+
+// migrateDataManagerPrivateFields moves the opener storage addresses of the data managers,
+// which were stored in the public state, to a private data collection
+func migrateDataManagerPrivateFields(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return db.ForEachIndexKey("dataManager~owner~key", []string{"dataManager"}, cursor, limit, func(attributes []string) error {
+		dataManagerKey := attributes[len(attributes)-1]
+		dataManager, err := db.GetDataManager(dataManagerKey)
+		if err != nil || dataManager.LegacyOpenerStorageAddress == "" {
+			return err
+		}
+		dataManager.OpenerStorageAddressHash, err = db.PutPrivateField(openerAddressesCollection, dataManagerKey, dataManager.LegacyOpenerStorageAddress)
+		if err != nil {
+			return err
+		}
+		dataManager.LegacyOpenerStorageAddress = ""
+		return db.Put(dataManagerKey, dataManager)
+	})
+}
+
+// migrateEvaluationIndex indexes the certified testtuples stored before the daily submissions
+// of a challenge were counted from the evaluation~objective~creator~day~key index
+func migrateEvaluationIndex(db LedgerDB, cursor string, limit int) (string, int, error) {
+	return db.ForEachIndexKey("testtuple~objective~certified~key", []string{"testtuple"}, cursor, limit, func(attributes []string) error {
+		if attributes[2] != "true" {
+			return nil
+		}
+		testtuple, err := db.GetTesttuple(attributes[len(attributes)-1])
+		if err != nil {
+			return err
+		}
+		return testtuple.createEvaluationIndex(db)
+	})
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the schema version
+// -------------------------------------------------------------------------------------------
+
+// migrate runs the next batch of the pending migrations
+func migrate(db LedgerDB, args []string) (outputVersion, error) {
+	if len(args) != 0 {
+		return outputVersion{}, errors.BadRequest("incorrect number of arguments, expecting nothing")
+	}
+	schema, err := runMigrations(db, migrationBatchSize)
+	if err != nil {
+		return outputVersion{}, err
+	}
+	out := outputVersion{}
+	out.Fill(schema)
+	return out, nil
+}
+
+// queryVersion returns the versions of the chaincode and of the schema of the stored assets
+func queryVersion(db LedgerDB, args []string) (outputVersion, error) {
+	if len(args) != 0 {
+		return outputVersion{}, errors.BadRequest("incorrect number of arguments, expecting nothing")
+	}
+	schema, err := getSchemaVersion(db)
+	if err != nil {
+		return outputVersion{}, err
+	}
+	out := outputVersion{}
+	out.Fill(schema)
+	return out, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryVersionOutput(t *testing.T, mockStub *MockStub, fn string) outputVersion {
+	resp := mockStub.MockInvoke("42", methodToByte(fn))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out := outputVersion{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &out))
+	return out
+}
+
+func TestInitSchemaVersion(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	out := queryVersionOutput(t, mockStub, "queryVersion")
+	assert.Equal(t, outputVersion{
+		ChaincodeVersion:    chaincodeVersion,
+		SchemaVersion:       len(migrations),
+		LatestSchemaVersion: len(migrations),
+	}, out)
+}
+
+func TestMigrations(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	testtupleKeys := []string{}
+	for _, inpTesttuple := range []inputTesttuple{
+		{Tag: "foo"},
+		{Tag: "foo", DataManagerKey: dataManagerOpenerHash, DataSampleKeys: []string{testDataSampleHash1}},
+	} {
+		resp := mockStub.MockInvoke("42", inpTesttuple.createDefault())
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(resp.Payload, &res))
+		testtupleKeys = append(testtupleKeys, res["key"])
+	}
+
+	// Store the testtuples as they were before the migrations
+	mockStub.MockTransactionStart("legacy")
+	for _, key := range testtupleKeys {
+		newIndex, _ := mockStub.CreateCompositeKey("testtuple~tag~key", []string{"testtuple", "foo", key})
+		oldIndex, _ := mockStub.CreateCompositeKey("testtuple~tag~key", []string{"traintuple", "foo", key})
+		require.NoError(t, mockStub.DelState(newIndex))
+		require.NoError(t, mockStub.PutState(oldIndex, []byte{0x00}))
+
+		buff, _ := mockStub.GetState(key)
+		testtuple := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buff, &testtuple))
+		testtuple["permissions"] = Permissions{}
+		buff, _ = json.Marshal(testtuple)
+		require.NoError(t, mockStub.PutState(key, buff))
+	}
+	// The logs and storage addresses were stored in the public state, a log having been
+	// appended to the private one of the first testtuple since the upgrade
+	legacyFields := map[string]map[string]interface{}{
+		traintupleKey: {
+			"log":      "legacy train log",
+			"outModel": HashDress{Hash: modelHash, StorageAddress: modelAddress},
+		},
+		testtupleKeys[0]: {
+			"log":     "legacy test log, ",
+			"logHash": hashPrivateField("new test log"),
+			"model":   Model{TraintupleKey: traintupleKey, StorageAddress: modelAddress},
+		},
+		dataManagerOpenerHash: {
+			"openerStorageAddress":     "https://toto/dataManager/42234/opener",
+			"openerStorageAddressHash": "",
+		},
+	}
+	require.NoError(t, mockStub.PutPrivateData(tupleLogsCollection, testtupleKeys[0], []byte("new test log")))
+	require.NoError(t, mockStub.DelPrivateData(openerAddressesCollection, dataManagerOpenerHash))
+	for key, fields := range legacyFields {
+		buff, _ := mockStub.GetState(key)
+		asset := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buff, &asset))
+		for name, value := range fields {
+			asset[name] = value
+		}
+		buff, _ = json.Marshal(asset)
+		require.NoError(t, mockStub.PutState(key, buff))
+	}
+	// The certified testtuple was not indexed by day of creation
+	legacyDB := NewLedgerDB(mockStub)
+	certified, err := legacyDB.GetTesttuple(testtupleKeys[0])
+	require.NoError(t, err)
+	require.True(t, certified.Certified)
+	evaluationAttributes := []string{"evaluation", certified.ObjectiveKey, certified.Creator, certified.CreationDate[:10], certified.getEvaluationKey()}
+	evaluationIndexKey, _ := mockStub.CreateCompositeKey(evaluationIndex, evaluationAttributes)
+	indexed, _ := mockStub.GetState(evaluationIndexKey)
+	require.NotNil(t, indexed)
+	require.NoError(t, mockStub.DelState(evaluationIndexKey))
+	require.NoError(t, mockStub.DelState(schemaVersionKey))
+	mockStub.MockTransactionEnd("legacy")
+	// and without endorsement policies, except on the data manager and on the tuples
+	ep, err := statebased.NewStateEP(nil)
+	require.NoError(t, err)
+	require.NoError(t, ep.AddOrgs(statebased.RoleTypeMember, worker))
+	policy, err := ep.Policy()
+	require.NoError(t, err)
+	mockStub.EndorsementPolicies = map[string]map[string][]byte{"": {}}
+	tupleKeys := append([]string{dataManagerOpenerHash, traintupleKey}, testtupleKeys...)
+	for _, key := range tupleKeys {
+		mockStub.EndorsementPolicies[""][key] = policy
+	}
+
+	// Migrate one asset per transaction
+	defer func(size int) { migrationBatchSize = size }(migrationBatchSize)
+	migrationBatchSize = 1
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out := queryVersionOutput(t, mockStub, "queryVersion")
+	assert.Equal(t, 0, out.SchemaVersion)
+	assert.NotEmpty(t, out.MigrationCursor)
+
+	expectedVersions := []int{1, 1, 2, 3, 3, 4, 5}
+	for _, expected := range expectedVersions {
+		out = queryVersionOutput(t, mockStub, "migrate")
+		assert.Equal(t, expected, out.SchemaVersion)
+	}
+	assert.Equal(t, len(migrations), out.LatestSchemaVersion)
+	assert.Empty(t, out.MigrationCursor)
+
+	// The testtuples can be filtered by tag and have the permissions of their traintuple
+	filter := inputQueryFilter{IndexName: "testtuple~tag", Attributes: "foo"}
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("queryFilter", filter))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	testtuples := []outputTesttuple{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &testtuples))
+	assert.Len(t, testtuples, 2)
+	db := NewLedgerDB(mockStub)
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	require.NoError(t, err)
+	for _, key := range testtupleKeys {
+		testtuple, err := db.GetTesttuple(key)
+		require.NoError(t, err)
+		assert.Equal(t, traintuple.Permissions, testtuple.Permissions)
+	}
+
+	// The logs and storage addresses are moved to the private data collections
+	traintuple, err = db.GetTraintuple(traintupleKey)
+	require.NoError(t, err)
+	assert.Empty(t, traintuple.LegacyLog)
+	assert.Equal(t, HashDress{Hash: modelHash, StorageAddressHash: hashPrivateField(modelAddress)}, *traintuple.OutModel)
+	trainLog, err := db.GetPrivateField(tupleLogsCollection, traintupleKey, traintuple.LogHash)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy train log", trainLog)
+	testtuple, err := db.GetTesttuple(testtupleKeys[0])
+	require.NoError(t, err)
+	assert.Empty(t, testtuple.LegacyLog)
+	assert.Empty(t, testtuple.Model.StorageAddress)
+	testLog, err := db.GetPrivateField(tupleLogsCollection, testtupleKeys[0], testtuple.LogHash)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy test log, new test log", testLog)
+	dataManager, err := db.GetDataManager(dataManagerOpenerHash)
+	require.NoError(t, err)
+	assert.Empty(t, dataManager.LegacyOpenerStorageAddress)
+	opener, err := db.GetPrivateField(openerAddressesCollection, dataManagerOpenerHash, dataManager.OpenerStorageAddressHash)
+	require.NoError(t, err)
+	assert.Equal(t, "https://toto/dataManager/42234/opener", opener)
+
+	// The endorsement policies are set on the assets of a single owner and removed from the
+	// data manager and the tuples, one asset per transaction
+	for i := 0; i < 20 && out.SchemaVersion < len(migrations); i++ {
+		out = queryVersionOutput(t, mockStub, "migrate")
+	}
+	assert.Equal(t, len(migrations), out.SchemaVersion)
+	evaluations, err := db.GetIndexKeys(evaluationIndex, evaluationAttributes[:4])
+	require.NoError(t, err)
+	assert.Equal(t, []string{certified.getEvaluationKey()}, evaluations)
+	for _, key := range []string{worker, objectiveDescriptionHash, trainDataSampleHash1, algoHash} {
+		orgs, err := db.GetEndorsementPolicy(key)
+		require.NoError(t, err)
+		assert.Equal(t, []string{worker}, orgs, key)
+	}
+	for _, key := range tupleKeys {
+		orgs, err := db.GetEndorsementPolicy(key)
+		require.NoError(t, err)
+		assert.Empty(t, orgs, key)
+	}
+
+	// Nothing left to migrate
+	out = queryVersionOutput(t, mockStub, "migrate")
+	assert.Equal(t, len(migrations), out.SchemaVersion)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
 	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	"github.com/hyperledger/fabric/protos/msp"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -69,6 +70,12 @@ type MockStub struct {
 
 	TxTimestamp *timestamp.Timestamp
 
+	// timestamp of the transactions, the current time if nil
+	FixedTxTimestamp *timestamp.Timestamp
+
+	// number of transactions started with each ID
+	txIDCount map[string]int
+
 	// mocked signedProposal
 	signedProposal *pb.SignedProposal
 
@@ -77,13 +84,32 @@ type MockStub struct {
 
 	PvtState map[string]map[string][]byte
 
+	// MSP IDs of the members of the private data collections, declared with memberOnlyRead,
+	// by collection. All the creators can read the collections not listed.
+	CollectionMembers map[string][]string
+
 	// stores per-key endorsement policy, first map index is the collection, second map index is the key
 	EndorsementPolicies map[string]map[string][]byte
 
+	// per-key endorsement policies set by the current transaction, stored when it ends
+	pendingEndorsementPolicies map[string]map[string][]byte
+
+	// MSP IDs of the peers endorsing the transactions, the creator only if empty
+	Endorsers []string
+
 	// channel to store ChaincodeEvents
 	ChaincodeEventsChannel chan *pb.ChaincodeEvent
 
+	// last ChaincodeEvent sent
+	LastEvent *pb.ChaincodeEvent
+
 	Decorations map[string][]byte
+
+	// MSP ID returned by GetCreator, SampleOrg if empty
+	Creator string
+
+	// Transient map returned by GetTransient
+	Transient map[string][]byte
 }
 
 func (stub *MockStub) GetTxID() string {
@@ -122,15 +148,63 @@ func (stub *MockStub) GetFunctionAndParameters() (function string, params []stri
 // This is important when chaincodes invoke each other.
 // MockStub doesn't support concurrent transactions at present.
 func (stub *MockStub) MockTransactionStart(txid string) {
+	// Fabric rejects duplicated transaction IDs, so the ones reused by the tests are suffixed
+	stub.txIDCount[txid]++
 	stub.TxID = txid
+	if count := stub.txIDCount[txid]; count > 1 {
+		stub.TxID = fmt.Sprintf("%s-%d", txid, count)
+	}
+	stub.LastEvent = nil
 	stub.setSignedProposal(&pb.SignedProposal{})
-	stub.setTxTimestamp(util.CreateUtcTimestamp())
+	if stub.FixedTxTimestamp != nil {
+		stub.setTxTimestamp(stub.FixedTxTimestamp)
+	} else {
+		stub.setTxTimestamp(util.CreateUtcTimestamp())
+	}
 }
 
 // End a mocked transaction, clearing the UUID.
 func (stub *MockStub) MockTransactionEnd(uuid string) {
 	stub.signedProposal = nil
 	stub.TxID = ""
+	for collection, policies := range stub.pendingEndorsementPolicies {
+		if _, in := stub.EndorsementPolicies[collection]; !in {
+			stub.EndorsementPolicies[collection] = make(map[string][]byte)
+		}
+		for key, ep := range policies {
+			stub.EndorsementPolicies[collection][key] = ep
+		}
+	}
+	for collection := range stub.pendingEndorsementPolicies {
+		delete(stub.pendingEndorsementPolicies, collection)
+	}
+}
+
+// checkEndorsementPolicy fails if the endorsers do not satisfy the endorsement policy of a
+// key, as stored before the transaction. The peers would invalidate such a transaction.
+func (stub *MockStub) checkEndorsementPolicy(key string) error {
+	policy := stub.EndorsementPolicies[""][key]
+	if len(policy) == 0 {
+		return nil
+	}
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return err
+	}
+	endorsers := stub.Endorsers
+	if len(endorsers) == 0 {
+		creator, err := GetTxCreator(stub)
+		if err != nil {
+			return err
+		}
+		endorsers = []string{creator}
+	}
+	for _, org := range ep.ListOrgs() {
+		if !stringInSlice(org, endorsers) {
+			return errors.Errorf("the endorsement policy of %s requires the endorsement of %s", key, org)
+		}
+	}
+	return nil
 }
 
 // Register a peer chaincode with this MockStub
@@ -151,13 +225,61 @@ func (stub *MockStub) MockInit(uuid string, args [][]byte) pb.Response {
 
 // Invoke this chaincode, also starts and ends a transaction.
 func (stub *MockStub) MockInvoke(uuid string, args [][]byte) pb.Response {
+	transient := stub.Transient
+	stub.Transient = withPrivateInput(transient, args)
+	defer func() { stub.Transient = transient }()
 	stub.args = args
 	stub.MockTransactionStart(uuid)
+	rollback := stub.snapshotState()
 	res := stub.cc.Invoke(stub)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		rollback()
+	}
 	stub.MockTransactionEnd(uuid)
 	return res
 }
 
+// snapshotState returns a function restoring the state as it is, to discard the writes of a
+// failed transaction like the peers do
+func (stub *MockStub) snapshotState() func() {
+	state := make(map[string][]byte, len(stub.State))
+	for key, value := range stub.State {
+		state[key] = value
+	}
+	pvtState := make(map[string]map[string][]byte, len(stub.PvtState))
+	for collection, values := range stub.PvtState {
+		pvtState[collection] = make(map[string][]byte, len(values))
+		for key, value := range values {
+			pvtState[collection][key] = value
+		}
+	}
+	keys := []interface{}{}
+	for elem := stub.Keys.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value)
+	}
+	return func() {
+		for key := range stub.State {
+			delete(stub.State, key)
+		}
+		for key, value := range state {
+			stub.State[key] = value
+		}
+		for collection := range stub.PvtState {
+			delete(stub.PvtState, collection)
+		}
+		for collection, values := range pvtState {
+			stub.PvtState[collection] = values
+		}
+		stub.Keys.Init()
+		for _, key := range keys {
+			stub.Keys.PushBack(key)
+		}
+		for collection := range stub.pendingEndorsementPolicies {
+			delete(stub.pendingEndorsementPolicies, collection)
+		}
+	}
+}
+
 func (stub *MockStub) GetDecorations() map[string][]byte {
 	return stub.Decorations
 }
@@ -173,6 +295,15 @@ func (stub *MockStub) MockInvokeWithSignedProposal(uuid string, args [][]byte, s
 }
 
 func (stub *MockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	if members, ok := stub.CollectionMembers[collection]; ok {
+		creator, err := GetTxCreator(stub)
+		if err != nil {
+			return nil, err
+		}
+		if !stringInSlice(creator, members) {
+			return nil, fmt.Errorf("tx creator does not have read access permission on privatedata in chaincodeName:%s collectionName: %s", stub.Name, collection)
+		}
+	}
 	m, in := stub.PvtState[collection]
 
 	if !in {
@@ -195,7 +326,10 @@ func (stub *MockStub) PutPrivateData(collection string, key string, value []byte
 }
 
 func (stub *MockStub) DelPrivateData(collection string, key string) error {
-	return errors.New("Not Implemented")
+	if m, in := stub.PvtState[collection]; in {
+		delete(m, key)
+	}
+	return nil
 }
 
 func (stub *MockStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
@@ -228,6 +362,11 @@ func (stub *MockStub) PutState(key string, value []byte) error {
 		return err
 	}
 
+	if err := stub.checkEndorsementPolicy(key); err != nil {
+		mockLogger.Errorf("%+v", err)
+		return err
+	}
+
 	// If the value is nil or empty, delete the key
 	if len(value) == 0 {
 		mockLogger.Debug("MockStub", stub.Name, "PutState called, but value is nil or empty. Delete ", key)
@@ -272,6 +411,10 @@ func (stub *MockStub) PutState(key string, value []byte) error {
 
 // DelState removes the specified `key` and its value from the ledger.
 func (stub *MockStub) DelState(key string) error {
+	if err := stub.checkEndorsementPolicy(key); err != nil {
+		mockLogger.Errorf("%+v", err)
+		return err
+	}
 	mockLogger.Debug("MockStub", stub.Name, "Deleting", key, stub.State[key])
 	delete(stub.State, key)
 
@@ -397,17 +540,20 @@ yuGnBXj8ytqU0CwIPX4WecigUCAkVDNx
 `
 
 func (stub *MockStub) GetCreator() ([]byte, error) {
+	mspid := "SampleOrg"
+	if stub.Creator != "" {
+		mspid = stub.Creator
+	}
 	sid := &msp.SerializedIdentity{
-		Mspid:   "SampleOrg",
+		Mspid:   mspid,
 		IdBytes: []byte(fakeCertificate),
 	}
 
 	return proto.Marshal(sid)
 }
 
-// Not implemented
 func (stub *MockStub) GetTransient() (map[string][]byte, error) {
-	return nil, nil
+	return stub.Transient, nil
 }
 
 // Not implemented
@@ -441,7 +587,13 @@ func (stub *MockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
 }
 
 func (stub *MockStub) SetEvent(name string, payload []byte) error {
-	stub.ChaincodeEventsChannel <- &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	event := &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	stub.LastEvent = event
+	select {
+	case stub.ChaincodeEventsChannel <- event:
+	default:
+		// the channel is full, only keep the last event
+	}
 	return nil
 }
 
@@ -453,11 +605,13 @@ func (stub *MockStub) GetStateValidationParameter(key string) ([]byte, error) {
 	return stub.GetPrivateDataValidationParameter("", key)
 }
 
+// SetPrivateDataValidationParameter sets the endorsement policy of a key, which applies
+// to the next transactions
 func (stub *MockStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
-	m, in := stub.EndorsementPolicies[collection]
+	m, in := stub.pendingEndorsementPolicies[collection]
 	if !in {
-		stub.EndorsementPolicies[collection] = make(map[string][]byte)
-		m, in = stub.EndorsementPolicies[collection]
+		stub.pendingEndorsementPolicies[collection] = make(map[string][]byte)
+		m, in = stub.pendingEndorsementPolicies[collection]
 	}
 
 	m[key] = ep
@@ -483,10 +637,12 @@ func NewMockStub(name string, cc shim.Chaincode) *MockStub {
 	s.State = make(map[string][]byte)
 	s.PvtState = make(map[string]map[string][]byte)
 	s.EndorsementPolicies = make(map[string]map[string][]byte)
+	s.pendingEndorsementPolicies = make(map[string]map[string][]byte)
 	s.Invokables = make(map[string]*MockStub)
 	s.Keys = list.New()
 	s.ChaincodeEventsChannel = make(chan *pb.ChaincodeEvent, 100) //define large capacity for non-blocking setEvent calls.
 	s.Decorations = make(map[string][]byte)
+	s.txIDCount = make(map[string]int)
 
 	return s
 }
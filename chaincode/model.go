@@ -0,0 +1,164 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+)
+
+// A model trained outside the platform is registered under its hash with registerModel.
+// It can then be used wherever the key of a traintuple is accepted for a model: as an in-model
+// of a traintuple, or as the model of a testtuple or of a predicttuple. Such a model is done
+// from the start.
+
+// parentModel describes the model used by a tuple, trained by a traintuple or registered
+type parentModel struct {
+	AlgoKey string
+	Creator string
+	// External is true for a registered model
+	External bool
+	// Hash is empty until the traintuple is done
+	Hash string
+	// ObjectiveKey is empty for a registered model
+	ObjectiveKey string
+	Permissions  Permissions
+	Rank         int
+	Status       string
+	Timeout      int
+}
+
+// getParentModel returns the model of a traintuple, or the registered model, of a given key
+func getParentModel(db LedgerDB, key string) (parentModel, error) {
+	traintuple, err := db.GetTraintuple(key)
+	if err == nil {
+		parent := parentModel{
+			AlgoKey:      traintuple.AlgoKey,
+			Creator:      traintuple.Creator,
+			ObjectiveKey: traintuple.ObjectiveKey,
+			Permissions:  traintuple.Permissions,
+			Rank:         traintuple.Rank,
+			Status:       traintuple.Status,
+			Timeout:      traintuple.Timeout,
+		}
+		if traintuple.OutModel != nil {
+			parent.Hash = traintuple.OutModel.Hash
+		}
+		return parent, nil
+	}
+	model, modelErr := db.GetExternalModel(key)
+	if modelErr != nil {
+		return parentModel{}, err
+	}
+	return parentModel{
+		AlgoKey:     model.AlgoKey,
+		Creator:     model.Owner,
+		External:    true,
+		Hash:        key,
+		Permissions: model.Permissions,
+		Status:      StatusDone,
+	}, nil
+}
+
+// checkProcess verifies that the requester can use the model, as far as the dry run allows it
+func (parent parentModel) checkProcess(db LedgerDB, key string, requester string) error {
+	if parent.Permissions.CanProcess(parent.Creator, requester) {
+		return nil
+	}
+	if parent.External {
+		return db.Check(errors.Forbidden(errors.CodePermissionDeniedModel, "not authorized to process model %s", key))
+	}
+	return db.Check(errors.Forbidden(errors.CodePermissionDeniedTraintuple, "not authorized to process traintuple %s", key))
+}
+
+// isRegistered checks if the model of a tuple is a registered model, stored under its hash,
+// rather than the model of a traintuple
+func (model *Model) isRegistered() bool {
+	return model.Hash != "" && model.Hash == model.TraintupleKey
+}
+
+// Set is a method of the receiver ExternalModel. It uses the inputModel fields to set the model
+// and returns its key, the hash of the model
+func (model *ExternalModel) Set(db LedgerDB, inp inputModel) (modelKey string, err error) {
+	modelKey = inp.Hash
+	owner, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
+	algo, err := db.GetAlgo(inp.AlgoKey)
+	if err != nil {
+		err = errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
+		return
+	}
+	if !algo.Permissions.CanProcess(algo.Owner, owner) {
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedAlgo, "not authorized to process algo %s", inp.AlgoKey))
+		if err != nil {
+			return
+		}
+	}
+	permissions, err := NewPermissions(db, inp.Permissions)
+	if err != nil {
+		return
+	}
+	model.StorageAddressHash, err = db.PutPrivateField(modelAddressesCollection, modelKey, inp.StorageAddress)
+	if err != nil {
+		return
+	}
+	model.AssetType = ModelType
+	model.Name = inp.Name
+	model.AlgoKey = inp.AlgoKey
+	model.Owner = owner
+	model.Permissions = permissions
+	return
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the registered models
+// -------------------------------------------------------------------------------------------
+
+// registerModel stores a model trained outside the platform in the ledger
+func registerModel(db LedgerDB, args []string) (resp map[string]string, err error) {
+	inp := inputModel{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	model := ExternalModel{}
+	modelKey, err := model.Set(db, inp)
+	if err != nil {
+		return
+	}
+	if err = db.Add(modelKey, model); err != nil {
+		return
+	}
+	if err = db.CreateIndex("model~owner~key", []string{"model", model.Owner, modelKey}); err != nil {
+		return
+	}
+	return map[string]string{"key": modelKey}, nil
+}
+
+// queryModel returns a model registered with registerModel given its key
+func queryModel(db LedgerDB, args []string) (out outputExternalModel, err error) {
+	inp := inputHash{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	model, err := db.GetExternalModel(inp.Key)
+	if err != nil {
+		return
+	}
+	err = out.Fill(db, inp.Key, model)
+	return
+}
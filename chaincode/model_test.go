@@ -0,0 +1,192 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalModel(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	createKey := func(fn string, inp interface{}) string {
+		status, message, payload := invoke(fn, inp)
+		require.EqualValues(t, 200, status, message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(payload, &res))
+		return res["key"]
+	}
+
+	inpModel := inputModel{
+		Name:           "pre-trained svm",
+		Hash:           externalModelHash,
+		StorageAddress: "https://toto/model/42/model",
+		AlgoKey:        "aa1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+		Permissions:    OpenPermissions,
+	}
+	status, message, _ := invoke("registerModel", inpModel)
+	assert.EqualValues(t, 400, status, "the algo of the model must exist: %s", message)
+	inpModel.AlgoKey = algoHash
+	assert.Equal(t, externalModelHash, createKey("registerModel", inpModel))
+	status, message, _ = invoke("registerModel", inpModel)
+	assert.EqualValues(t, 409, status, message)
+
+	status, message, payload := invoke("queryModel", inputHash{externalModelHash})
+	require.EqualValues(t, 200, status, message)
+	model := outputExternalModel{}
+	require.NoError(t, json.Unmarshal(payload, &model))
+	assert.Equal(t, HashDress{Hash: externalModelHash, StorageAddress: "https://toto/model/42/model"}, model.Content)
+	assert.Equal(t, algoHash, model.AlgoKey)
+	assert.Equal(t, worker, model.Owner)
+
+	// The model is a done in-model of a traintuple
+	inpTraintuple := inputTraintuple{InModels: []string{externalModelHash}}
+	inpTraintuple.createDefault()
+	traintupleKey := createKey("createTraintuple", inpTraintuple)
+	status, message, payload = invoke("queryTraintuple", inputHash{traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	traintuple := outputTraintuple{}
+	require.NoError(t, json.Unmarshal(payload, &traintuple))
+	assert.Equal(t, StatusTodo, traintuple.Status)
+	require.Len(t, traintuple.InModels, 1)
+	assert.Equal(t, &Model{
+		TraintupleKey:  externalModelHash,
+		Hash:           externalModelHash,
+		StorageAddress: "https://toto/model/42/model",
+	}, traintuple.InModels[0])
+
+	// The model can be evaluated on the test data of an objective
+	inpTesttuple := inputTesttuple{TraintupleKey: externalModelHash}
+	status, message, _ = invoke("createTesttuple", inpTesttuple)
+	assert.EqualValues(t, 400, status, "the objective of a model must be given: %s", message)
+	inpTesttuple.ObjectiveKey = objectiveDescriptionHash
+	testtupleKey := createKey("createTesttuple", inpTesttuple)
+	status, message, payload = invoke("queryTesttuple", inputHash{testtupleKey})
+	require.EqualValues(t, 200, status, message)
+	testtuple := outputTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &testtuple))
+	assert.Equal(t, StatusTodo, testtuple.Status)
+	assert.True(t, testtuple.Certified)
+	assert.Equal(t, algoHash, testtuple.Algo.Hash)
+	assert.Equal(t, externalModelHash, testtuple.Model.Hash)
+
+	// The model can make predictions
+	inpPredicttuple := inputPredicttuple{
+		TraintupleKey:  externalModelHash,
+		DataManagerKey: dataManagerOpenerHash,
+		DataSampleKeys: []string{trainDataSampleHash1},
+	}
+	predicttupleKey := createKey("createPredicttuple", inpPredicttuple)
+	status, message, payload = invoke("queryPredicttuple", inputHash{predicttupleKey})
+	require.EqualValues(t, 200, status, message)
+	predicttuple := outputPredicttuple{}
+	require.NoError(t, json.Unmarshal(payload, &predicttuple))
+	assert.Equal(t, StatusTodo, predicttuple.Status)
+
+	// Only the nodes allowed to process a private model can use it
+	inpModel.Hash = "ee2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482eee"
+	inpModel.Permissions = inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}}
+	createKey("registerModel", inpModel)
+	mockStub.Creator = "OtherOrg"
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: inpModel.Hash, ObjectiveKey: objectiveDescriptionHash})
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+}
+
+func TestExternalModelTesttuple(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	createKey := func(fn string, inp interface{}) string {
+		status, message, payload := invoke(fn, inp)
+		require.EqualValues(t, 200, status, message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(payload, &res))
+		return res["key"]
+	}
+
+	otherAlgoHash := "fd2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"
+	inpAlgo := inputAlgo{Name: "baseline", Hash: otherAlgoHash}
+	resp := mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	createKey("registerModel", inputModel{
+		Name:           "pre-trained svm",
+		Hash:           externalModelHash,
+		StorageAddress: "https://toto/model/42/model",
+		AlgoKey:        algoHash,
+		Permissions:    OpenPermissions,
+	})
+
+	// The model is evaluated by its compatible algo, or by another one
+	inpTesttuple := inputTesttuple{ModelKey: externalModelHash, ObjectiveKey: objectiveDescriptionHash}
+	defaultKey := createKey("createTesttuple", inpTesttuple)
+	inpTesttuple.AlgoKey = otherAlgoHash
+	testtupleKey := createKey("createTesttuple", inpTesttuple)
+	assert.NotEqual(t, defaultKey, testtupleKey)
+	status, message, _ := invoke("createTesttuple", inpTesttuple)
+	assert.EqualValues(t, 409, status, message)
+
+	status, message, payload := invoke("queryTesttuple", inputHash{testtupleKey})
+	require.EqualValues(t, 200, status, message)
+	testtuple := outputTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &testtuple))
+	assert.Equal(t, StatusTodo, testtuple.Status)
+	assert.True(t, testtuple.Certified)
+	assert.Equal(t, otherAlgoHash, testtuple.Algo.Hash)
+	assert.Equal(t, externalModelHash, testtuple.Model.TraintupleKey)
+
+	// The modelKey must be a registered model, and the algo of a traintuple cannot be changed
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	traintupleKey := createKey("createTraintuple", inpTraintuple)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{ModelKey: traintupleKey})
+	assert.EqualValues(t, 400, status, message)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: traintupleKey, AlgoKey: otherAlgoHash})
+	assert.EqualValues(t, 400, status, message)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: traintupleKey, ModelKey: externalModelHash})
+	assert.EqualValues(t, 400, status, message)
+
+	// The certified testtuple of the model is in the leaderboard
+	status, message, _ = invoke("logStartTest", inputHash{testtupleKey})
+	require.EqualValues(t, 200, status, message)
+	success := inputLogSuccessTest{}
+	success.Key = testtupleKey
+	success.createDefault()
+	status, message, _ = invoke("logSuccessTest", success)
+	require.EqualValues(t, 200, status, message)
+	status, message, payload = invoke("queryObjectiveLeaderboard", inputLeaderboard{ObjectiveKey: objectiveDescriptionHash})
+	require.EqualValues(t, 200, status, message)
+	leaderboard := outputLeaderboard{}
+	require.NoError(t, json.Unmarshal(payload, &leaderboard))
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, testtupleKey, leaderboard.Testtuples[0].Key)
+	assert.Equal(t, otherAlgoHash, leaderboard.Testtuples[0].Algo.Hash)
+	assert.Equal(t, "https://toto/model/42/model", leaderboard.Testtuples[0].Model.StorageAddress)
+}
@@ -15,7 +15,7 @@
 package main
 
 func registerNode(db LedgerDB, args []string) (Node, error) {
-	txCreator, err := GetTxCreator(db.cc)
+	txCreator, err := GetTxCreator(db)
 	if err != nil {
 		return Node{}, err
 	}
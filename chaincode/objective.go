@@ -18,16 +18,27 @@ import (
 	"chaincode/errors"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Set is a method of the receiver Objective. It checks the validity of inputObjective and uses its fields to set the Objective.
-// Returns the objectiveKey and the dataManagerKey associated to test dataSample
-func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey string, dataManagerKey string, err error) {
-	dataManagerKey = inp.TestDataset.DataManagerKey
-	if dataManagerKey != "" {
+// Returns the objectiveKey and the dataManagerKeys associated to test dataSamples
+func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey string, dataManagerKeys []string, err error) {
+	inpDatasets := inp.TestDatasets
+	if inp.TestDataset.DataManagerKey != "" {
+		inpDatasets = append([]inputDataset{inp.TestDataset}, inpDatasets...)
+	}
+	testDatasets := []*Dataset{}
+	for _, inpDataset := range inpDatasets {
+		dataManagerKey := inpDataset.DataManagerKey
+		if stringInSlice(dataManagerKey, dataManagerKeys) {
+			err = errors.BadRequest("invalid input: the test datasets should have different dataManagers, %s is used twice", dataManagerKey)
+			return
+		}
 		var testOnly bool
-		dataSampleKeys := inp.TestDataset.DataSampleKeys
-		testOnly, _, err = checkSameDataManager(db, dataManagerKey, dataSampleKeys)
+		testOnly, _, err = checkSameDataManager(db, dataManagerKey, inpDataset.DataSampleKeys)
 		if err != nil {
 			err = errors.BadRequest(err, "invalid test dataSample")
 			return
@@ -35,12 +46,28 @@ func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey s
 			err = errors.BadRequest("test dataSample are not tagged as testOnly dataSample")
 			return
 		}
-		objective.TestDataset = &Dataset{
-			DataManagerKey: dataManagerKey,
-			DataSampleKeys: dataSampleKeys,
+		if err = checkPrivateDataSamples(inpDataset); err != nil {
+			return
+		}
+		testDatasets = append(testDatasets, &Dataset{
+			DataManagerKey:        dataManagerKey,
+			DataSampleKeys:        inpDataset.DataSampleKeys,
+			PrivateDataSampleKeys: inpDataset.PrivateDataSampleKeys,
+		})
+		dataManagerKeys = append(dataManagerKeys, dataManagerKey)
+	}
+	objective.TestDataset = nil
+	objective.TestDatasets = nil
+	if len(testDatasets) > 0 {
+		objective.TestDataset = testDatasets[0]
+	}
+	if len(testDatasets) > 1 {
+		objective.TestDatasets = testDatasets
+	}
+	if inp.Challenge != (inputChallenge{}) {
+		if objective.Challenge, err = newChallenge(inp.Challenge); err != nil {
+			return
 		}
-	} else {
-		objective.TestDataset = nil
 	}
 	objective.AssetType = ObjectiveType
 	objective.Name = inp.Name
@@ -50,7 +77,7 @@ func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey s
 		Hash:           inp.MetricsHash,
 		StorageAddress: inp.MetricsStorageAddress,
 	}
-	owner, err := GetTxCreator(db.cc)
+	owner, err := GetTxCreator(db)
 	if err != nil {
 		return
 	}
@@ -60,10 +87,141 @@ func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey s
 	}
 	objective.Owner = owner
 	objective.Permissions = permissions
+	objective.Timeout = inp.Timeout
+	objective.AutoEvaluate = inp.AutoEvaluate
+	objective.SkipEvaluationTags = inp.SkipEvaluationTags
+	if objective.SkipEvaluationTags == nil {
+		objective.SkipEvaluationTags = []string{}
+	}
 	objectiveKey = inp.DescriptionHash
 	return
 }
 
+// checkPrivateDataSamples verifies that the private partition of a test dataset is made of some of its
+// data samples, leaving at least one of them public
+func checkPrivateDataSamples(inpDataset inputDataset) error {
+	if len(inpDataset.PrivateDataSampleKeys) == 0 {
+		return nil
+	}
+	for _, key := range inpDataset.PrivateDataSampleKeys {
+		if !stringInSlice(key, inpDataset.DataSampleKeys) {
+			return errors.BadRequest(errors.CodeInvalidInput, "invalid input: private dataSample %s is not a dataSample of the test dataset of dataManager %s", key, inpDataset.DataManagerKey)
+		}
+	}
+	if len(inpDataset.PrivateDataSampleKeys) >= len(inpDataset.DataSampleKeys) {
+		return errors.BadRequest(errors.CodeInvalidInput, "invalid input: the test dataset of dataManager %s should keep at least one public dataSample", inpDataset.DataManagerKey)
+	}
+	return nil
+}
+
+// newChallenge checks the rules of a challenge
+func newChallenge(inp inputChallenge) (*Challenge, error) {
+	var start, end time.Time
+	var err error
+	if inp.StartDate != "" {
+		if start, err = time.Parse(time.RFC3339, inp.StartDate); err != nil {
+			return nil, errors.BadRequest(errors.CodeInvalidInput, "invalid input: challenge startDate should be in RFC 3339 format - %s", err.Error())
+		}
+	}
+	if inp.EndDate != "" {
+		if end, err = time.Parse(time.RFC3339, inp.EndDate); err != nil {
+			return nil, errors.BadRequest(errors.CodeInvalidInput, "invalid input: challenge endDate should be in RFC 3339 format - %s", err.Error())
+		}
+	}
+	if inp.StartDate != "" && inp.EndDate != "" && !end.After(start) {
+		return nil, errors.BadRequest(errors.CodeInvalidInput, "invalid input: challenge endDate should be after its startDate")
+	}
+	return &Challenge{
+		StartDate:           inp.StartDate,
+		EndDate:             inp.EndDate,
+		MaxDailySubmissions: inp.MaxDailySubmissions,
+		FinalLeaderboard:    outputBoardTuples{},
+	}, nil
+}
+
+// evaluationIndex lists the certified evaluations of an objective by creator and UTC day of creation
+const evaluationIndex = "evaluation~objective~creator~day~key"
+
+// checkLeaderboardNotFrozen verifies that a certified testtuple of an objective can report its perf:
+// once the final leaderboard of its challenge is frozen, the perfs would only change the live one
+func checkLeaderboardNotFrozen(db LedgerDB, objectiveKey string) error {
+	objective, err := db.GetObjective(objectiveKey)
+	if err != nil {
+		return err
+	}
+	if objective.Challenge != nil && objective.Challenge.FrozenDate != "" {
+		return errors.BadRequest(errors.CodeChallengeClosed, "the leaderboard of objective %s was frozen at %s", objectiveKey, objective.Challenge.FrozenDate)
+	}
+	return nil
+}
+
+// checkChallenge verifies that a node can submit a certified evaluation of the objective: within the
+// dates of its challenge and its daily quota, a certified evaluation on several test datasets counting once
+func (objective *Objective) checkChallenge(db LedgerDB, objectiveKey string, creator string) error {
+	challenge := objective.Challenge
+	if challenge == nil {
+		return nil
+	}
+	now, err := getTxTime(db)
+	if err != nil {
+		return err
+	}
+	if challenge.StartDate != "" {
+		start, err := time.Parse(time.RFC3339, challenge.StartDate)
+		if err != nil {
+			return errors.Internal(err, "invalid start date of objective %s:", objectiveKey)
+		}
+		if now.Before(start) {
+			return errors.BadRequest(errors.CodeChallengeClosed, "the challenge of objective %s starts at %s", objectiveKey, challenge.StartDate)
+		}
+	}
+	if challenge.EndDate != "" {
+		end, err := time.Parse(time.RFC3339, challenge.EndDate)
+		if err != nil {
+			return errors.Internal(err, "invalid end date of objective %s:", objectiveKey)
+		}
+		if now.After(end) {
+			return errors.BadRequest(errors.CodeChallengeClosed, "the challenge of objective %s ended at %s", objectiveKey, challenge.EndDate)
+		}
+	}
+	if challenge.MaxDailySubmissions == 0 {
+		return nil
+	}
+
+	day := now.Format("2006-01-02")
+	evaluations, err := db.GetIndexKeys(evaluationIndex, []string{"evaluation", objectiveKey, creator, day})
+	if err != nil {
+		return err
+	}
+	if len(evaluations) >= challenge.MaxDailySubmissions {
+		return errors.BadRequest(errors.CodeChallengeQuotaExceeded,
+			"%s has already submitted %d certified evaluations of objective %s today", creator, len(evaluations), objectiveKey)
+	}
+	return nil
+}
+
+// getTestDatasets returns the test datasets of the objective: the ones it is evaluated on
+// with several data managers, or its test dataset
+func (objective *Objective) getTestDatasets() []*Dataset {
+	if len(objective.TestDatasets) > 0 {
+		return objective.TestDatasets
+	}
+	if objective.TestDataset == nil {
+		return []*Dataset{}
+	}
+	return []*Dataset{objective.TestDataset}
+}
+
+// hasPrivateDataSamples checks if one of the test datasets of an objective has a private partition
+func (objective *Objective) hasPrivateDataSamples() bool {
+	for _, dataset := range objective.getTestDatasets() {
+		if len(dataset.PrivateDataSampleKeys) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // -------------------------------------------------------------------------------------------
 // Smart contract related to objectivess
 // -------------------------------------------------------------------------------------------
@@ -73,14 +231,14 @@ func (objective *Objective) Set(db LedgerDB, inp inputObjective) (objectiveKey s
 func registerObjective(db LedgerDB, args []string) (resp map[string]string, err error) {
 	// convert input strings args to input struct inputObjective
 	inp := inputObjective{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
 
 	// check validity of input args and convert it to Objective
 	objective := Objective{}
-	objectiveKey, dataManagerKey, err := objective.Set(db, inp)
+	objectiveKey, dataManagerKeys, err := objective.Set(db, inp)
 	if err != nil {
 		return
 	}
@@ -92,15 +250,19 @@ func registerObjective(db LedgerDB, args []string) (resp map[string]string, err
 	if err = db.CreateIndex("objective~owner~key", []string{"objective", objective.Owner, objectiveKey}); err != nil {
 		return
 	}
-	// add objective to dataManager
-	err = addObjectiveDataManager(db, dataManagerKey, objectiveKey)
-	return map[string]string{"key": objectiveKey}, err
+	// add objective to the dataManagers
+	for _, dataManagerKey := range dataManagerKeys {
+		if err = addObjectiveDataManager(db, dataManagerKey, objectiveKey); err != nil {
+			return
+		}
+	}
+	return map[string]string{"key": objectiveKey}, nil
 }
 
 // queryObjective returns a objective of the ledger given its key
 func queryObjective(db LedgerDB, args []string) (out outputObjective, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -136,10 +298,12 @@ func queryObjectives(db LedgerDB, args []string) (outObjectives []outputObjectiv
 }
 
 // getObjectiveLeaderboard returns for an objective, all its certified testtuples with a done status, ordered by their perf
-// It can be an ascending sort or not depending on the ascendingOrder value.
+// It can be an ascending sort or not depending on the ascendingOrder value. With several test datasets, an entry
+// aggregates the certified testtuples of the same models on each of them, once they are all done. The final
+// leaderboard of a challenge is the snapshot of the entries frozen once it ended.
 func queryObjectiveLeaderboard(db LedgerDB, args []string) (outputLeaderboard, error) {
 	inp := inputLeaderboard{}
-	err := AssetFromJSON(args, &inp)
+	err := AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return outputLeaderboard{}, err
 	}
@@ -152,33 +316,284 @@ func queryObjectiveLeaderboard(db LedgerDB, args []string) (outputLeaderboard, e
 	outObjective.Fill(inp.ObjectiveKey, objective)
 	out := outputLeaderboard{Objective: outObjective, Testtuples: []outputBoardTuple{}}
 
-	testtupleKeys, err := db.GetIndexKeys("testtuple~objective~certified~key", []string{"testtuple", inp.ObjectiveKey, "true"})
+	if inp.Final {
+		if objective.Challenge == nil || objective.Challenge.FrozenDate == "" {
+			return outputLeaderboard{}, errors.NotFound("the final leaderboard of objective %s is not frozen", inp.ObjectiveKey)
+		}
+		out.Testtuples = append(out.Testtuples, objective.Challenge.FinalLeaderboard...)
+	} else {
+		boardTuples, err := getLeaderboardTuples(db, inp.ObjectiveKey, objective)
+		if err != nil {
+			return outputLeaderboard{}, err
+		}
+		out.Testtuples = boardTuples
+	}
+
+	if inp.AscendingOrder {
+		sort.Sort(out.Testtuples)
+	} else {
+		sort.Sort(sort.Reverse(out.Testtuples))
+	}
+	return out, nil
+}
+
+// freezeObjectiveLeaderboard stores the final leaderboard of the challenge of an objective: the snapshot
+// of the entries of the certified evaluations created until the end of the challenge and done when it is
+// frozen. Its owner freezes it once, after the end of the challenge, the certified testtuples of the
+// objective then no longer accepting results.
+func freezeObjectiveLeaderboard(db LedgerDB, args []string) (outputLeaderboard, error) {
+	inp := inputHash{}
+	err := AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	objective, err := db.GetObjective(inp.Key)
 	if err != nil {
 		return outputLeaderboard{}, err
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	if requester != objective.Owner {
+		return outputLeaderboard{}, errors.Forbidden(errors.CodePermissionDeniedObjective, "%s is not allowed to freeze the leaderboard of objective %s", requester, inp.Key)
+	}
+	challenge := objective.Challenge
+	if challenge == nil || challenge.EndDate == "" {
+		return outputLeaderboard{}, errors.BadRequest("objective %s has no challenge end date", inp.Key)
+	}
+	if challenge.FrozenDate != "" {
+		return outputLeaderboard{}, errors.Conflict("the leaderboard of objective %s is already frozen", inp.Key)
+	}
+	now, err := getTxTime(db)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	end, err := time.Parse(time.RFC3339, challenge.EndDate)
+	if err != nil {
+		return outputLeaderboard{}, errors.Internal(err, "invalid end date of objective %s:", inp.Key)
+	}
+	if !now.After(end) {
+		return outputLeaderboard{}, errors.BadRequest("cannot freeze the leaderboard of objective %s before the end of its challenge at %s", inp.Key, challenge.EndDate)
+	}
+
+	challenge.FinalLeaderboard, err = getChallengeEntries(db, inp.Key, objective, end)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	challenge.FrozenDate = now.Format(time.RFC3339)
+	if err = db.Put(inp.Key, objective); err != nil {
+		return outputLeaderboard{}, err
+	}
+
+	out := outputLeaderboard{Testtuples: challenge.FinalLeaderboard}
+	out.Objective.Fill(inp.Key, objective)
+	return out, nil
+}
+
+// getChallengeEntries returns the leaderboard entries of the certified evaluations of an objective
+// created until the end of its challenge, ranked. An entry is created with the testtuple of its
+// evaluation on the first test dataset of the objective.
+func getChallengeEntries(db LedgerDB, objectiveKey string, objective Objective, end time.Time) (outputBoardTuples, error) {
+	boardTuples, err := getLeaderboardTuples(db, objectiveKey, objective)
+	if err != nil {
+		return nil, err
+	}
+	entries := outputBoardTuples{}
+	for _, boardTuple := range boardTuples {
+		testtuple, err := db.GetTesttuple(boardTuple.Key)
+		if err != nil {
+			return nil, err
+		}
+		creation, err := time.Parse(time.RFC3339, testtuple.CreationDate)
+		if err != nil {
+			return nil, errors.Internal(err, "invalid creation date of testtuple %s:", boardTuple.Key)
+		}
+		if !creation.After(end) {
+			entries = append(entries, boardTuple)
+		}
+	}
+	sort.Sort(sort.Reverse(entries))
+	return entries, nil
+}
+
+// revealPrivateLeaderboard shows the private perfs of the certified testtuples of an objective, on
+// its leaderboard and in its testtuples. Its owner, a member of the privatePerfs collection, reveals
+// them once.
+func revealPrivateLeaderboard(db LedgerDB, args []string) (outputLeaderboard, error) {
+	inp := inputHash{}
+	err := AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	objective, err := db.GetObjective(inp.Key)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	if requester != objective.Owner {
+		return outputLeaderboard{}, errors.Forbidden(errors.CodePermissionDeniedObjective, "%s is not allowed to reveal the private leaderboard of objective %s", requester, inp.Key)
+	}
+	if !objective.hasPrivateDataSamples() {
+		return outputLeaderboard{}, errors.BadRequest("objective %s has no private test dataSample", inp.Key)
+	}
+	if objective.PrivateLeaderboardRevealed {
+		return outputLeaderboard{}, errors.Conflict("the private leaderboard of objective %s is already revealed", inp.Key)
+	}
+	member, err := db.IsCollectionMember(privatePerfsCollection)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	if !member {
+		return outputLeaderboard{}, errors.Forbidden(errors.CodePermissionDeniedObjective, "%s cannot read the private perfs of objective %s", requester, inp.Key)
+	}
+	if err = revealPrivatePerfs(db, inp.Key); err != nil {
+		return outputLeaderboard{}, err
+	}
+	objective.PrivateLeaderboardRevealed = true
+	if err = db.Put(inp.Key, objective); err != nil {
+		return outputLeaderboard{}, err
+	}
+
+	boardTuples, err := getLeaderboardTuples(db, inp.Key, objective)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
+	sort.Sort(sort.Reverse(boardTuples))
+	out := outputLeaderboard{Testtuples: boardTuples}
+	out.Objective.Fill(inp.Key, objective)
+	return out, nil
+}
 
+// revealPrivatePerfs copies the private perfs of the done certified testtuples of an objective
+// from the privatePerfs private data collection to the public state
+func revealPrivatePerfs(db LedgerDB, objectiveKey string) error {
+	testtupleKeys, err := db.GetIndexKeys("testtuple~objective~certified~key", []string{"testtuple", objectiveKey, "true"})
+	if err != nil {
+		return err
+	}
 	for _, testtupleKey := range testtupleKeys {
-		var boardTuple outputBoardTuple
 		testtuple, err := db.GetTesttuple(testtupleKey)
 		if err != nil {
-			return outputLeaderboard{}, err
+			return err
 		}
-		if testtuple.Status != StatusDone {
+		if testtuple.Status != StatusDone || testtuple.Dataset.PrivatePerfHash == "" {
 			continue
 		}
-		err = boardTuple.Fill(db, testtuple, testtupleKey)
+		value, err := db.GetPrivateField(privatePerfsCollection, testtupleKey, testtuple.Dataset.PrivatePerfHash)
 		if err != nil {
-			return outputLeaderboard{}, err
+			return err
+		}
+		if value == "" {
+			return errors.Internal("the private perf of testtuple %s is missing or does not match its hash", testtupleKey)
+		}
+		if testtuple.Dataset.PrivatePerf, err = parsePrivatePerf(value); err != nil {
+			return errors.Internal(err, "invalid private perf of testtuple %s:", testtupleKey)
+		}
+		if err = db.Put(testtupleKey, testtuple); err != nil {
+			return err
 		}
-		out.Testtuples = append(out.Testtuples, boardTuple)
 	}
+	return nil
+}
 
-	if inp.AscendingOrder {
-		sort.Sort(out.Testtuples)
-	} else {
-		sort.Sort(sort.Reverse(out.Testtuples))
+// formatPrivatePerf returns the value of a perf stored in a private data collection, prefixed
+// with the salt chosen by the worker so that its hash in the public state cannot be brute-forced
+func formatPrivatePerf(salt string, perf float32) string {
+	return salt + ":" + strconv.FormatFloat(float64(perf), 'g', -1, 32)
+}
+
+// parsePrivatePerf returns the perf of a value stored in a private data collection, after its salt
+func parsePrivatePerf(value string) (float32, error) {
+	perf, err := strconv.ParseFloat(value[strings.LastIndex(value, ":")+1:], 32)
+	return float32(perf), err
+}
+
+// getLeaderboardTuples returns the entries of the leaderboard of an objective, unsorted
+func getLeaderboardTuples(db LedgerDB, objectiveKey string, objective Objective) (outputBoardTuples, error) {
+	testtupleKeys, err := db.GetIndexKeys("testtuple~objective~certified~key", []string{"testtuple", objectiveKey, "true"})
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+
+	// the certified testtuples of an evaluation, one per test dataset, make a single entry
+	testDatasets := objective.getTestDatasets()
+	evaluationKeys := []string{}
+	evaluationTuples := map[string]*outputBoardTuple{}
+	boardDatasets := map[string][]*outputBoardDataset{}
+	for _, testtupleKey := range testtupleKeys {
+		testtuple, err := db.GetTesttuple(testtupleKey)
+		if err != nil {
+			return nil, err
+		}
+		if testtuple.Status != StatusDone {
+			continue
+		}
+		position := -1
+		for i, dataset := range testDatasets {
+			if dataset.DataManagerKey == testtuple.Dataset.OpenerHash {
+				position = i
+			}
+		}
+		if position < 0 {
+			continue
+		}
+		evaluationKey := testtuple.getEvaluationKey()
+		if _, ok := boardDatasets[evaluationKey]; !ok {
+			evaluationKeys = append(evaluationKeys, evaluationKey)
+			boardDatasets[evaluationKey] = make([]*outputBoardDataset, len(testDatasets))
+		}
+		boardDataset := &outputBoardDataset{}
+		boardDataset.Fill(testtuple, testtupleKey, objective.PrivateLeaderboardRevealed)
+		boardDatasets[evaluationKey][position] = boardDataset
+		// the entry is the one of the testtuple on the first test dataset
+		if position == 0 {
+			var boardTuple outputBoardTuple
+			err = boardTuple.Fill(db, testtuple, testtupleKey)
+			if err != nil {
+				return nil, err
+			}
+			evaluationTuples[evaluationKey] = &boardTuple
+		}
+	}
+
+	// only the evaluations done on all the test datasets are ranked
+	boardTuples := outputBoardTuples{}
+	for _, evaluationKey := range evaluationKeys {
+		boardTuple, ok := evaluationTuples[evaluationKey]
+		if !ok {
+			continue
+		}
+		complete := true
+		var weightedPerf, nbDataSamples, weightedPrivatePerf, nbPrivateDataSamples float32
+		boardTuple.Datasets = []outputBoardDataset{}
+		for _, boardDataset := range boardDatasets[evaluationKey] {
+			if boardDataset == nil {
+				complete = false
+				break
+			}
+			boardTuple.Datasets = append(boardTuple.Datasets, *boardDataset)
+			weightedPerf += boardDataset.Perf * float32(boardDataset.NbDataSamples)
+			nbDataSamples += float32(boardDataset.NbDataSamples)
+			weightedPrivatePerf += boardDataset.PrivatePerf * float32(boardDataset.NbPrivateDataSamples)
+			nbPrivateDataSamples += float32(boardDataset.NbPrivateDataSamples)
+		}
+		if !complete {
+			continue
+		}
+		if nbDataSamples > 0 {
+			boardTuple.Perf = weightedPerf / nbDataSamples
+		}
+		if nbPrivateDataSamples > 0 {
+			boardTuple.PrivatePerf = weightedPrivatePerf / nbPrivateDataSamples
+		}
+		boardTuples = append(boardTuples, *boardTuple)
+	}
+
+	return boardTuples, nil
 }
 
 // -------------------------------------------------------------------------------------------
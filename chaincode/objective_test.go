@@ -15,9 +15,15 @@
 package main
 
 import (
+	"chaincode/errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,18 +52,18 @@ func TestLeaderBoard(t *testing.T) {
 	assert.Len(t, leaderboard.Testtuples, 0)
 
 	// Update testtuple status directly
-	testtuple, err := db.GetTesttuple(keyMap["key"])
+	testtuple, err := db.GetTesttuple(keyMap.Key)
 	assert.NoError(t, err)
 	testtuple.Status = StatusDone
 	testtuple.Dataset.Perf = 0.9
-	err = db.Put(keyMap["key"], testtuple)
+	err = db.Put(keyMap.Key, testtuple)
 	assert.NoError(t, err)
 
 	leaderboard, err = queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
 	assert.NoError(t, err)
 	assert.Equal(t, objectiveDescriptionHash, leaderboard.Objective.Key)
 	require.Len(t, leaderboard.Testtuples, 1)
-	assert.Equal(t, keyMap["key"], leaderboard.Testtuples[0].Key)
+	assert.Equal(t, keyMap.Key, leaderboard.Testtuples[0].Key)
 }
 func TestRegisterObjectiveWhitoutDataset(t *testing.T) {
 	scc := new(SubstraChaincode)
@@ -145,13 +151,15 @@ func TestObjective(t *testing.T) {
 	objective := outputObjective{}
 	err = bytesToStruct(resp.Payload, &objective)
 	assert.NoError(t, err, "when unmarshalling queried objective")
+	testDataset := &Dataset{
+		DataManagerKey: dataManagerOpenerHash,
+		DataSampleKeys: []string{testDataSampleHash1, testDataSampleHash2},
+	}
 	expectedObjective := outputObjective{
-		Key:   objectiveKey,
-		Owner: worker,
-		TestDataset: &Dataset{
-			DataManagerKey: dataManagerOpenerHash,
-			DataSampleKeys: []string{testDataSampleHash1, testDataSampleHash2},
-		},
+		Key:          objectiveKey,
+		Owner:        worker,
+		TestDataset:  testDataset,
+		TestDatasets: []*Dataset{testDataset},
 		Name: inpObjective.Name,
 		Description: HashDress{
 			StorageAddress: inpObjective.DescriptionStorageAddress,
@@ -165,6 +173,7 @@ func TestObjective(t *testing.T) {
 			Name:           inpObjective.MetricsName,
 			StorageAddress: inpObjective.MetricsStorageAddress,
 		},
+		SkipEvaluationTags: []string{},
 	}
 	assert.Exactly(t, expectedObjective, objective)
 
@@ -178,3 +187,508 @@ func TestObjective(t *testing.T) {
 	assert.Len(t, objectives, 1)
 	assert.Exactly(t, expectedObjective, objectives[0], "return objective different from registered one")
 }
+
+func TestObjectiveTestDatasets(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	registerTestDataset := func(creator string, dataManagerKey string, dataSampleKeys ...string) {
+		mockStub.Creator = creator
+		defer func() { mockStub.Creator = "" }()
+		inpDataManager := inputDataManager{OpenerHash: dataManagerKey}
+		resp := mockStub.MockInvoke("42", inpDataManager.createDefault())
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+		inpDataSample := inputDataSample{Hashes: dataSampleKeys, DataManagerKeys: []string{dataManagerKey}, TestOnly: "true"}
+		resp = mockStub.MockInvoke("42", inpDataSample.createDefault())
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+	}
+	logTest := func(creator string, testtupleKey string, perf float32) {
+		mockStub.Creator = creator
+		defer func() { mockStub.Creator = "" }()
+		status, message, _ := invoke("logStartTest", inputHash{testtupleKey})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTest{Perf: perf}
+		success.Key = testtupleKey
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTest", success)
+		require.EqualValues(t, 200, status, message)
+	}
+	queryLeaderboard := func(objectiveKey string) outputLeaderboard {
+		status, message, payload := invoke("queryObjectiveLeaderboard", inputLeaderboard{ObjectiveKey: objectiveKey})
+		require.EqualValues(t, 200, status, message)
+		leaderboard := outputLeaderboard{}
+		require.NoError(t, json.Unmarshal(payload, &leaderboard))
+		return leaderboard
+	}
+
+	// An objective with test datasets on two workers
+	mockStub.Creator = "OtherOrg"
+	mockStub.MockInvoke("42", [][]byte{[]byte("registerNode")})
+	mockStub.Creator = ""
+	localDataManagerKey := "aa1a20b2a67c8003cc748d6666534f2b01f3f08d175440537a5bf86b7d08d5ee"
+	otherDataManagerKey := "aa2a20b2a67c8003cc748d6666534f2b01f3f08d175440537a5bf86b7d08d5ee"
+	registerTestDataset("", localDataManagerKey,
+		"cc1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+		"cc2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+		"cc3bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc")
+	registerTestDataset("OtherOrg", otherDataManagerKey,
+		"cc4bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc")
+	objectiveKey := "5c2d9cd1c2c1082dde0921b56d11030c81f62fbb51932758b58ac2569dd0b379"
+	inpObjective := inputObjective{
+		DescriptionHash: objectiveKey,
+		TestDataset: inputDataset{
+			DataManagerKey: localDataManagerKey,
+			DataSampleKeys: []string{
+				"cc1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+				"cc2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+				"cc3bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc",
+			},
+		},
+		TestDatasets: []inputDataset{{
+			DataManagerKey: localDataManagerKey,
+			DataSampleKeys: []string{"cc1bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"},
+		}},
+	}
+	inpObjective.createDefault()
+	status, message, _ := invoke("registerObjective", inpObjective)
+	assert.EqualValues(t, 400, status, "the test datasets must have different data managers: %s", message)
+	inpObjective.TestDatasets = []inputDataset{{
+		DataManagerKey: otherDataManagerKey,
+		DataSampleKeys: []string{"cc4bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"},
+	}}
+	// the data manager of the other worker is associated with the objective too
+	status, message, payload := invoke("registerObjective", inpObjective)
+	require.EqualValues(t, 200, status, message)
+
+	status, message, payload = invoke("queryObjective", inputHash{objectiveKey})
+	require.EqualValues(t, 200, status, message)
+	objective := outputObjective{}
+	require.NoError(t, json.Unmarshal(payload, &objective))
+	require.Len(t, objective.TestDatasets, 2)
+	assert.Equal(t, localDataManagerKey, objective.TestDataset.DataManagerKey)
+	assert.Equal(t, otherDataManagerKey, objective.TestDatasets[1].DataManagerKey)
+
+	// A certified evaluation creates one testtuple per test dataset
+	inpTraintuple := inputTraintuple{ObjectiveKey: objectiveKey}
+	inpTraintuple.createDefault()
+	status, message, _ = invoke("createTraintuple", inpTraintuple)
+	require.EqualValues(t, 200, status, message)
+	success := inputLogSuccessTrain{}
+	success.createDefault()
+	status, message, _ = invoke("logStartTrain", inputHash{traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	status, message, _ = invoke("logSuccessTrain", success)
+	require.EqualValues(t, 200, status, message)
+
+	status, message, payload = invoke("createTesttuple", inputTesttuple{TraintupleKey: traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	created := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &created))
+	require.Len(t, created.Keys, 2)
+	assert.Equal(t, created.Key, created.Keys[0])
+	event := getLastEvent(t, mockStub)
+	require.Len(t, event.Testtuples, 2)
+	assert.Equal(t, worker, event.Testtuples[0].Dataset.Worker)
+	assert.Equal(t, "OtherOrg", event.Testtuples[1].Dataset.Worker)
+	assert.True(t, event.Testtuples[1].Certified)
+
+	// The evaluation is ranked on its perf weighted by sample count once done on all the test datasets
+	logTest("", created.Keys[0], 0.5)
+	assert.Len(t, queryLeaderboard(objectiveKey).Testtuples, 0)
+	logTest("OtherOrg", created.Keys[1], 0.9)
+	leaderboard := queryLeaderboard(objectiveKey)
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, created.Key, leaderboard.Testtuples[0].Key)
+	assert.InDelta(t, 0.6, leaderboard.Testtuples[0].Perf, 1e-6)
+	assert.Equal(t, []outputBoardDataset{
+		{DataManagerKey: localDataManagerKey, NbDataSamples: 3, Perf: 0.5, TesttupleKey: created.Keys[0], Worker: worker},
+		{DataManagerKey: otherDataManagerKey, NbDataSamples: 1, Perf: 0.9, TesttupleKey: created.Keys[1], Worker: "OtherOrg"},
+	}, leaderboard.Testtuples[0].Datasets)
+
+	// The models return all their certified testtuples, the first one being on the objective test dataset
+	status, message, payload = invoke("queryModelDetails", inputHash{traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	details := outputModelDetails{}
+	require.NoError(t, json.Unmarshal(payload, &details))
+	require.Len(t, details.Testtuples, 2)
+	assert.Equal(t, created.Keys, []string{details.Testtuples[0].Key, details.Testtuples[1].Key})
+	assert.Equal(t, created.Key, details.Testtuple.Key)
+	assert.Empty(t, details.NonCertifiedTesttuples)
+	resp := mockStub.MockInvoke("42", [][]byte{[]byte("queryModels")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	models := []outputModel{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &models))
+	require.Len(t, models, 1)
+	require.Len(t, models[0].Testtuples, 2)
+	assert.Equal(t, created.Keys, []string{models[0].Testtuples[0].Key, models[0].Testtuples[1].Key})
+	assert.Equal(t, created.Key, models[0].Testtuple.Key)
+}
+
+func TestObjectiveAutoEvaluate(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "testDataset")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	train := func(key string) []outputTesttuple {
+		status, message, _ := invoke("logStartTrain", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTrain{}
+		success.Key = key
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTrain", success)
+		require.EqualValues(t, 200, status, message)
+		return getLastEvent(t, mockStub).Testtuples
+	}
+	createComputePlan := func(inp inputComputePlan) outputComputePlan {
+		status, message, payload := invoke("createComputePlan", inp)
+		require.EqualValues(t, 200, status, message)
+		plan := outputComputePlan{}
+		require.NoError(t, json.Unmarshal(payload, &plan))
+		return plan
+	}
+
+	inpObjective := inputObjective{AutoEvaluate: true, SkipEvaluationTags: []string{"debug"}}
+	resp := mockStub.MockInvoke("42", inpObjective.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpDataSample := inputDataSample{}
+	resp = mockStub.MockInvoke("42", inpDataSample.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpAlgo := inputAlgo{}
+	resp = mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// A done traintuple of a compute plan is evaluated in the same transaction
+	inpComputePlan := defaultComputePlan
+	inpComputePlan.Testtuples = nil
+	plan := createComputePlan(inpComputePlan)
+	testtuples := train(plan.TraintupleKeys[0])
+	require.Len(t, testtuples, 1)
+	assert.Equal(t, StatusTodo, testtuples[0].Status)
+	assert.True(t, testtuples[0].Certified)
+	assert.Equal(t, worker, testtuples[0].Creator)
+	assert.Equal(t, plan.TraintupleKeys[0], testtuples[0].Model.TraintupleKey)
+	assert.Equal(t, modelHash, testtuples[0].Model.Hash)
+
+	// The testtuple already created by the creator of the traintuple is kept
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	status, message, _ := invoke("createTraintuple", inpTraintuple)
+	require.EqualValues(t, 200, status, message)
+	status, message, payload := invoke("createTesttuple", inputTesttuple{TraintupleKey: traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	created := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &created))
+	testtuples = train(traintupleKey)
+	require.Len(t, testtuples, 1)
+	assert.Equal(t, created.Key, testtuples[0].Key)
+
+	// The traintuples can opt out with their tag or their compute plan
+	inpTraintuple = inputTraintuple{DataSampleKeys: []string{trainDataSampleHash2}, Tag: "debug"}
+	inpTraintuple.createDefault()
+	status, message, payload = invoke("createTraintuple", inpTraintuple)
+	require.EqualValues(t, 200, status, message)
+	res := map[string]string{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	assert.Len(t, train(res["key"]), 0)
+
+	otherAlgoHash := "fd2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"
+	inpAlgo = inputAlgo{Name: "baseline", Hash: otherAlgoHash}
+	resp = mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpComputePlan.AlgoKey = otherAlgoHash
+	inpComputePlan.SkipEvaluation = true
+	plan = createComputePlan(inpComputePlan)
+	assert.Len(t, train(plan.TraintupleKeys[0]), 0)
+}
+
+func TestObjectiveChallenge(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "testDataset")
+
+	day := int64(24 * 60 * 60)
+	setTime := func(seconds int64) {
+		mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp + seconds}
+	}
+	date := func(seconds int64) string {
+		return time.Unix(pipelineTimestamp+seconds, 0).UTC().Format(time.RFC3339)
+	}
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	train := func(inp inputTraintuple) string {
+		inp.createDefault()
+		status, message, payload := invoke("createTraintuple", inp)
+		require.EqualValues(t, 200, status, message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(payload, &res))
+		status, message, _ = invoke("logStartTrain", inputHash{res["key"]})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTrain{}
+		success.Key = res["key"]
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTrain", success)
+		require.EqualValues(t, 200, status, message)
+		return res["key"]
+	}
+	test := func(key string) {
+		status, message, _ := invoke("logStartTest", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTest{}
+		success.Key = key
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTest", success)
+		require.EqualValues(t, 200, status, message)
+	}
+	queryLeaderboard := func(final bool) (int32, string, outputLeaderboard) {
+		status, message, payload := invoke("queryObjectiveLeaderboard", inputLeaderboard{ObjectiveKey: objectiveDescriptionHash, Final: final})
+		leaderboard := outputLeaderboard{}
+		if status == 200 {
+			require.NoError(t, json.Unmarshal(payload, &leaderboard))
+		}
+		return status, message, leaderboard
+	}
+
+	// A challenge of two days, with one submission per node and per day
+	setTime(0)
+	inpObjective := inputObjective{Challenge: inputChallenge{StartDate: date(day), EndDate: date(0)}}
+	resp := mockStub.MockInvoke("42", inpObjective.createDefault())
+	assert.EqualValues(t, 400, resp.Status, "the challenge must end after it starts: %s", resp.Message)
+	inpObjective.Challenge = inputChallenge{StartDate: date(100), EndDate: date(2 * day), MaxDailySubmissions: 1}
+	resp = mockStub.MockInvoke("42", inpObjective.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpDataSample := inputDataSample{}
+	resp = mockStub.MockInvoke("42", inpDataSample.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpAlgo := inputAlgo{}
+	resp = mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	firstKey := train(inputTraintuple{})
+	secondKey := train(inputTraintuple{DataSampleKeys: []string{trainDataSampleHash1}})
+	thirdKey := train(inputTraintuple{DataSampleKeys: []string{trainDataSampleHash2}})
+
+	status, message, _ := invoke("createTesttuple", inputTesttuple{TraintupleKey: firstKey})
+	assert.EqualValues(t, 400, status, "the challenge has not started: %s", message)
+	assert.Contains(t, message, errors.CodeChallengeClosed)
+	setTime(200)
+	status, message, payload := invoke("createTesttuple", inputTesttuple{TraintupleKey: firstKey})
+	require.EqualValues(t, 200, status, message)
+	first := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &first))
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: secondKey})
+	assert.EqualValues(t, 400, status, "one submission per day: %s", message)
+	assert.Contains(t, message, errors.CodeChallengeQuotaExceeded)
+	// the non-certified testtuples are not limited
+	status, message, _ = invoke("createTesttuple", inputTesttuple{
+		TraintupleKey:  secondKey,
+		DataManagerKey: dataManagerOpenerHash,
+		DataSampleKeys: []string{testDataSampleHash1},
+	})
+	assert.EqualValues(t, 200, status, message)
+	setTime(day + 200)
+	status, message, payload = invoke("createTesttuple", inputTesttuple{TraintupleKey: secondKey})
+	require.EqualValues(t, 200, status, message)
+	second := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &second))
+	test(first.Key)
+	setTime(2*day - 100)
+	status, message, payload = invoke("createTesttuple", inputTesttuple{TraintupleKey: thirdKey})
+	require.EqualValues(t, 200, status, message)
+	third := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &third))
+
+	// The owner freezes the final leaderboard once the challenge ended
+	status, message, _ = queryLeaderboard(true)
+	assert.EqualValues(t, 404, status, message)
+	status, message, _ = invoke("freezeObjectiveLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 400, status, "the challenge has not ended: %s", message)
+	setTime(2*day + 1)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: secondKey})
+	assert.EqualValues(t, 400, status, "the challenge has ended: %s", message)
+	assert.Contains(t, message, errors.CodeChallengeClosed)
+	// the evaluations submitted before the end can still be done until the freeze
+	test(second.Key)
+	status, message, _ = invoke("logStartTest", inputHash{third.Key})
+	require.EqualValues(t, 200, status, message)
+	mockStub.Creator = "OtherOrg"
+	status, message, _ = invoke("freezeObjectiveLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+	status, message, _ = invoke("freezeObjectiveLeaderboard", inputHash{objectiveDescriptionHash})
+	require.EqualValues(t, 200, status, message)
+	status, message, _ = invoke("freezeObjectiveLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 409, status, message)
+
+	// The final leaderboard is the snapshot of the evaluations submitted before the end of the
+	// challenge and done when it was frozen, with their perf and creator
+	status, message, leaderboard := queryLeaderboard(true)
+	require.EqualValues(t, 200, status, message)
+	require.Len(t, leaderboard.Testtuples, 2)
+	assert.ElementsMatch(t, []string{first.Key, second.Key}, []string{leaderboard.Testtuples[0].Key, leaderboard.Testtuples[1].Key})
+	for _, entry := range leaderboard.Testtuples {
+		assert.Equal(t, worker, entry.Creator)
+		assert.InDelta(t, 0.9, entry.Perf, 1e-6)
+	}
+	assert.Equal(t, leaderboard.Testtuples, leaderboard.Objective.Challenge.FinalLeaderboard)
+	assert.Equal(t, date(2*day+1), leaderboard.Objective.Challenge.FrozenDate)
+
+	// The certified testtuples no longer accept results once the leaderboard is frozen
+	success := inputLogSuccessTest{}
+	success.Key = third.Key
+	success.createDefault()
+	status, message, _ = invoke("logSuccessTest", success)
+	assert.EqualValues(t, 400, status, message)
+	assert.Contains(t, message, errors.CodeChallengeClosed)
+	status, message, frozen := queryLeaderboard(true)
+	require.EqualValues(t, 200, status, message)
+	assert.Equal(t, leaderboard.Testtuples, frozen.Testtuples)
+}
+
+func TestObjectivePrivateLeaderboard(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "testDataset")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	train := func(inp inputTraintuple) string {
+		inp.createDefault()
+		status, message, payload := invoke("createTraintuple", inp)
+		require.EqualValues(t, 200, status, message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(payload, &res))
+		status, message, _ = invoke("logStartTrain", inputHash{res["key"]})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTrain{}
+		success.Key = res["key"]
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTrain", success)
+		require.EqualValues(t, 200, status, message)
+		return res["key"]
+	}
+	salt := "3f8b2c1d9e7a6b5c4d3e2f1a0b9c8d7e"
+	evaluate := func(traintupleKey string, perf float32, privatePerf float32) string {
+		status, message, payload := invoke("createTesttuple", inputTesttuple{TraintupleKey: traintupleKey})
+		require.EqualValues(t, 200, status, message)
+		created := outputCreatedTesttuple{}
+		require.NoError(t, json.Unmarshal(payload, &created))
+		status, message, _ = invoke("logStartTest", inputHash{created.Key})
+		require.EqualValues(t, 200, status, message)
+		success := inputLogSuccessTest{Perf: perf, PrivatePerf: privatePerf, PrivatePerfSalt: salt}
+		success.Key = created.Key
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTest", success)
+		require.EqualValues(t, 200, status, message)
+		return created.Key
+	}
+	queryTesttuple := func(key string) outputTesttuple {
+		status, message, payload := invoke("queryTesttuple", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		testtuple := outputTesttuple{}
+		require.NoError(t, json.Unmarshal(payload, &testtuple))
+		return testtuple
+	}
+	queryLeaderboard := func() outputLeaderboard {
+		status, message, payload := invoke("queryObjectiveLeaderboard", inputLeaderboard{ObjectiveKey: objectiveDescriptionHash})
+		require.EqualValues(t, 200, status, message)
+		leaderboard := outputLeaderboard{}
+		require.NoError(t, json.Unmarshal(payload, &leaderboard))
+		return leaderboard
+	}
+
+	// The private data samples must be some of the test data samples, keeping one of them public
+	inpObjective := inputObjective{}
+	inpObjective.TestDataset.PrivateDataSampleKeys = []string{trainDataSampleHash1}
+	resp := mockStub.MockInvoke("42", inpObjective.createDefault())
+	assert.EqualValues(t, 400, resp.Status, "the private data samples must be test data samples: %s", resp.Message)
+	inpObjective.TestDataset.PrivateDataSampleKeys = []string{testDataSampleHash1, testDataSampleHash2}
+	resp = mockStub.MockInvoke("42", inpObjective.createDefault())
+	assert.EqualValues(t, 400, resp.Status, "a test data sample must stay public: %s", resp.Message)
+	inpObjective.TestDataset.PrivateDataSampleKeys = []string{testDataSampleHash2}
+	resp = mockStub.MockInvoke("42", inpObjective.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpDataSample := inputDataSample{}
+	resp = mockStub.MockInvoke("42", inpDataSample.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	inpAlgo := inputAlgo{}
+	resp = mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// The certified testtuples evaluate both partitions, only the public perfs being shown
+	firstKey := evaluate(train(inputTraintuple{}), 0.9, 0.4)
+	secondKey := evaluate(train(inputTraintuple{DataSampleKeys: []string{trainDataSampleHash1}}), 0.7, 0.8)
+	testtuple := queryTesttuple(firstKey)
+	assert.Equal(t, []string{testDataSampleHash2}, testtuple.Dataset.PrivateDataSampleKeys)
+	assert.EqualValues(t, 0.9, testtuple.Dataset.Perf)
+	assert.Zero(t, testtuple.Dataset.PrivatePerf)
+	leaderboard := queryLeaderboard()
+	assert.False(t, leaderboard.Objective.PrivateLeaderboardRevealed)
+	require.Len(t, leaderboard.Testtuples, 2)
+	assert.Equal(t, firstKey, leaderboard.Testtuples[0].Key)
+	assert.Zero(t, leaderboard.Testtuples[0].PrivatePerf)
+	assert.Equal(t, 1, leaderboard.Testtuples[0].Datasets[0].NbDataSamples)
+	assert.Equal(t, 1, leaderboard.Testtuples[0].Datasets[0].NbPrivateDataSamples)
+
+	// The private perf is passed in the transient map with its salt, and only the hash of both is
+	// stored in the public state, so that it cannot be brute-forced from the possible perfs
+	stored := Testtuple{}
+	require.NoError(t, json.Unmarshal(mockStub.State[firstKey], &stored))
+	assert.Zero(t, stored.Dataset.PrivatePerf)
+	unsalted := sha256.Sum256([]byte(strconv.FormatFloat(0.4, 'g', -1, 32)))
+	assert.NotEqual(t, hex.EncodeToString(unsalted[:]), stored.Dataset.PrivatePerfHash)
+	assert.Equal(t, hashPrivateField(salt+":0.4"), stored.Dataset.PrivatePerfHash)
+	assert.Equal(t, []byte(salt+":0.4"), mockStub.PvtState[privatePerfsCollection][firstKey])
+	args, err := json.Marshal(inputLogSuccessTest{inputLog: inputLog{Key: firstKey}, PrivatePerf: 0.4, PrivatePerfSalt: salt})
+	require.NoError(t, err)
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("logSuccessTest"), args})
+	assert.EqualValues(t, 400, resp.Status, "the private perf is rejected in the args: %s", resp.Message)
+	status, message, payload := invoke("createTesttuple", inputTesttuple{TraintupleKey: train(inputTraintuple{DataSampleKeys: []string{trainDataSampleHash2}})})
+	require.EqualValues(t, 200, status, message)
+	unsaltedTesttuple := outputCreatedTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &unsaltedTesttuple))
+	status, message, _ = invoke("logStartTest", inputHash{unsaltedTesttuple.Key})
+	require.EqualValues(t, 200, status, message)
+	for _, unsaltedSalt := range []string{"", "short"} {
+		success := inputLogSuccessTest{Perf: 0.5, PrivatePerf: 0.5, PrivatePerfSalt: unsaltedSalt}
+		success.Key = unsaltedTesttuple.Key
+		success.createDefault()
+		status, message, _ = invoke("logSuccessTest", success)
+		assert.EqualValues(t, 400, status, "the private perf must be salted: %s", message)
+	}
+
+	// Only the owner of the objective reveals its private leaderboard, once, if it can read the private perfs
+	mockStub.Creator = "OtherOrg"
+	mockStub.MockInvoke("42", [][]byte{[]byte("registerNode")})
+	status, message, _ = invoke("revealPrivateLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 403, status, "only the owner reveals the private leaderboard: %s", message)
+	mockStub.Creator = ""
+	mockStub.CollectionMembers = map[string][]string{privatePerfsCollection: {"OtherOrg"}}
+	status, message, _ = invoke("revealPrivateLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 403, status, "the owner must read the private perfs: %s", message)
+	mockStub.CollectionMembers = nil
+	status, message, payload = invoke("revealPrivateLeaderboard", inputHash{objectiveDescriptionHash})
+	require.EqualValues(t, 200, status, message)
+	leaderboard = outputLeaderboard{}
+	require.NoError(t, json.Unmarshal(payload, &leaderboard))
+	assert.True(t, leaderboard.Objective.PrivateLeaderboardRevealed)
+	require.Len(t, leaderboard.Testtuples, 2)
+	assert.Equal(t, secondKey, leaderboard.Testtuples[0].Key, "the entries are ranked on their private perf")
+	assert.EqualValues(t, 0.8, leaderboard.Testtuples[0].PrivatePerf)
+	assert.EqualValues(t, 0.7, leaderboard.Testtuples[0].Perf)
+	assert.Equal(t, leaderboard.Testtuples, queryLeaderboard().Testtuples)
+	assert.EqualValues(t, 0.4, queryTesttuple(firstKey).Dataset.PrivatePerf)
+	status, message, _ = invoke("revealPrivateLeaderboard", inputHash{objectiveDescriptionHash})
+	assert.EqualValues(t, 409, status, "the private leaderboard is already revealed: %s", message)
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The outbox of a worker stores the tuples events it has to process under increasing
+// sequence numbers, until the worker acknowledges them. Since the sequence number of a
+// worker is updated by every transaction sending it tuples, such transactions are
+// serialized by the MVCC validation of Fabric.
+
+const outboxIndex = "outboxEvent~worker~sequence~key"
+
+// storeOutboxEvents splits the tuples of an event by worker and adds them to their outboxes
+func storeOutboxEvents(db LedgerDB, event TuplesEvent) error {
+	eventsByWorker := map[string]*TuplesEvent{}
+	getWorkerEvent := func(worker string) *TuplesEvent {
+		if _, ok := eventsByWorker[worker]; !ok {
+			eventsByWorker[worker] = &TuplesEvent{}
+		}
+		return eventsByWorker[worker]
+	}
+	for _, traintuple := range event.Traintuples {
+		workerEvent := getWorkerEvent(traintuple.Dataset.Worker)
+		workerEvent.Traintuples = append(workerEvent.Traintuples, traintuple)
+	}
+	for _, testtuple := range event.Testtuples {
+		workerEvent := getWorkerEvent(testtuple.Dataset.Worker)
+		workerEvent.Testtuples = append(workerEvent.Testtuples, testtuple)
+	}
+	for _, predicttuple := range event.Predicttuples {
+		workerEvent := getWorkerEvent(predicttuple.Dataset.Worker)
+		workerEvent.Predicttuples = append(workerEvent.Predicttuples, predicttuple)
+	}
+
+	// Sort the workers to store the events in a deterministic way
+	workers := []string{}
+	for worker := range eventsByWorker {
+		workers = append(workers, worker)
+	}
+	sort.Strings(workers)
+	for _, worker := range workers {
+		if err := addOutboxEvent(db, worker, *eventsByWorker[worker]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addOutboxEvent stores an event in the outbox of a worker with the next sequence number
+func addOutboxEvent(db LedgerDB, worker string, event TuplesEvent) error {
+	sequenceKey := HashForKey("outboxSequence", worker)
+	sequence := OutboxSequence{Worker: worker}
+	exists, err := db.KeyExists(sequenceKey)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := db.Get(sequenceKey, &sequence); err != nil {
+			return err
+		}
+	}
+	sequence.Sequence++
+	if err := db.Put(sequenceKey, sequence); err != nil {
+		return err
+	}
+
+	outboxEvent := OutboxEvent{
+		TuplesEvent: event,
+		Sequence:    sequence.Sequence,
+		TxID:        db.GetTxID(),
+		Worker:      worker,
+	}
+	key := getOutboxEventKey(worker, sequence.Sequence)
+	if err := db.Add(key, outboxEvent); err != nil {
+		return err
+	}
+	return db.CreateIndex(outboxIndex, []string{"outboxEvent", worker, formatSequence(sequence.Sequence), key})
+}
+
+// getOutboxEventKey returns the key of the event stored in the outbox of a worker
+func getOutboxEventKey(worker string, sequence int) string {
+	return HashForKey("outboxEvent", fmt.Sprintf("%s~%d", worker, sequence))
+}
+
+// formatSequence pads the sequence number so that the outbox index is sorted by sequence
+func formatSequence(sequence int) string {
+	return fmt.Sprintf("%020d", sequence)
+}
+
+// getOutboxEvents returns the events of the outbox of a worker, ordered by sequence number
+func getOutboxEvents(db LedgerDB, worker string) ([]OutboxEvent, error) {
+	events := []OutboxEvent{}
+	keys, err := db.GetIndexKeys(outboxIndex, []string{"outboxEvent", worker})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		event := OutboxEvent{}
+		if err := db.Get(key, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the outbox of the workers
+// -------------------------------------------------------------------------------------------
+
+// queryEventsSince returns the events of the requester's outbox with a sequence number
+// greater than the input one
+func queryEventsSince(db LedgerDB, args []string) (outEvents []OutboxEvent, err error) {
+	outEvents = []OutboxEvent{}
+	inp := inputEventsSince{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	worker, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
+	events, err := getOutboxEvents(db, worker)
+	if err != nil {
+		return
+	}
+	for _, event := range events {
+		if event.Sequence > inp.Sequence {
+			outEvents = append(outEvents, event)
+		}
+	}
+	return
+}
+
+// ackEvents removes from the requester's outbox the events with a sequence number
+// lower or equal to the input one
+func ackEvents(db LedgerDB, args []string) (resp map[string]int, err error) {
+	inp := inputAckEvents{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	worker, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
+	events, err := getOutboxEvents(db, worker)
+	if err != nil {
+		return
+	}
+	count := 0
+	for _, event := range events {
+		if event.Sequence > inp.Sequence {
+			break
+		}
+		key := getOutboxEventKey(worker, event.Sequence)
+		if err = db.Delete(key); err != nil {
+			return
+		}
+		if err = db.DeleteIndex(outboxIndex, []string{"outboxEvent", worker, formatSequence(event.Sequence), key}); err != nil {
+			return
+		}
+		count++
+	}
+	return map[string]int{"count": count}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryOutbox(t *testing.T, mockStub *MockStub, sequence int) []OutboxEvent {
+	resp := mockStub.MockInvoke("42", methodAndAssetToByte("queryEventsSince", inputEventsSince{Sequence: sequence}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	events := []OutboxEvent{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &events))
+	return events
+}
+
+func TestOutbox(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	assert.Len(t, queryOutbox(t, mockStub, 0), 1)
+
+	inpTraintuple := inputTraintuple{InModels: []string{traintupleKey}}
+	resp := mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("logStartTrain", inputHash{traintupleKey}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	success := inputLogSuccessTrain{}
+	resp = mockStub.MockInvoke("42", success.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// Starting a traintuple does not send any tuple to the worker
+	events := queryOutbox(t, mockStub, 0)
+	require.Len(t, events, 3)
+	for i, event := range events {
+		assert.Equal(t, i+1, event.Sequence)
+		assert.Equal(t, worker, event.Worker)
+		assert.NotEmpty(t, event.TxID)
+	}
+	assert.Equal(t, traintupleKey, events[0].Traintuples[0].Key)
+	require.Len(t, events[2].Traintuples, 1)
+	assert.Equal(t, StatusTodo, events[2].Traintuples[0].Status)
+
+	events = queryOutbox(t, mockStub, 2)
+	require.Len(t, events, 1)
+	assert.Equal(t, 3, events[0].Sequence)
+
+	// Another worker has its own outbox
+	mockStub.Creator = "OtherOrg"
+	assert.Len(t, queryOutbox(t, mockStub, 0), 0)
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("ackEvents", inputAckEvents{Sequence: 3}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.JSONEq(t, `{"count": 0}`, string(resp.Payload))
+	mockStub.Creator = ""
+
+	resp = mockStub.MockInvoke("42", methodAndAssetToByte("ackEvents", inputAckEvents{Sequence: 2}))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	assert.JSONEq(t, `{"count": 2}`, string(resp.Payload))
+	events = queryOutbox(t, mockStub, 0)
+	require.Len(t, events, 1)
+	assert.Equal(t, 3, events[0].Sequence)
+
+	// Sequence numbers keep increasing after an acknowledgment
+	inpTraintuple = inputTraintuple{DataSampleKeys: []string{trainDataSampleHash1}}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	events = queryOutbox(t, mockStub, 3)
+	require.Len(t, events, 1)
+	assert.Equal(t, 4, events[0].Sequence)
+}
@@ -21,13 +21,21 @@ import (
 // Struct use as output representation of ledger data
 
 type outputObjective struct {
-	Key         string            `json:"key"`
-	Name        string            `json:"name"`
-	Description HashDress         `json:"description"`
-	Metrics     *HashDressName    `json:"metrics"`
-	Owner       string            `json:"owner"`
-	TestDataset *Dataset          `json:"testDataset"`
-	Permissions outputPermissions `json:"permissions"`
+	Key          string            `json:"key"`
+	Name         string            `json:"name"`
+	Description  HashDress         `json:"description"`
+	Metrics      *HashDressName    `json:"metrics"`
+	Owner        string            `json:"owner"`
+	TestDataset  *Dataset          `json:"testDataset"`
+	TestDatasets []*Dataset        `json:"testDatasets"`
+	Permissions  outputPermissions `json:"permissions"`
+	Timeout      int               `json:"timeout"`
+	// AutoEvaluate and SkipEvaluationTags configure the automatic evaluation of the traintuples
+	AutoEvaluate       bool       `json:"autoEvaluate"`
+	SkipEvaluationTags []string   `json:"skipEvaluationTags"`
+	Challenge          *Challenge `json:"challenge"`
+	// PrivateLeaderboardRevealed is set once the private perfs are shown on the leaderboard
+	PrivateLeaderboardRevealed bool `json:"privateLeaderboardRevealed"`
 }
 
 func (out *outputObjective) Fill(key string, in Objective) {
@@ -38,7 +46,16 @@ func (out *outputObjective) Fill(key string, in Objective) {
 	out.Metrics = in.Metrics
 	out.Owner = in.Owner
 	out.TestDataset = in.TestDataset
+	out.TestDatasets = in.getTestDatasets()
 	out.Permissions.Fill(in.Permissions)
+	out.Timeout = in.Timeout
+	out.AutoEvaluate = in.AutoEvaluate
+	out.SkipEvaluationTags = in.SkipEvaluationTags
+	if out.SkipEvaluationTags == nil {
+		out.SkipEvaluationTags = []string{}
+	}
+	out.Challenge = in.Challenge
+	out.PrivateLeaderboardRevealed = in.PrivateLeaderboardRevealed
 }
 
 // outputDataManager is the return representation of the DataManager type stored in the ledger
@@ -50,6 +67,7 @@ type outputDataManager struct {
 	Opener       HashDress         `json:"opener"`
 	Owner        string            `json:"owner"`
 	Permissions  outputPermissions `json:"permissions"`
+	Redacted     bool              `json:"redacted"`
 	Type         string            `json:"type"`
 }
 
@@ -59,12 +77,25 @@ func (out *outputDataManager) Fill(key string, in DataManager) {
 	out.Key = key
 	out.Name = in.Name
 	out.Opener.Hash = key
-	out.Opener.StorageAddress = in.OpenerStorageAddress
+	out.Opener.StorageAddressHash = in.OpenerStorageAddressHash
 	out.Owner = in.Owner
 	out.Permissions.Fill(in.Permissions)
 	out.Type = in.Type
 }
 
+// fillPrivateFields sets the opener storage address, stored in a private data collection,
+// if the requester is a member of the collection
+func (out *outputDataManager) fillPrivateFields(db LedgerDB, in DataManager) (err error) {
+	out.Opener.StorageAddress, err = db.GetPrivateField(openerAddressesCollection, out.Key, in.OpenerStorageAddressHash)
+	return
+}
+
+// redact blanks the opener storage address of a dataManager the requester cannot process
+func (out *outputDataManager) redact() {
+	out.Opener.StorageAddress = ""
+	out.Redacted = true
+}
+
 type outputDataSample struct {
 	DataManagerKeys []string `json:"dataManagerKeys"`
 	Owner           string   `json:"owner"`
@@ -96,6 +127,7 @@ type outputAlgo struct {
 	Description *HashDress        `json:"description"`
 	Owner       string            `json:"owner"`
 	Permissions outputPermissions `json:"permissions"`
+	Redacted    bool              `json:"redacted"`
 }
 
 func (out *outputAlgo) Fill(key string, in Algo) {
@@ -108,22 +140,61 @@ func (out *outputAlgo) Fill(key string, in Algo) {
 	out.Permissions.Fill(in.Permissions)
 }
 
+// redact blanks the storage address of an algo the requester cannot process
+func (out *outputAlgo) redact() {
+	out.Content.StorageAddress = ""
+	out.Redacted = true
+}
+
+// outputExternalModel is the representation of a model registered with registerModel
+type outputExternalModel struct {
+	Key         string            `json:"key"`
+	Name        string            `json:"name"`
+	Content     HashDress         `json:"content"`
+	AlgoKey     string            `json:"algoKey"`
+	Owner       string            `json:"owner"`
+	Permissions outputPermissions `json:"permissions"`
+}
+
+// Fill sets the fields of the outputExternalModel, its storage address being only set if the
+// requester is a member of the modelAddresses private data collection
+func (out *outputExternalModel) Fill(db LedgerDB, key string, in ExternalModel) (err error) {
+	out.Key = key
+	out.Name = in.Name
+	out.Content.Hash = key
+	out.AlgoKey = in.AlgoKey
+	out.Owner = in.Owner
+	out.Permissions.Fill(in.Permissions)
+	out.Content.StorageAddress, err = db.GetPrivateField(modelAddressesCollection, key, in.StorageAddressHash)
+	return
+}
+
 // outputTraintuple is the representation of one the element type stored in the
 // ledger. It describes a training task occuring on the platform
 type outputTraintuple struct {
 	Key           string            `json:"key"`
 	Algo          *HashDressName    `json:"algo"`
+	Attempts      int               `json:"attempts"`
+	CreationDate  string            `json:"creationDate"`
 	Creator       string            `json:"creator"`
 	Dataset       *TtDataset        `json:"dataset"`
 	ComputePlanID string            `json:"computePlanID"`
+	FailureReport *FailureReport    `json:"failureReport"`
+	FullLog       *HashDress        `json:"fullLog"`
 	InModels      []*Model          `json:"inModels"`
 	Log           string            `json:"log"`
+	LogHash       string            `json:"logHash"`
 	Objective     *TtObjective      `json:"objective"`
 	OutModel      *HashDress        `json:"outModel"`
 	Permissions   outputPermissions `json:"permissions"`
+	Priority      int               `json:"priority"`
+	Progress      *Progress         `json:"progress"`
 	Rank          int               `json:"rank"`
+	Redacted      bool              `json:"redacted"`
+	StartDate     string            `json:"startDate"`
 	Status        string            `json:"status"`
 	Tag           string            `json:"tag"`
+	Timeout       int               `json:"timeout"`
 }
 
 //Fill is a method of the receiver outputTraintuple. It returns all elements necessary to do a training task from a trainuple stored in the ledger
@@ -132,11 +203,25 @@ func (outputTraintuple *outputTraintuple) Fill(db LedgerDB, traintuple Traintupl
 	outputTraintuple.Key = traintupleKey
 	outputTraintuple.Creator = traintuple.Creator
 	outputTraintuple.Permissions.Fill(traintuple.Permissions)
-	outputTraintuple.Log = traintuple.Log
 	outputTraintuple.Status = traintuple.Status
 	outputTraintuple.Rank = traintuple.Rank
+	outputTraintuple.Attempts = traintuple.Attempts
+	outputTraintuple.StartDate = traintuple.StartDate
+	outputTraintuple.Timeout = traintuple.Timeout
+	outputTraintuple.Priority = traintuple.Priority
+	outputTraintuple.CreationDate = traintuple.CreationDate
 	outputTraintuple.ComputePlanID = traintuple.ComputePlanID
-	outputTraintuple.OutModel = traintuple.OutModel
+	outputTraintuple.LogHash = traintuple.LogHash
+	outputTraintuple.FailureReport = traintuple.FailureReport
+	outputTraintuple.Progress = traintuple.Progress
+	if traintuple.FullLog != nil {
+		fullLog := *traintuple.FullLog
+		outputTraintuple.FullLog = &fullLog
+	}
+	if traintuple.OutModel != nil {
+		outModel := *traintuple.OutModel
+		outputTraintuple.OutModel = &outModel
+	}
 	outputTraintuple.Tag = traintuple.Tag
 	// fill algo
 	algo, err := db.GetAlgo(traintuple.AlgoKey)
@@ -173,16 +258,13 @@ func (outputTraintuple *outputTraintuple) Fill(db LedgerDB, traintuple Traintupl
 		if inModelKey == "" {
 			break
 		}
-		parentTraintuple, err := db.GetTraintuple(inModelKey)
+		parent, err := getParentModel(db, inModelKey)
 		if err != nil {
-			return fmt.Errorf("could not retrieve parent traintuple with key %s - %s", inModelKey, err.Error())
+			return fmt.Errorf("could not retrieve parent traintuple or model with key %s - %s", inModelKey, err.Error())
 		}
 		inModel := &Model{
 			TraintupleKey: inModelKey,
-		}
-		if parentTraintuple.OutModel != nil {
-			inModel.Hash = parentTraintuple.OutModel.Hash
-			inModel.StorageAddress = parentTraintuple.OutModel.StorageAddress
+			Hash:          parent.Hash,
 		}
 		outputTraintuple.InModels = append(outputTraintuple.InModels, inModel)
 	}
@@ -198,28 +280,134 @@ func (outputTraintuple *outputTraintuple) Fill(db LedgerDB, traintuple Traintupl
 	return
 }
 
+// fillPrivateFields sets the log and the models storage addresses, stored in private data
+// collections, if the requester is a member of the collections
+func (outputTraintuple *outputTraintuple) fillPrivateFields(db LedgerDB) error {
+	traintuple, err := db.GetTraintuple(outputTraintuple.Key)
+	if err != nil {
+		return err
+	}
+	outputTraintuple.Log, err = db.GetPrivateField(tupleLogsCollection, outputTraintuple.Key, traintuple.LogHash)
+	if err != nil {
+		return err
+	}
+	if err = fillFullLog(db, outputTraintuple.Key, outputTraintuple.FullLog); err != nil {
+		return err
+	}
+	if outputTraintuple.OutModel != nil {
+		outputTraintuple.OutModel.StorageAddress, err = getModelStorageAddress(db, outputTraintuple.Key)
+		if err != nil {
+			return err
+		}
+	}
+	for _, inModel := range outputTraintuple.InModels {
+		inModel.StorageAddress, err = getModelStorageAddress(db, inModel.TraintupleKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillFullLog sets the storage address of the full log of a tuple, if any, stored in the
+// private data collection of the logs
+func fillFullLog(db LedgerDB, tupleKey string, fullLog *HashDress) (err error) {
+	if fullLog != nil {
+		fullLog.StorageAddress, err = db.GetPrivateField(tupleLogsCollection, fullLogKey(tupleKey), fullLog.StorageAddressHash)
+	}
+	return err
+}
+
+// getModelStorageAddress returns the storage address of the model trained by a traintuple,
+// or of a registered model, stored in a private data collection, if the requester is a member
+// of the collection
+func getModelStorageAddress(db LedgerDB, traintupleKey string) (string, error) {
+	if model, err := db.GetExternalModel(traintupleKey); err == nil {
+		return db.GetPrivateField(modelAddressesCollection, traintupleKey, model.StorageAddressHash)
+	}
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	if err != nil || traintuple.OutModel == nil {
+		return "", err
+	}
+	return db.GetPrivateField(modelAddressesCollection, traintupleKey, traintuple.OutModel.StorageAddressHash)
+}
+
+// redact blanks the algo and models storage addresses of a traintuple the requester cannot process
+func (outputTraintuple *outputTraintuple) redact() {
+	if outputTraintuple.Algo != nil {
+		outputTraintuple.Algo.StorageAddress = ""
+	}
+	for _, inModel := range outputTraintuple.InModels {
+		inModel.StorageAddress = ""
+	}
+	if outputTraintuple.OutModel != nil {
+		outModel := *outputTraintuple.OutModel
+		outModel.StorageAddress = ""
+		outputTraintuple.OutModel = &outModel
+	}
+	outputTraintuple.Redacted = true
+}
+
+// outputCreatedTesttuple is the key of a created testtuple. Keys lists the keys of all the testtuples
+// of a certified evaluation on several test datasets, Key being the first of them.
+type outputCreatedTesttuple struct {
+	Key  string   `json:"key"`
+	Keys []string `json:"keys,omitempty"`
+}
+
 type outputTesttuple struct {
-	Key       string         `json:"key"`
-	Algo      *HashDressName `json:"algo"`
-	Certified bool           `json:"certified"`
-	Creator   string         `json:"creator"`
-	Dataset   *TtDataset     `json:"dataset"`
-	Log       string         `json:"log"`
-	Model     *Model         `json:"model"`
-	Objective *TtObjective   `json:"objective"`
-	Status    string         `json:"status"`
-	Tag       string         `json:"tag"`
+	Key           string         `json:"key"`
+	Algo          *HashDressName `json:"algo"`
+	Attempts      int            `json:"attempts"`
+	Certified     bool           `json:"certified"`
+	CreationDate  string         `json:"creationDate"`
+	Creator       string         `json:"creator"`
+	Dataset       *TtDataset     `json:"dataset"`
+	FailureReport *FailureReport `json:"failureReport"`
+	FullLog       *HashDress     `json:"fullLog"`
+	Log           string         `json:"log"`
+	LogHash       string         `json:"logHash"`
+	Model         *Model         `json:"model"`
+	Models        []*Model       `json:"models"`
+	Objective     *TtObjective   `json:"objective"`
+	Priority      int            `json:"priority"`
+	Redacted      bool           `json:"redacted"`
+	StartDate     string         `json:"startDate"`
+	Status        string         `json:"status"`
+	Tag           string         `json:"tag"`
+	Timeout       int            `json:"timeout"`
 }
 
 func (out *outputTesttuple) Fill(db LedgerDB, key string, in Testtuple) error {
 	out.Key = key
 	out.Certified = in.Certified
 	out.Creator = in.Creator
-	out.Dataset = in.Dataset
-	out.Log = in.Log
-	out.Model = in.Model
+	if in.Dataset != nil {
+		dataset := *in.Dataset
+		out.Dataset = &dataset
+	}
+	out.LogHash = in.LogHash
+	out.FailureReport = in.FailureReport
+	if in.FullLog != nil {
+		fullLog := *in.FullLog
+		out.FullLog = &fullLog
+	}
+	// Model is the first of the Models of an ensemble testtuple
+	out.Models = []*Model{}
+	for _, inModel := range in.getModels() {
+		model := *inModel
+		out.Models = append(out.Models, &model)
+	}
+	if len(out.Models) > 0 {
+		out.Model = out.Models[0]
+	}
 	out.Status = in.Status
 	out.Tag = in.Tag
+	out.Attempts = in.Attempts
+	out.StartDate = in.StartDate
+	out.Timeout = in.Timeout
+	out.Priority = in.Priority
+	out.CreationDate = in.CreationDate
 
 	// fill algo
 	algo, err := db.GetAlgo(in.AlgoKey)
@@ -247,24 +435,178 @@ func (out *outputTesttuple) Fill(db LedgerDB, key string, in Testtuple) error {
 		Key:     in.ObjectiveKey,
 		Metrics: &metrics,
 	}
+	// the private perf is hidden until the owner of the objective reveals the private leaderboard
+	if out.Dataset != nil && !objective.PrivateLeaderboardRevealed {
+		out.Dataset.PrivatePerf = 0
+	}
+	return nil
+}
+
+// fillPrivateFields sets the log and the model storage address, stored in private data
+// collections, if the requester is a member of the collections
+func (out *outputTesttuple) fillPrivateFields(db LedgerDB) error {
+	testtuple, err := db.GetTesttuple(out.Key)
+	if err != nil {
+		return err
+	}
+	out.Log, err = db.GetPrivateField(tupleLogsCollection, out.Key, testtuple.LogHash)
+	if err != nil {
+		return err
+	}
+	if err = fillFullLog(db, out.Key, out.FullLog); err != nil {
+		return err
+	}
+	for _, model := range out.Models {
+		if model.Hash == "" {
+			continue
+		}
+		model.StorageAddress, err = getModelStorageAddress(db, model.TraintupleKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redact blanks the algo and model storage addresses of a testtuple the requester cannot process
+func (out *outputTesttuple) redact() {
+	if out.Algo != nil {
+		out.Algo.StorageAddress = ""
+	}
+	for i, model := range out.Models {
+		out.Models[i] = &Model{TraintupleKey: model.TraintupleKey, Hash: model.Hash}
+	}
+	if len(out.Models) > 0 {
+		out.Model = out.Models[0]
+	}
+	out.Redacted = true
+}
+
+// outputPredicttuple is the representation of a Predicttuple, with its algo and its model
+type outputPredicttuple struct {
+	Key           string            `json:"key"`
+	Algo          *HashDressName    `json:"algo"`
+	Attempts      int               `json:"attempts"`
+	CreationDate  string            `json:"creationDate"`
+	Creator       string            `json:"creator"`
+	Dataset       *TtDataset        `json:"dataset"`
+	FailureReport *FailureReport    `json:"failureReport"`
+	FullLog       *HashDress        `json:"fullLog"`
+	Log           string            `json:"log"`
+	LogHash       string            `json:"logHash"`
+	Model         *Model            `json:"model"`
+	Permissions   outputPermissions `json:"permissions"`
+	Predictions   *HashDress        `json:"predictions"`
+	Priority      int               `json:"priority"`
+	Redacted      bool              `json:"redacted"`
+	StartDate     string            `json:"startDate"`
+	Status        string            `json:"status"`
+	Tag           string            `json:"tag"`
+	Timeout       int               `json:"timeout"`
+}
+
+// Fill sets the fields of the outputPredicttuple from a Predicttuple stored in the ledger
+func (out *outputPredicttuple) Fill(db LedgerDB, key string, in Predicttuple) error {
+	out.Key = key
+	out.Attempts = in.Attempts
+	out.CreationDate = in.CreationDate
+	out.Creator = in.Creator
+	out.Dataset = in.Dataset
+	out.FailureReport = in.FailureReport
+	if in.FullLog != nil {
+		fullLog := *in.FullLog
+		out.FullLog = &fullLog
+	}
+	out.LogHash = in.LogHash
+	if in.Model != nil {
+		model := *in.Model
+		out.Model = &model
+	}
+	out.Permissions.Fill(in.Permissions)
+	if in.Predictions != nil {
+		predictions := *in.Predictions
+		out.Predictions = &predictions
+	}
+	out.Priority = in.Priority
+	out.StartDate = in.StartDate
+	out.Status = in.Status
+	out.Tag = in.Tag
+	out.Timeout = in.Timeout
+
+	algo, err := db.GetAlgo(in.AlgoKey)
+	if err != nil {
+		return fmt.Errorf("could not retrieve algo with key %s - %s", in.AlgoKey, err.Error())
+	}
+	out.Algo = &HashDressName{
+		Name:           algo.Name,
+		Hash:           in.AlgoKey,
+		StorageAddress: algo.StorageAddress}
 	return nil
 }
 
+// fillPrivateFields sets the log, the model and the predictions storage addresses, stored in
+// private data collections, if the requester is a member of the collections
+func (out *outputPredicttuple) fillPrivateFields(db LedgerDB) error {
+	predicttuple, err := db.GetPredicttuple(out.Key)
+	if err != nil {
+		return err
+	}
+	out.Log, err = db.GetPrivateField(tupleLogsCollection, out.Key, predicttuple.LogHash)
+	if err != nil {
+		return err
+	}
+	if err = fillFullLog(db, out.Key, out.FullLog); err != nil {
+		return err
+	}
+	if out.Model != nil && out.Model.Hash != "" {
+		out.Model.StorageAddress, err = getModelStorageAddress(db, out.Model.TraintupleKey)
+		if err != nil {
+			return err
+		}
+	}
+	if out.Predictions != nil {
+		out.Predictions.StorageAddress, err = db.GetPrivateField(predictionAddressesCollection, out.Key, out.Predictions.StorageAddressHash)
+	}
+	return err
+}
+
+// redact blanks the algo, model and predictions storage addresses of a predicttuple the requester cannot process
+func (out *outputPredicttuple) redact() {
+	if out.Algo != nil {
+		out.Algo.StorageAddress = ""
+	}
+	if out.Model != nil {
+		out.Model = &Model{TraintupleKey: out.Model.TraintupleKey, Hash: out.Model.Hash}
+	}
+	if out.Predictions != nil {
+		predictions := *out.Predictions
+		predictions.StorageAddress = ""
+		out.Predictions = &predictions
+	}
+	out.Redacted = true
+}
+
+// outputModelDetails is a traintuple with its testtuples. Testtuples lists the certified testtuples,
+// one per test dataset of the objective, Testtuple being the one on its first test dataset.
 type outputModelDetails struct {
 	Traintuple             outputTraintuple  `json:"traintuple"`
 	Testtuple              outputTesttuple   `json:"testtuple"`
+	Testtuples             []outputTesttuple `json:"testtuples"`
 	NonCertifiedTesttuples []outputTesttuple `json:"nonCertifiedTesttuples"`
 }
 
+// outputModel is a traintuple with its certified testtuples, like in outputModelDetails
 type outputModel struct {
-	Traintuple outputTraintuple `json:"traintuple"`
-	Testtuple  outputTesttuple  `json:"testtuple"`
+	Traintuple outputTraintuple  `json:"traintuple"`
+	Testtuple  outputTesttuple   `json:"testtuple"`
+	Testtuples []outputTesttuple `json:"testtuples"`
 }
 
 // TuplesEvent is the collection of tuples sent in an event
 type TuplesEvent struct {
-	Testtuples  []outputTesttuple  `json:"testtuple"`
-	Traintuples []outputTraintuple `json:"traintuple"`
+	Testtuples    []outputTesttuple    `json:"testtuple"`
+	Traintuples   []outputTraintuple   `json:"traintuple"`
+	Predicttuples []outputPredicttuple `json:"predicttuple"`
 }
 
 // SetTesttuples add one or several testtuples to the event struct
@@ -277,6 +619,31 @@ func (te *TuplesEvent) SetTraintuples(otuples ...outputTraintuple) {
 	te.Traintuples = otuples
 }
 
+// SetPredicttuples add one or several predicttuples to the event struct
+func (te *TuplesEvent) SetPredicttuples(otuples ...outputPredicttuple) {
+	te.Predicttuples = otuples
+}
+
+// outputReclaimedTuples lists the tuples moved back to todo or to failed by a reclaim
+type outputReclaimedTuples struct {
+	Testtuples    []outputTesttuple    `json:"testtuples"`
+	Traintuples   []outputTraintuple   `json:"traintuples"`
+	Predicttuples []outputPredicttuple `json:"predicttuples"`
+}
+
+// outputQueuedTuple is a todo tuple in the queue of a worker, either a traintuple, a testtuple
+// or a predicttuple
+type outputQueuedTuple struct {
+	AssetType    string              `json:"assetType"`
+	Key          string              `json:"key"`
+	Priority     int                 `json:"priority"`
+	Rank         int                 `json:"rank"`
+	CreationDate string              `json:"creationDate"`
+	Traintuple   *outputTraintuple   `json:"traintuple"`
+	Testtuple    *outputTesttuple    `json:"testtuple"`
+	Predicttuple *outputPredicttuple `json:"predicttuple"`
+}
+
 type outputComputePlan struct {
 	ComputePlanID  string   `json:"computePlanID"`
 	TraintupleKeys []string `json:"traintupleKeys"`
@@ -310,7 +677,12 @@ func (out outputBoardTuples) Swap(i, j int) {
 	out[i], out[j] = out[j], out[i]
 }
 
+// Less ranks the entries on their private perf once the private leaderboard is revealed, the public
+// perf breaking ties
 func (out outputBoardTuples) Less(i, j int) bool {
+	if out[i].PrivatePerf != out[j].PrivatePerf {
+		return out[i].PrivatePerf < out[j].PrivatePerf
+	}
 	return out[i].Perf < out[j].Perf
 }
 
@@ -319,8 +691,37 @@ type outputBoardTuple struct {
 	Creator string         `json:"creator"`
 	Key     string         `json:"key"`
 	Model   *Model         `json:"model"`
+	Models  []*Model       `json:"models"`
 	Perf    float32        `json:"perf"`
-	Tag     string         `json:"tag"`
+	// PrivatePerf is the perf on the private partitions, zero until the private leaderboard is revealed
+	PrivatePerf float32 `json:"privatePerf"`
+	Tag         string  `json:"tag"`
+	// Datasets is the breakdown of the perf per test dataset, Perf being their mean weighted by sample count
+	Datasets []outputBoardDataset `json:"datasets"`
+}
+
+// outputBoardDataset is the perf of a leaderboard entry on one of the test datasets of the objective,
+// NbDataSamples being the number of its public samples
+type outputBoardDataset struct {
+	DataManagerKey       string  `json:"dataManagerKey"`
+	NbDataSamples        int     `json:"nbDataSamples"`
+	NbPrivateDataSamples int     `json:"nbPrivateDataSamples"`
+	Perf                 float32 `json:"perf"`
+	PrivatePerf          float32 `json:"privatePerf"`
+	TesttupleKey         string  `json:"testtupleKey"`
+	Worker               string  `json:"worker"`
+}
+
+func (out *outputBoardDataset) Fill(in Testtuple, testtupleKey string, revealed bool) {
+	out.DataManagerKey = in.Dataset.OpenerHash
+	out.NbPrivateDataSamples = len(in.Dataset.PrivateDataSampleKeys)
+	out.NbDataSamples = len(in.Dataset.DataSampleKeys) - out.NbPrivateDataSamples
+	out.Perf = in.Dataset.Perf
+	if revealed {
+		out.PrivatePerf = in.Dataset.PrivatePerf
+	}
+	out.TesttupleKey = testtupleKey
+	out.Worker = in.Dataset.Worker
 }
 
 func (out *outputBoardTuple) Fill(db LedgerDB, in Testtuple, testtupleKey string) error {
@@ -335,8 +736,49 @@ func (out *outputBoardTuple) Fill(db LedgerDB, in Testtuple, testtupleKey string
 		Hash:           in.AlgoKey,
 		StorageAddress: algo.StorageAddress,
 	}
-	out.Model = in.Model
+	// the members of an ensemble are listed with the ensemble
+	out.Models = []*Model{}
+	for _, inModel := range in.getModels() {
+		model := *inModel
+		model.StorageAddress, err = getModelStorageAddress(db, model.TraintupleKey)
+		if err != nil {
+			return err
+		}
+		out.Models = append(out.Models, &model)
+	}
+	if len(out.Models) > 0 {
+		out.Model = out.Models[0]
+	}
 	out.Perf = in.Dataset.Perf
 	out.Tag = in.Tag
 	return nil
 }
+
+type outputDryRun struct {
+	Valid  bool                     `json:"valid"`
+	Result interface{}              `json:"result"`
+	Assets EventAssets              `json:"assets"`
+	Errors []map[string]interface{} `json:"errors"`
+}
+
+type outputVersion struct {
+	ChaincodeVersion    string `json:"chaincodeVersion"`
+	SchemaVersion       int    `json:"schemaVersion"`
+	LatestSchemaVersion int    `json:"latestSchemaVersion"`
+	// MigrationCursor is where the pending migration has stopped, if any
+	MigrationCursor string `json:"migrationCursor"`
+}
+
+func (out *outputVersion) Fill(schema SchemaVersion) {
+	out.ChaincodeVersion = chaincodeVersion
+	out.SchemaVersion = schema.Version
+	out.LatestSchemaVersion = len(migrations)
+	out.MigrationCursor = schema.Cursor
+}
+
+// outputEndorsementPolicy lists the organisations which must endorse the updates of an asset,
+// none if only the endorsement policy of the chaincode applies
+type outputEndorsementPolicy struct {
+	Key  string   `json:"key"`
+	Orgs []string `json:"orgs"`
+}
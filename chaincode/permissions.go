@@ -86,7 +86,7 @@ func NewPermissions(db LedgerDB, in inputPermissions) (Permissions, error) {
 		}
 	}
 
-	owner, err := GetTxCreator(db.cc)
+	owner, err := GetTxCreator(db)
 	if err != nil {
 		return Permissions{}, err
 	}
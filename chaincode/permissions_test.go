@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,3 +120,58 @@ func TestMergingMechanism(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryRedaction(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "trainDataset")
+
+	// Register an algo only its owner can process
+	inpAlgo := inputAlgo{}
+	inpAlgo.createDefault()
+	inpAlgo.Permissions = inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}}
+	resp := mockStub.MockInvoke("42", methodAndAssetToByte("registerAlgo", inpAlgo))
+	assert.EqualValuesf(t, 200, resp.Status, "when adding private algo with status %d and message %s", resp.Status, resp.Message)
+	inpTraintuple := inputTraintuple{}
+	resp = mockStub.MockInvoke("42", inpTraintuple.createDefault())
+	assert.EqualValuesf(t, 200, resp.Status, "when adding traintuple with status %d and message %s", resp.Status, resp.Message)
+
+	testTable := []struct {
+		name             string
+		requester        string
+		expectedRedacted bool
+	}{
+		{"Owner sees storage addresses", worker, false},
+		{"Other node gets redacted assets", "OtherOrg", true},
+	}
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			mockStub.Creator = test.requester
+
+			resp := mockStub.MockInvoke("42", methodToByte("queryAlgos"))
+			assert.EqualValues(t, 200, resp.Status, resp.Message)
+			var algos []outputAlgo
+			assert.NoError(t, json.Unmarshal(resp.Payload, &algos))
+			assert.Len(t, algos, 1)
+			assert.Equal(t, test.expectedRedacted, algos[0].Redacted)
+			assert.Equal(t, test.expectedRedacted, algos[0].Content.StorageAddress == "")
+			assert.Equal(t, algoHash, algos[0].Content.Hash)
+
+			resp = mockStub.MockInvoke("42", methodToByte("queryDataManagers"))
+			assert.EqualValues(t, 200, resp.Status, resp.Message)
+			var dataManagers []outputDataManager
+			assert.NoError(t, json.Unmarshal(resp.Payload, &dataManagers))
+			assert.Len(t, dataManagers, 1)
+			assert.False(t, dataManagers[0].Redacted, "public dataManager should never be redacted")
+			assert.NotEmpty(t, dataManagers[0].Opener.StorageAddress)
+
+			resp = mockStub.MockInvoke("42", methodToByte("queryTraintuples"))
+			assert.EqualValues(t, 200, resp.Status, resp.Message)
+			var traintuples []outputTraintuple
+			assert.NoError(t, json.Unmarshal(resp.Payload, &traintuples))
+			assert.Len(t, traintuples, 1)
+			assert.Equal(t, test.expectedRedacted, traintuples[0].Redacted)
+			assert.Equal(t, test.expectedRedacted, traintuples[0].Algo.StorageAddress == "")
+		})
+	}
+}
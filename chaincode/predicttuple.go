@@ -0,0 +1,402 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"sort"
+)
+
+// -------------------------------------------------------------------------------------------
+// Methods on receivers predicttuple
+// -------------------------------------------------------------------------------------------
+
+// SetFromInput is a method of the receiver Predicttuple. It checks and sets the predicttuple's
+// parameters from the inputPredicttuple and the traintuple whose model it runs, or the registered
+// model. Its permissions are merged from the ones of the model and of the dataManager.
+func (predicttuple *Predicttuple) SetFromInput(db LedgerDB, inp inputPredicttuple) error {
+	creator, err := GetTxCreator(db)
+	if err != nil {
+		return err
+	}
+	predicttuple.AssetType = PredicttupleType
+	predicttuple.Creator = creator
+	predicttuple.Tag = inp.Tag
+	predicttuple.Priority = inp.Priority
+	if predicttuple.CreationDate, err = getTxTimestamp(db); err != nil {
+		return err
+	}
+
+	// check the traintuple, or the registered model
+	traintuple, err := getParentModel(db, inp.TraintupleKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve traintuple or model with key %s", inp.TraintupleKey)
+	}
+	if err = traintuple.checkProcess(db, inp.TraintupleKey, creator); err != nil {
+		return err
+	}
+	predicttuple.AlgoKey = traintuple.AlgoKey
+	predicttuple.Timeout = traintuple.Timeout
+	predicttuple.Model = &Model{TraintupleKey: inp.TraintupleKey}
+	switch traintuple.Status {
+	case StatusDone:
+		predicttuple.Model.Hash = traintuple.Hash
+		predicttuple.Status = StatusTodo
+	case StatusFailed:
+		return errors.BadRequest("could not register this predicttuple, the traintuple %s has a failed status", inp.TraintupleKey)
+	default:
+		predicttuple.Status = StatusWaiting
+	}
+
+	// check the dataset
+	if _, _, err = checkSameDataManager(db, inp.DataManagerKey, inp.DataSampleKeys); err != nil {
+		return err
+	}
+	dataManager, err := db.GetDataManager(inp.DataManagerKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
+	}
+	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedDataManager, "not authorized to process dataManager %s", inp.DataManagerKey))
+		if err != nil {
+			return err
+		}
+	}
+	dataSampleKeys := append([]string{}, inp.DataSampleKeys...)
+	sort.Strings(dataSampleKeys)
+	predicttuple.Dataset = &TtDataset{
+		Worker:         dataManager.Owner,
+		DataSampleKeys: dataSampleKeys,
+		OpenerHash:     inp.DataManagerKey,
+	}
+	predicttuple.Permissions = MergePermissions(traintuple.Permissions, dataManager.Permissions)
+	return nil
+}
+
+// GetKey returns the key of the predicttuple depending on its key parameters
+func (predicttuple *Predicttuple) GetKey() string {
+	hashKeys := []string{
+		predicttuple.Model.TraintupleKey,
+		predicttuple.Dataset.OpenerHash,
+		predicttuple.Creator,
+	}
+	hashKeys = append(hashKeys, predicttuple.Dataset.DataSampleKeys...)
+	return HashForKey("predicttuple", hashKeys...)
+}
+
+// Save will put in the legder interface both the predicttuple with its key
+// and all the associated composite keys
+func (predicttuple *Predicttuple) Save(db LedgerDB, predicttupleKey string) error {
+	if err := db.Add(predicttupleKey, predicttuple); err != nil {
+		return err
+	}
+	if err := db.CreateIndex("predicttuple~worker~status~key", []string{"predicttuple", predicttuple.Dataset.Worker, predicttuple.Status, predicttupleKey}); err != nil {
+		return err
+	}
+	if err := db.CreateIndex("predicttuple~traintuple~key", []string{"predicttuple", predicttuple.Model.TraintupleKey, predicttupleKey}); err != nil {
+		return err
+	}
+	if predicttuple.Tag != "" {
+		if err := db.CreateIndex("predicttuple~tag~key", []string{"predicttuple", predicttuple.Tag, predicttupleKey}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNewStatus verifies that the new status is consistent with the predicttuple current status
+func (predicttuple *Predicttuple) validateNewStatus(db LedgerDB, status string) error {
+	return checkUpdateTuple(db, predicttuple.Dataset.Worker, predicttuple.Status, status)
+}
+
+// commitStatusUpdate update the predicttuple status in the ledger
+func (predicttuple *Predicttuple) commitStatusUpdate(db LedgerDB, predicttupleKey string, newStatus string) error {
+	if err := predicttuple.validateNewStatus(db, newStatus); err != nil {
+		return errors.BadRequest(err, "update predicttuple %s failed:", predicttupleKey)
+	}
+	return predicttuple.saveStatus(db, predicttupleKey, newStatus)
+}
+
+// saveStatus stores the new status of a predicttuple and updates its index, the status
+// transition being already checked
+func (predicttuple *Predicttuple) saveStatus(db LedgerDB, predicttupleKey string, newStatus string) error {
+	oldStatus := predicttuple.Status
+	predicttuple.Status = newStatus
+	if err := db.Put(predicttupleKey, predicttuple); err != nil {
+		return fmt.Errorf("failed to update predicttuple status to %s with key %s", newStatus, predicttupleKey)
+	}
+
+	// update associated composite key
+	indexName := "predicttuple~worker~status~key"
+	oldAttributes := []string{"predicttuple", predicttuple.Dataset.Worker, oldStatus, predicttupleKey}
+	newAttributes := []string{"predicttuple", predicttuple.Dataset.Worker, predicttuple.Status, predicttupleKey}
+	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
+		return err
+	}
+	logger.Infof("predicttuple %s status updated: %s (from=%s)", predicttupleKey, newStatus, oldStatus)
+	return nil
+}
+
+// updatePredicttupleChildren updates the status of the predicttuples waiting for the model of
+// a traintuple once it has been trained (succesfully or failed)
+func (traintuple *Traintuple) updatePredicttupleChildren(db LedgerDB, traintupleKey string) ([]outputPredicttuple, error) {
+	otuples := []outputPredicttuple{}
+
+	var newStatus string
+	if traintuple.Status == StatusFailed {
+		newStatus = StatusFailed
+	} else if traintuple.Status == StatusDone {
+		newStatus = StatusTodo
+	} else {
+		return otuples, nil
+	}
+
+	predicttupleKeys, err := db.GetIndexKeys("predicttuple~traintuple~key", []string{"predicttuple", traintupleKey})
+	if err != nil {
+		return otuples, err
+	}
+	for _, predicttupleKey := range predicttupleKeys {
+		predicttuple, err := db.GetPredicttuple(predicttupleKey)
+		if err != nil {
+			return otuples, err
+		}
+		if predicttuple.Status != StatusWaiting {
+			continue
+		}
+		if newStatus == StatusTodo {
+			predicttuple.Model.Hash = traintuple.OutModel.Hash
+		}
+		if err := predicttuple.commitStatusUpdate(db, predicttupleKey, newStatus); err != nil {
+			return otuples, err
+		}
+		if newStatus == StatusTodo {
+			out := outputPredicttuple{}
+			if err = out.Fill(db, predicttupleKey, predicttuple); err != nil {
+				return otuples, err
+			}
+			otuples = append(otuples, out)
+		}
+	}
+	return otuples, nil
+}
+
+// getOutputPredicttuple returns the outputPredicttuple of a key, with its private fields if
+// the requester is a member of their collections
+func getOutputPredicttuple(db LedgerDB, predicttupleKey string) (out outputPredicttuple, err error) {
+	predicttuple, err := db.GetPredicttuple(predicttupleKey)
+	if err != nil {
+		return
+	}
+	if err = out.Fill(db, predicttupleKey, predicttuple); err != nil {
+		return
+	}
+	err = out.fillPrivateFields(db)
+	return
+}
+
+// getOutputPredicttupleFor returns the outputPredicttuple of a key, redacted if the requester
+// is not allowed to process the predicttuple
+func getOutputPredicttupleFor(db LedgerDB, predicttupleKey string, requester string) (out outputPredicttuple, err error) {
+	predicttuple, err := db.GetPredicttuple(predicttupleKey)
+	if err != nil {
+		return
+	}
+	if err = out.Fill(db, predicttupleKey, predicttuple); err != nil {
+		return
+	}
+	if err = out.fillPrivateFields(db); err != nil {
+		return
+	}
+	if !predicttuple.Permissions.CanProcess(predicttuple.Creator, requester) {
+		out.redact()
+	}
+	return
+}
+
+// getOutputPredicttuples returns the outputPredicttuples of a list of keys
+func getOutputPredicttuples(db LedgerDB, predicttupleKeys []string) (outPredicttuples []outputPredicttuple, err error) {
+	for _, key := range predicttupleKeys {
+		var out outputPredicttuple
+		if out, err = getOutputPredicttuple(db, key); err != nil {
+			return
+		}
+		outPredicttuples = append(outPredicttuples, out)
+	}
+	return
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to predicttuples
+// -------------------------------------------------------------------------------------------
+
+// createPredicttuple adds a Predicttuple in the ledger
+func createPredicttuple(db LedgerDB, args []string) (map[string]string, error) {
+	inp := inputPredicttuple{}
+	err := AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return nil, err
+	}
+
+	predicttuple := Predicttuple{}
+	if err = predicttuple.SetFromInput(db, inp); err != nil {
+		return nil, err
+	}
+	predicttupleKey := predicttuple.GetKey()
+	if err = predicttuple.Save(db, predicttupleKey); err != nil {
+		return nil, err
+	}
+	out := outputPredicttuple{}
+	if err = out.Fill(db, predicttupleKey, predicttuple); err != nil {
+		return nil, err
+	}
+
+	event := TuplesEvent{}
+	if predicttuple.Status == StatusTodo {
+		event.SetPredicttuples(out)
+	}
+	db.AddTuplesEvent(event)
+
+	return map[string]string{"key": predicttupleKey}, nil
+}
+
+// logStartPredict modifies a predicttuple by changing its status from todo to doing
+func logStartPredict(db LedgerDB, args []string) (out outputPredicttuple, err error) {
+	inp := inputHash{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+
+	predicttuple, err := db.GetPredicttuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, predicttuple.Dataset.Worker); err != nil {
+		return
+	}
+	if predicttuple.StartDate, err = getTxTimestamp(db); err != nil {
+		return
+	}
+	predicttuple.Attempts++
+	if err = predicttuple.commitStatusUpdate(db, inp.Key, StatusDoing); err != nil {
+		return
+	}
+	err = out.Fill(db, inp.Key, predicttuple)
+	return
+}
+
+// logSuccessPredict modifies a predicttuple by changing its status from doing to done,
+// and reports the logs and the predictions
+func logSuccessPredict(db LedgerDB, args []string) (out outputPredicttuple, err error) {
+	inp := inputLogSuccessPredict{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+
+	predicttuple, err := db.GetPredicttuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, predicttuple.Dataset.Worker); err != nil {
+		return
+	}
+	storageAddressHash, err := db.PutPrivateField(predictionAddressesCollection, inp.Key, inp.Predictions.StorageAddress)
+	if err != nil {
+		return
+	}
+	predicttuple.Predictions = &HashDress{
+		Hash:               inp.Predictions.Hash,
+		StorageAddressHash: storageAddressHash,
+	}
+	if predicttuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, inp.Key, predicttuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if predicttuple.FullLog, err = putFullLog(db, inp.Key, inp.FullLog); err != nil {
+		return
+	}
+	if err = predicttuple.commitStatusUpdate(db, inp.Key, StatusDone); err != nil {
+		return
+	}
+	err = out.Fill(db, inp.Key, predicttuple)
+	return
+}
+
+// logFailPredict modifies a predicttuple by changing its status to failed and reports the logs
+func logFailPredict(db LedgerDB, args []string) (out outputPredicttuple, err error) {
+	inp := inputLogFailPredict{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+
+	predicttuple, err := db.GetPredicttuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, predicttuple.Dataset.Worker); err != nil {
+		return
+	}
+	if predicttuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, inp.Key, predicttuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if predicttuple.FullLog, err = putFullLog(db, inp.Key, inp.FullLog); err != nil {
+		return
+	}
+	predicttuple.FailureReport = newFailureReport(inp.FailureReport)
+	if err = predicttuple.commitStatusUpdate(db, inp.Key, StatusFailed); err != nil {
+		return
+	}
+	err = out.Fill(db, inp.Key, predicttuple)
+	return
+}
+
+// queryPredicttuple returns a predicttuple of the ledger given its key
+func queryPredicttuple(db LedgerDB, args []string) (out outputPredicttuple, err error) {
+	inp := inputHash{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	return getOutputPredicttuple(db, inp.Key)
+}
+
+// queryPredicttuples returns all predicttuples of the ledger
+// Storage addresses of predicttuples the requester cannot process are redacted
+func queryPredicttuples(db LedgerDB, args []string) ([]outputPredicttuple, error) {
+	outPredicttuples := []outputPredicttuple{}
+
+	if len(args) != 0 {
+		err := errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return outPredicttuples, err
+	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outPredicttuples, err
+	}
+	elementsKeys, err := db.GetIndexKeys("predicttuple~traintuple~key", []string{"predicttuple"})
+	if err != nil {
+		return outPredicttuples, err
+	}
+	for _, key := range elementsKeys {
+		out, err := getOutputPredicttupleFor(db, key, requester)
+		if err != nil {
+			return outPredicttuples, err
+		}
+		outPredicttuples = append(outPredicttuples, out)
+	}
+	return outPredicttuples, nil
+}
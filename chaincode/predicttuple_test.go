@@ -0,0 +1,149 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicttuple(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	queryPredicttuple := func(key string) outputPredicttuple {
+		status, message, payload := invoke("queryPredicttuple", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		out := outputPredicttuple{}
+		require.NoError(t, json.Unmarshal(payload, &out))
+		return out
+	}
+
+	// A private data manager, only processable by its owner
+	privateDataManagerKey := "38a320b2a67c8003cc748d6666534f2b01f3f08d175440537a5bf86b7d08d5ee"
+	inpDataManager := inputDataManager{
+		Name:                      "private liver slide",
+		OpenerHash:                privateDataManagerKey,
+		OpenerStorageAddress:      "https://toto/dataManager/42235/opener",
+		Type:                      "images",
+		DescriptionHash:           "8d4bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482eee",
+		DescriptionStorageAddress: "https://toto/dataManager/42235/description",
+		Permissions: inputPermissions{
+			Process: inputPermission{Public: false, AuthorizedIDs: []string{worker}},
+		},
+	}
+	status, message, _ := invoke("registerDataManager", inpDataManager)
+	require.EqualValues(t, 200, status, message)
+	status, message, _ = invoke("updateDataSample", inputUpdateDataSample{
+		DataManagerKeys: []string{privateDataManagerKey},
+		Hashes:          []string{trainDataSampleHash1},
+	})
+	require.EqualValues(t, 200, status, message)
+
+	// The predicttuple waits for the model of the traintuple
+	inp := inputPredicttuple{
+		TraintupleKey:  traintupleKey,
+		DataManagerKey: privateDataManagerKey,
+		DataSampleKeys: []string{trainDataSampleHash1},
+	}
+	mockStub.Creator = "OtherOrg"
+	status, message, _ = invoke("createPredicttuple", inp)
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+	status, message, payload := invoke("createPredicttuple", inp)
+	require.EqualValues(t, 200, status, message)
+	res := map[string]string{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	predicttupleKey := res["key"]
+	status, message, _ = invoke("createPredicttuple", inp)
+	assert.EqualValues(t, 409, status, message)
+
+	out := queryPredicttuple(predicttupleKey)
+	assert.Equal(t, StatusWaiting, out.Status)
+	assert.Equal(t, worker, out.Dataset.Worker)
+	assert.Equal(t, algoHash, out.Algo.Hash)
+	assert.Equal(t, Permission{Public: false, AuthorizedIDs: []string{worker}}, out.Permissions.Process,
+		"the permissions are merged from the ones of the traintuple and of the data manager")
+
+	// The predicttuple is sent to its worker once the traintuple is done
+	status, message, _ = invoke("logStartTrain", inputHash{traintupleKey})
+	require.EqualValues(t, 200, status, message)
+	success := inputLogSuccessTrain{}
+	success.createDefault()
+	status, message, _ = invoke("logSuccessTrain", success)
+	require.EqualValues(t, 200, status, message)
+	event := getLastEvent(t, mockStub)
+	require.Len(t, event.Predicttuples, 1)
+	assert.Equal(t, predicttupleKey, event.Predicttuples[0].Key)
+	assert.Equal(t, StatusTodo, event.Predicttuples[0].Status)
+	assert.Equal(t, modelHash, event.Predicttuples[0].Model.Hash)
+
+	// The worker reports the predictions
+	status, message, _ = invoke("logStartPredict", inputHash{predicttupleKey})
+	require.EqualValues(t, 200, status, message)
+	successPredict := inputLogSuccessPredict{
+		Predictions: inputHashDress{Hash: fullLogHash, StorageAddress: "https://toto/predictions"},
+	}
+	successPredict.Key = predicttupleKey
+	successPredict.Log = "no error, ah ah ah"
+	status, message, _ = invoke("logSuccessPredict", successPredict)
+	require.EqualValues(t, 200, status, message)
+
+	out = queryPredicttuple(predicttupleKey)
+	assert.Equal(t, StatusDone, out.Status)
+	assert.Equal(t, 1, out.Attempts)
+	require.NotNil(t, out.Predictions)
+	assert.Equal(t, fullLogHash, out.Predictions.Hash)
+	assert.Equal(t, "https://toto/predictions", out.Predictions.StorageAddress)
+	assert.Equal(t, "no error, ah ah ah", out.Log)
+	assert.Equal(t, modelAddress, out.Model.StorageAddress)
+
+	// A predicttuple on a done traintuple is directly todo, and its worker can fail it
+	inp = inputPredicttuple{
+		TraintupleKey:  traintupleKey,
+		DataManagerKey: dataManagerOpenerHash,
+		DataSampleKeys: []string{trainDataSampleHash2},
+	}
+	status, message, payload = invoke("createPredicttuple", inp)
+	require.EqualValues(t, 200, status, message)
+	res = map[string]string{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	failedKey := res["key"]
+	assert.Equal(t, StatusTodo, queryPredicttuple(failedKey).Status)
+
+	status, message, _ = invoke("logStartPredict", inputHash{failedKey})
+	require.EqualValues(t, 200, status, message)
+	fail := inputLogFailPredict{FailureReport: &inputFailureReport{ErrorType: "model", Stage: "predict"}}
+	fail.Key = failedKey
+	status, message, _ = invoke("logFailPredict", fail)
+	require.EqualValues(t, 200, status, message)
+	out = queryPredicttuple(failedKey)
+	assert.Equal(t, StatusFailed, out.Status)
+	assert.Equal(t, &FailureReport{ErrorType: "model", Stage: "predict"}, out.FailureReport)
+
+	resp := mockStub.MockInvoke("42", methodToByte("queryPredicttuples"))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	outs := []outputPredicttuple{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &outs))
+	assert.Len(t, outs, 2)
+}
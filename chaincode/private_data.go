@@ -0,0 +1,369 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The private data collections declared in collections_config.json, storing the sensitive
+// fields of the assets. The public state only stores the hashes of these fields.
+const (
+	// tupleLogsCollection stores the logs of the tuples
+	tupleLogsCollection = "tupleLogs"
+	// modelAddressesCollection stores the storage addresses of the models, by traintuple
+	modelAddressesCollection = "modelAddresses"
+	// openerAddressesCollection stores the storage addresses of the dataManagers' openers
+	openerAddressesCollection = "openerAddresses"
+	// predictionAddressesCollection stores the storage addresses of the predictions, by predicttuple
+	predictionAddressesCollection = "predictionAddresses"
+	// privatePerfsCollection stores the salted perfs of the certified testtuples on the private test
+	// data samples, until the private leaderboard is revealed
+	privatePerfsCollection = "privatePerfs"
+)
+
+// collectionReadDenied is in the error of the peer when the requester reads a collection
+// declared with memberOnlyRead, which it is not a member of
+const collectionReadDenied = "does not have read access permission"
+
+// collectionMemberKey is the key read to check the read access to a collection
+const collectionMemberKey = "member"
+
+// privateInputTransientKey is the key of the transient map holding the json of the
+// sensitive fields of an input, the ones tagged transient
+const privateInputTransientKey = "private"
+
+// privateDataCache stores the private data read or written during the transaction by
+// collection, a deleted key being stored with a nil value, since Fabric does not read
+// the writes of a transaction
+type privateDataCache struct {
+	mutex  *sync.RWMutex
+	states map[string]map[string][]byte
+	// members stores whether the requester can read the collections
+	members map[string]bool
+}
+
+// newPrivateDataCache returns an empty cache
+func newPrivateDataCache() privateDataCache {
+	return privateDataCache{
+		mutex:   &sync.RWMutex{},
+		states:  make(map[string]map[string][]byte),
+		members: make(map[string]bool),
+	}
+}
+
+// getPrivateData returns the private data of a key, including the writes of the transaction
+func (c privateDataCache) getPrivateData(ctx TransactionContext, collection string, key string) ([]byte, error) {
+	c.mutex.RLock()
+	buff, ok := c.states[collection][key]
+	c.mutex.RUnlock()
+	if ok {
+		return buff, nil
+	}
+	buff, err := ctx.GetPrivateData(collection, key)
+	if err != nil {
+		return nil, err
+	}
+	c.put(collection, key, buff)
+	return buff, nil
+}
+
+// put stores the private data of a key during a transaction lifetime
+func (c privateDataCache) put(collection string, key string, buff []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.states[collection]; !ok {
+		c.states[collection] = make(map[string][]byte)
+	}
+	c.states[collection][key] = buff
+}
+
+// getMember returns whether the requester can read a collection, ok being false if it is unknown
+func (c privateDataCache) getMember(collection string) (member bool, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	member, ok = c.members[collection]
+	return
+}
+
+// putMember stores whether the requester can read a collection
+func (c privateDataCache) putMember(collection string, member bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.members[collection] = member
+}
+
+// privateCollections are the private data collections storing the assets of some types,
+// the other assets being stored in the public state of the ledger
+var privateCollections = map[AssetType]string{}
+
+// privateDataRef is stored in the public state in place of an asset stored in a private
+// data collection, so that its key is taken and it can be indexed
+type privateDataRef struct {
+	AssetType  AssetType `json:"assetType"`
+	Collection string    `json:"privateCollection"`
+}
+
+// privateDataStorage stores the assets of some types in private data collections and
+// the other ones in a public storage
+type privateDataStorage struct {
+	Storage
+	cc          TransactionContext
+	collections map[AssetType]string
+	cache       privateDataCache
+}
+
+// newPrivateDataStorage returns a storage routing the asset types of collections to
+// their private data collection
+func newPrivateDataStorage(ctx TransactionContext, public Storage, collections map[AssetType]string) *privateDataStorage {
+	return &privateDataStorage{
+		Storage:     public,
+		cc:          ctx,
+		collections: collections,
+		cache:       newPrivateDataCache(),
+	}
+}
+
+// getCollection returns the private data collection of an object, empty if it is public
+func (s *privateDataStorage) getCollection(object interface{}) (AssetType, string) {
+	assetType, _, ok := eventAssetInfo(object)
+	if !ok {
+		return assetType, ""
+	}
+	return assetType, s.collections[assetType]
+}
+
+// getRef returns the private data collection of a stored key, empty if it is public
+func (s *privateDataStorage) getRef(key string) (privateDataRef, error) {
+	ref := privateDataRef{}
+	err := s.Storage.Get(key, &ref)
+	return ref, err
+}
+
+// Get retrieves an object from its private data collection or from the public storage
+func (s *privateDataStorage) Get(key string, object interface{}) error {
+	ref, err := s.getRef(key)
+	if err != nil {
+		return err
+	}
+	if ref.Collection == "" {
+		return s.Storage.Get(key, object)
+	}
+	buff, err := s.cache.getPrivateData(s.cc, ref.Collection, key)
+	if err != nil || buff == nil {
+		return errors.NotFound(err, "%s %s not found in the private data collection %s", ref.AssetType, key, ref.Collection)
+	}
+	return json.Unmarshal(buff, &object)
+}
+
+// Put stores an object in the private data collection of its asset type, if any,
+// and its reference in the public storage. Otherwise it is stored in the public storage.
+func (s *privateDataStorage) Put(key string, object interface{}) error {
+	assetType, collection := s.getCollection(object)
+	if collection == "" {
+		return s.Storage.Put(key, object)
+	}
+	buff, _ := json.Marshal(object)
+	if err := s.cc.PutPrivateData(collection, key, buff); err != nil {
+		return err
+	}
+	s.cache.put(collection, key, buff)
+	return s.Storage.Put(key, privateDataRef{AssetType: assetType, Collection: collection})
+}
+
+// Add stores an object, it fails if the object already exists
+func (s *privateDataStorage) Add(key string, object interface{}) error {
+	return addObject(s, key, object)
+}
+
+// Delete removes an object and its private data if any
+func (s *privateDataStorage) Delete(key string) error {
+	ref, err := s.getRef(key)
+	if err != nil && !errors.Is(err, errors.NotFound()) {
+		return err
+	}
+	if ref.Collection != "" {
+		if err := s.cc.DelPrivateData(ref.Collection, key); err != nil {
+			return err
+		}
+		s.cache.put(ref.Collection, key, nil)
+	}
+	return s.Storage.Delete(key)
+}
+
+// UpdateIndex updates an existing composite key of the public storage
+func (s *privateDataStorage) UpdateIndex(index string, oldAttributes []string, newAttributes []string) error {
+	return updateIndex(s, index, oldAttributes, newAttributes)
+}
+
+// ----------------------------------------------
+// Sensitive fields of the assets
+// ----------------------------------------------
+
+// hashPrivateField returns the hash of a sensitive field, stored in the public state
+func hashPrivateField(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// PutPrivateField stores the sensitive field of an asset in a private data collection.
+// It returns the hash of the value, to store in the public state.
+func (db *ledgerDB) PutPrivateField(collection string, key string, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	buff := []byte(value)
+	if err := db.PutPrivateData(collection, key, buff); err != nil {
+		return "", errors.Internal(err, "cannot store %s in the private data collection %s:", key, collection)
+	}
+	db.private.put(collection, key, buff)
+	return hashPrivateField(value), nil
+}
+
+// readPrivateData returns the value of a key in a private data collection, including the writes
+// of the transaction
+func (db *ledgerDB) readPrivateData(collection string, key string) ([]byte, error) {
+	return db.private.getPrivateData(db, collection, key)
+}
+
+// AppendPrivateField appends a value to the sensitive field of an asset stored in a private
+// data collection, whose hash is given. It returns the hash of the new value.
+func (db *ledgerDB) AppendPrivateField(collection string, key string, hash string, value string) (string, error) {
+	if value == "" {
+		return hash, nil
+	}
+	previous := []byte{}
+	if hash != "" {
+		buff, err := db.readPrivateData(collection, key)
+		if err != nil {
+			return "", errors.Internal(err, "cannot read %s in the private data collection %s:", key, collection)
+		}
+		previous = buff
+	}
+	return db.PutPrivateField(collection, key, string(previous)+value)
+}
+
+// GetPrivateField returns the sensitive field of an asset stored in a private data collection.
+// It is empty if the requester is not a member of the collection, or if the value available
+// to the peer does not match the hash stored in the public state.
+func (db *ledgerDB) GetPrivateField(collection string, key string, hash string) (string, error) {
+	if hash == "" {
+		return "", nil
+	}
+	member, err := db.IsCollectionMember(collection)
+	if err != nil || !member {
+		return "", err
+	}
+	buff, err := db.readPrivateData(collection, key)
+	if err != nil {
+		return "", errors.Internal(err, "cannot read %s in the private data collection %s:", key, collection)
+	}
+	value := string(buff)
+	if hashPrivateField(value) != hash {
+		logger.Warningf("the private data of %s in %s does not match its hash", key, collection)
+		return "", nil
+	}
+	return value, nil
+}
+
+// IsCollectionMember checks if the requester belongs to a private data collection. The
+// collections are declared with memberOnlyRead, so that the peers deny the reads of the
+// organisations which are not in their policy.
+func (db *ledgerDB) IsCollectionMember(collection string) (bool, error) {
+	if member, ok := db.private.getMember(collection); ok {
+		return member, nil
+	}
+	_, err := db.GetPrivateData(collection, collectionMemberKey)
+	if err != nil && !strings.Contains(err.Error(), collectionReadDenied) {
+		return false, errors.Internal(err, "cannot read the private data collection %s:", collection)
+	}
+	member := err == nil
+	db.private.putMember(collection, member)
+	return member, nil
+}
+
+// transientInput sets the sensitive fields of an input, tagged transient, from the
+// transient map, so that they are not recorded in the transaction. It fails if they
+// are passed in the args.
+func transientInput(ctx TransactionContext, input interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(input))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	args := reflect.New(value.Type()).Elem()
+	if names := moveTransientFields(args, value, ""); len(names) > 0 {
+		return errors.BadRequest(errors.CodeInvalidInput, "%s must be passed in the transient map under the %s key", strings.Join(names, ", "), privateInputTransientKey)
+	}
+	transient, err := ctx.GetTransient()
+	if err != nil || len(transient[privateInputTransientKey]) == 0 {
+		return err
+	}
+	private := reflect.New(value.Type())
+	if err := json.Unmarshal(transient[privateInputTransientKey], private.Interface()); err != nil {
+		return errors.BadRequest(err, errors.CodeInvalidJSON, "problem when reading the transient %s json, error is:", privateInputTransientKey)
+	}
+	moveTransientFields(value, private.Elem(), "")
+	return nil
+}
+
+// moveTransientFields moves the fields tagged transient of a struct to another one of
+// the same type. It returns the json path of the moved fields which were set.
+func moveTransientFields(dst reflect.Value, src reflect.Value, path string) []string {
+	names := []string{}
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		name := path + strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if field.Tag.Get("transient") == "true" {
+			if !reflect.DeepEqual(src.Field(i).Interface(), reflect.Zero(field.Type).Interface()) {
+				names = append(names, name)
+			}
+			dst.Field(i).Set(src.Field(i))
+			src.Field(i).Set(reflect.Zero(field.Type))
+			continue
+		}
+		if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+			if src.Field(i).IsNil() {
+				continue
+			}
+			// Copy the struct so that the one of the input is not updated
+			srcElem := reflect.New(field.Type.Elem())
+			srcElem.Elem().Set(src.Field(i).Elem())
+			src.Field(i).Set(srcElem)
+			if dst.Field(i).IsNil() {
+				dst.Field(i).Set(reflect.New(field.Type.Elem()))
+			}
+			names = append(names, moveTransientFields(dst.Field(i).Elem(), srcElem.Elem(), name+".")...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			if !field.Anonymous {
+				name += "."
+			} else {
+				name = path
+			}
+			names = append(names, moveTransientFields(dst.Field(i), src.Field(i), name)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateFields(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.CollectionMembers = map[string][]string{tupleLogsCollection: {worker}, openerAddressesCollection: {worker}}
+
+	// The sensitive fields cannot be passed in the args
+	inpDataManager := inputDataManager{}
+	inpDataManager.createDefault()
+	payload, err := json.Marshal(inpDataManager)
+	require.NoError(t, err)
+	resp := mockStub.MockInvoke("42", [][]byte{[]byte("registerDataManager"), payload})
+	require.EqualValues(t, 400, resp.Status, resp.Message)
+	errStruct := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &errStruct))
+	assert.Equal(t, string(errors.CodeInvalidInput), errStruct["code"])
+	assert.Contains(t, errStruct["error"], "openerStorageAddress must be passed in the transient map")
+
+	// Only their hashes are stored in the public state
+	registerItem(t, *mockStub, "traintuple")
+	for _, args := range [][][]byte{
+		{[]byte("logStartTrain"), keyToJSON(traintupleKey)},
+		(&inputLogSuccessTrain{}).createDefault(),
+	} {
+		resp = mockStub.MockInvoke("42", args)
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+	}
+	publicState := ""
+	for _, key := range []string{dataManagerOpenerHash, traintupleKey} {
+		publicState += string(mockStub.State[key])
+	}
+	for _, sensitive := range []string{inpDataManager.OpenerStorageAddress, "no error, ah ah ah", modelAddress} {
+		assert.NotContains(t, publicState, sensitive)
+		assert.Contains(t, publicState, hashPrivateField(sensitive))
+	}
+
+	// The members of the collections can read them
+	dataManager := outputDataManager{}
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryDataManager"), keyToJSON(dataManagerOpenerHash)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &dataManager))
+	assert.Equal(t, inpDataManager.OpenerStorageAddress, dataManager.Opener.StorageAddress)
+	traintuple := outputTraintuple{}
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryTraintuple"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &traintuple))
+	assert.Equal(t, "no error, ah ah ah", traintuple.Log)
+	assert.Equal(t, modelAddress, traintuple.OutModel.StorageAddress)
+
+	// but not the other nodes, except for the collections they are members of
+	mockStub.Creator = "OtherOrg"
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryDataManager"), keyToJSON(dataManagerOpenerHash)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &dataManager))
+	assert.Empty(t, dataManager.Opener.StorageAddress)
+	assert.Equal(t, hashPrivateField(inpDataManager.OpenerStorageAddress), dataManager.Opener.StorageAddressHash)
+	traintuple = outputTraintuple{}
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryTraintuple"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	require.NoError(t, json.Unmarshal(resp.Payload, &traintuple))
+	assert.Empty(t, traintuple.Log)
+	assert.Equal(t, hashPrivateField("no error, ah ah ah"), traintuple.LogHash)
+	assert.Equal(t, modelAddress, traintuple.OutModel.StorageAddress)
+}
+
+func TestCollectionsConfig(t *testing.T) {
+	buff, err := ioutil.ReadFile("collections_config.json")
+	require.NoError(t, err)
+	collections := []struct {
+		Name           string `json:"name"`
+		MemberOnlyRead bool   `json:"memberOnlyRead"`
+	}{}
+	require.NoError(t, json.Unmarshal(buff, &collections))
+
+	// The peers check the read access to the collections, only granted to their members
+	names := []string{}
+	for _, collection := range collections {
+		names = append(names, collection.Name)
+		assert.True(t, collection.MemberOnlyRead, collection.Name)
+	}
+	assert.ElementsMatch(t, []string{tupleLogsCollection, modelAddressesCollection, openerAddressesCollection, predictionAddressesCollection, privatePerfsCollection}, names)
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+)
+
+// The worker of a running traintuple reports its progress with heartbeats. The last report
+// is stored in the traintuple, and every report is added to the history of the traintuple
+// under an increasing sequence number, like the outbox of the workers. A report also
+// refreshes the start date of the traintuple, from which its timeout runs.
+
+const progressIndex = "progressReport~traintuple~sequence~key"
+
+// addProgressReport stores a progress report in the history of a traintuple
+func addProgressReport(db LedgerDB, traintupleKey string, progress Progress) error {
+	report := ProgressReport{
+		Progress:      progress,
+		TraintupleKey: traintupleKey,
+	}
+	key := getProgressReportKey(traintupleKey, progress.Sequence)
+	if err := db.Add(key, report); err != nil {
+		return err
+	}
+	return db.CreateIndex(progressIndex, []string{"progressReport", traintupleKey, formatSequence(progress.Sequence), key})
+}
+
+// getProgressReportKey returns the key of a report stored in the history of a traintuple
+func getProgressReportKey(traintupleKey string, sequence int) string {
+	return HashForKey("progressReport", fmt.Sprintf("%s~%d", traintupleKey, sequence))
+}
+
+// nextProgressSequence returns the sequence number of the next report of a traintuple whose
+// last report has been cleared by a reclaim, following the ones of its history
+func nextProgressSequence(db LedgerDB, traintupleKey string) (int, error) {
+	keys, err := db.GetIndexKeys(progressIndex, []string{"progressReport", traintupleKey})
+	if err != nil {
+		return 0, err
+	}
+	return len(keys) + 1, nil
+}
+
+// getProgressReports returns the history of a traintuple, ordered by sequence number
+func getProgressReports(db LedgerDB, traintupleKey string) ([]ProgressReport, error) {
+	reports := []ProgressReport{}
+	keys, err := db.GetIndexKeys(progressIndex, []string{"progressReport", traintupleKey})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		report := ProgressReport{}
+		if err := db.Get(key, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the progress of the traintuples
+// -------------------------------------------------------------------------------------------
+
+// logProgress records a progress report of the worker of a running traintuple
+func logProgress(db LedgerDB, args []string) (outputTraintuple outputTraintuple, err error) {
+	inp := inputLogProgress{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+
+	traintuple, err := db.GetTraintuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+		return
+	}
+	if traintuple.Status != StatusDoing {
+		err = errors.BadRequest("cannot log the progress of traintuple %s - status %s", inp.Key, traintuple.Status)
+		return
+	}
+
+	timestamp, err := getTxTimestamp(db)
+	if err != nil {
+		return
+	}
+	progress := Progress{
+		Sequence:   1,
+		Step:       inp.Step,
+		Percentage: inp.Percentage,
+		Metrics:    inp.Metrics,
+		Timestamp:  timestamp,
+		TxID:       db.GetTxID(),
+	}
+	if progress.Metrics == nil {
+		progress.Metrics = map[string]float32{}
+	}
+	if traintuple.Progress != nil {
+		progress.Sequence = traintuple.Progress.Sequence + 1
+	} else if progress.Sequence, err = nextProgressSequence(db, inp.Key); err != nil {
+		return
+	}
+	traintuple.Progress = &progress
+	traintuple.StartDate = timestamp
+	if err = db.Put(inp.Key, traintuple); err != nil {
+		return
+	}
+	if err = addProgressReport(db, inp.Key, progress); err != nil {
+		return
+	}
+	err = outputTraintuple.Fill(db, traintuple, inp.Key)
+	return
+}
+
+// queryTraintupleProgress returns the progress reports of a traintuple, ordered by sequence number
+func queryTraintupleProgress(db LedgerDB, args []string) (reports []ProgressReport, err error) {
+	inp := inputHash{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	if _, err = db.GetTraintuple(inp.Key); err != nil {
+		return
+	}
+	return getProgressReports(db, inp.Key)
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogProgress(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	calls := 0
+	logProgress := func(inp inputLogProgress) (int32, string) {
+		payload, err := json.Marshal(inp)
+		require.NoError(t, err)
+		calls++
+		resp := mockStub.MockInvoke(fmt.Sprintf("progress%d", calls), [][]byte{[]byte("logProgress"), payload})
+		return resp.Status, resp.Message
+	}
+
+	// The progress can only be logged on a running traintuple
+	status, message := logProgress(inputLogProgress{Key: traintupleKey, Step: 1, Percentage: 10})
+	assert.EqualValues(t, 400, status, message)
+	resp := mockStub.MockInvoke("42", [][]byte{[]byte("logStartTrain"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// The percentage is validated
+	status, message = logProgress(inputLogProgress{Key: traintupleKey, Step: 1, Percentage: 110})
+	assert.EqualValues(t, 400, status, message)
+
+	// Only the worker can log the progress
+	mockStub.Creator = "OtherOrg"
+	status, message = logProgress(inputLogProgress{Key: traintupleKey, Step: 1, Percentage: 10})
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp}
+	status, message = logProgress(inputLogProgress{Key: traintupleKey, Step: 1, Percentage: 10})
+	require.EqualValues(t, 200, status, message)
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp + 60}
+	status, message = logProgress(inputLogProgress{
+		Key:        traintupleKey,
+		Step:       2,
+		Percentage: 20,
+		Metrics:    map[string]float32{"loss": 0.5},
+	})
+	require.EqualValues(t, 200, status, message)
+
+	// The traintuple returns the last report
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryTraintuple"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	traintuple := outputTraintuple{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &traintuple))
+	expected := &Progress{
+		Sequence:   2,
+		Step:       2,
+		Percentage: 20,
+		Metrics:    map[string]float32{"loss": 0.5},
+		Timestamp:  "2019-10-14T08:01:00Z",
+		TxID:       "progress5",
+	}
+	assert.Equal(t, expected, traintuple.Progress)
+
+	// The history returns all the reports in order
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryTraintupleProgress"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	reports := []ProgressReport{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &reports))
+	require.Len(t, reports, 2)
+	assert.Equal(t, ProgressReport{
+		Progress: Progress{
+			Sequence:   1,
+			Step:       1,
+			Percentage: 10,
+			Metrics:    map[string]float32{},
+			Timestamp:  "2019-10-14T08:00:00Z",
+			TxID:       "progress4",
+		},
+		TraintupleKey: traintupleKey,
+	}, reports[0])
+	assert.Equal(t, *expected, reports[1].Progress)
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"sort"
+)
+
+// The queue of a worker is made of its todo traintuples, testtuples and predicttuples, the ones with the
+// highest priority first, then the ones with the lowest compute plan rank, then the oldest ones.
+
+// getQueuedTraintuple returns a traintuple as a tuple of a queue
+func getQueuedTraintuple(db LedgerDB, key string) (outputQueuedTuple, error) {
+	out, err := getOutputTraintuple(db, key)
+	if err != nil {
+		return outputQueuedTuple{}, err
+	}
+	return outputQueuedTuple{
+		AssetType:    TraintupleType.String(),
+		Key:          key,
+		Priority:     out.Priority,
+		Rank:         out.Rank,
+		CreationDate: out.CreationDate,
+		Traintuple:   &out,
+	}, nil
+}
+
+// getQueuedTesttuple returns a testtuple as a tuple of a queue, with the rank of its traintuple
+func getQueuedTesttuple(db LedgerDB, key string) (outputQueuedTuple, error) {
+	out, err := getOutputTesttuple(db, key)
+	if err != nil {
+		return outputQueuedTuple{}, err
+	}
+	traintuple, err := getParentModel(db, out.Model.TraintupleKey)
+	if err != nil {
+		return outputQueuedTuple{}, err
+	}
+	return outputQueuedTuple{
+		AssetType:    TesttupleType.String(),
+		Key:          key,
+		Priority:     out.Priority,
+		Rank:         traintuple.Rank,
+		CreationDate: out.CreationDate,
+		Testtuple:    &out,
+	}, nil
+}
+
+// getQueuedPredicttuple returns a predicttuple as a tuple of a queue, with the rank of its traintuple
+func getQueuedPredicttuple(db LedgerDB, key string) (outputQueuedTuple, error) {
+	out, err := getOutputPredicttuple(db, key)
+	if err != nil {
+		return outputQueuedTuple{}, err
+	}
+	traintuple, err := getParentModel(db, out.Model.TraintupleKey)
+	if err != nil {
+		return outputQueuedTuple{}, err
+	}
+	return outputQueuedTuple{
+		AssetType:    PredicttupleType.String(),
+		Key:          key,
+		Priority:     out.Priority,
+		Rank:         traintuple.Rank,
+		CreationDate: out.CreationDate,
+		Predicttuple: &out,
+	}, nil
+}
+
+// getWorkerQueue returns the todo traintuples, testtuples and predicttuples of a worker, in the order they
+// should be processed
+func getWorkerQueue(db LedgerDB, worker string) ([]outputQueuedTuple, error) {
+	queue := []outputQueuedTuple{}
+	traintupleKeys, err := db.GetIndexKeys("traintuple~worker~status~key", []string{"traintuple", worker, StatusTodo})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range traintupleKeys {
+		tuple, err := getQueuedTraintuple(db, key)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, tuple)
+	}
+	testtupleKeys, err := db.GetIndexKeys("testtuple~worker~status~key", []string{"testtuple", worker, StatusTodo})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range testtupleKeys {
+		tuple, err := getQueuedTesttuple(db, key)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, tuple)
+	}
+	predicttupleKeys, err := db.GetIndexKeys("predicttuple~worker~status~key", []string{"predicttuple", worker, StatusTodo})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range predicttupleKeys {
+		tuple, err := getQueuedPredicttuple(db, key)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, tuple)
+	}
+	sortQueue(queue)
+	return queue, nil
+}
+
+// sortQueue orders the tuples of a queue by priority, then rank, then creation date.
+// The keys break the ties so that the order is deterministic.
+func sortQueue(queue []outputQueuedTuple) {
+	sort.SliceStable(queue, func(i, j int) bool {
+		a, b := queue[i], queue[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if a.CreationDate != b.CreationDate {
+			return a.CreationDate < b.CreationDate
+		}
+		return a.Key < b.Key
+	})
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the queue of the workers
+// -------------------------------------------------------------------------------------------
+
+// queryWorkerQueue returns the todo tuples of the requester, in the order they should be processed
+func queryWorkerQueue(db LedgerDB, args []string) (queue []outputQueuedTuple, err error) {
+	if len(args) != 0 {
+		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return
+	}
+	worker, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
+	return getWorkerQueue(db, worker)
+}
+
+// updateTuplePriority changes the priority of a traintuple, a testtuple or a predicttuple of the requester
+func updateTuplePriority(db LedgerDB, args []string) (resp outputQueuedTuple, err error) {
+	inp := inputUpdatePriority{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
+
+	if traintuple, err := db.GetTraintuple(inp.Key); err == nil {
+		if requester != traintuple.Creator {
+			return resp, errors.Forbidden(errors.CodePermissionDeniedTupleUpdate, "%s is not allowed to update the priority of traintuple %s", requester, inp.Key)
+		}
+		traintuple.Priority = inp.Priority
+		if err = db.Put(inp.Key, traintuple); err != nil {
+			return resp, err
+		}
+		return getQueuedTraintuple(db, inp.Key)
+	}
+
+	if testtuple, err := db.GetTesttuple(inp.Key); err == nil {
+		if requester != testtuple.Creator {
+			return resp, errors.Forbidden(errors.CodePermissionDeniedTupleUpdate, "%s is not allowed to update the priority of testtuple %s", requester, inp.Key)
+		}
+		testtuple.Priority = inp.Priority
+		if err = db.Put(inp.Key, testtuple); err != nil {
+			return resp, err
+		}
+		return getQueuedTesttuple(db, inp.Key)
+	}
+
+	predicttuple, err := db.GetPredicttuple(inp.Key)
+	if err != nil {
+		return resp, errors.NotFound("no traintuple, testtuple or predicttuple with key %s", inp.Key)
+	}
+	if requester != predicttuple.Creator {
+		return resp, errors.Forbidden(errors.CodePermissionDeniedTupleUpdate, "%s is not allowed to update the priority of predicttuple %s", requester, inp.Key)
+	}
+	predicttuple.Priority = inp.Priority
+	if err = db.Put(inp.Key, predicttuple); err != nil {
+		return
+	}
+	return getQueuedPredicttuple(db, inp.Key)
+}
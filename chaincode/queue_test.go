@@ -0,0 +1,107 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerQueue(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		args := [][]byte{[]byte(fn)}
+		if inp != nil {
+			payload, err := json.Marshal(inp)
+			require.NoError(t, err)
+			args = append(args, payload)
+		}
+		resp := mockStub.MockInvoke("42", args)
+		return resp.Status, resp.Message, resp.Payload
+	}
+	queueKeys := func() []string {
+		status, message, payload := invoke("queryWorkerQueue", nil)
+		require.EqualValues(t, 200, status, message)
+		queue := []outputQueuedTuple{}
+		require.NoError(t, json.Unmarshal(payload, &queue))
+		keys := []string{}
+		for _, tuple := range queue {
+			require.NotNil(t, tuple.Traintuple, "the tuples of the queue are resolved")
+			require.NotNil(t, tuple.Traintuple.Algo)
+			keys = append(keys, tuple.Key)
+		}
+		return keys
+	}
+
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp}
+	status, message, payload := invoke("createComputePlan", defaultComputePlan)
+	require.EqualValues(t, 200, status, message)
+	plan := outputComputePlan{}
+	require.NoError(t, json.Unmarshal(payload, &plan))
+	planKey := plan.TraintupleKeys[0]
+
+	mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp + 60}
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	status, message, payload = invoke("createTraintuple", inpTraintuple)
+	require.EqualValues(t, 200, status, message)
+	res := map[string]string{}
+	require.NoError(t, json.Unmarshal(payload, &res))
+	newerKey := res["key"]
+
+	// Only the todo tuples are in the queue, the oldest first with the same priority and rank
+	assert.Equal(t, []string{planKey, newerKey}, queueKeys())
+
+	// The creator can raise the priority of a tuple
+	mockStub.Creator = "OtherOrg"
+	status, message, _ = invoke("updateTuplePriority", inputUpdatePriority{Key: newerKey, Priority: 10})
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+	status, message, payload = invoke("updateTuplePriority", inputUpdatePriority{Key: newerKey, Priority: 10})
+	require.EqualValues(t, 200, status, message)
+	updated := outputQueuedTuple{}
+	require.NoError(t, json.Unmarshal(payload, &updated))
+	assert.Equal(t, 10, updated.Priority)
+	assert.Equal(t, []string{newerKey, planKey}, queueKeys())
+
+	// The priority of the testtuples can be updated too
+	status, message, _ = invoke("updateTuplePriority", inputUpdatePriority{Key: plan.TesttupleKeys[0], Priority: 1})
+	require.EqualValues(t, 200, status, message)
+	status, message, _ = invoke("updateTuplePriority", inputUpdatePriority{Key: objectiveDescriptionHash, Priority: 1})
+	assert.EqualValues(t, 404, status, message)
+}
+
+func TestSortQueue(t *testing.T) {
+	queue := []outputQueuedTuple{
+		{Key: "e", Priority: 0, Rank: 0, CreationDate: "2019-10-14T08:00:00Z"},
+		{Key: "d", Priority: 0, Rank: 0, CreationDate: "2019-10-14T08:00:00Z"},
+		{Key: "c", Priority: 0, Rank: 0, CreationDate: "2019-10-14T07:00:00Z"},
+		{Key: "b", Priority: 0, Rank: 1, CreationDate: "2019-10-14T06:00:00Z"},
+		{Key: "a", Priority: 1, Rank: 2, CreationDate: "2019-10-14T09:00:00Z"},
+	}
+	sortQueue(queue)
+	keys := []string{}
+	for _, tuple := range queue {
+		keys = append(keys, tuple.Key)
+	}
+	assert.Equal(t, []string{"a", "c", "d", "e", "b"}, keys)
+}
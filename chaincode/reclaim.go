@@ -0,0 +1,255 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"time"
+)
+
+// A tuple stays doing if its worker crashes after starting it. Once the timeout of the tuple
+// has passed, its creator can reclaim it: the tuple goes back to todo to be started again,
+// or fails when it has already been started the maximum number of times.
+
+// checkStaleTuple verifies that a tuple can be reclaimed by the requester. The timeout is
+// only advisory: it is checked against the timestamp of the transaction, which is set by the
+// client submitting it, so a creator can reclaim a tuple early by forging its timestamp.
+// The progress reports of a traintuple refresh its start date, so that a worker still
+// sending heartbeats keeps it.
+func checkStaleTuple(db LedgerDB, key string, creator string, status string, startDate string, timeout int) error {
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return err
+	}
+	if requester != creator {
+		return errors.Forbidden(errors.CodePermissionDeniedTupleUpdate, "%s is not allowed to reclaim tuple %s (%s)", requester, key, creator)
+	}
+	if status != StatusDoing {
+		return errors.BadRequest(errors.CodeTupleInvalidStatusTransition, "cannot reclaim tuple %s - status %s", key, status)
+	}
+	if timeout == 0 || startDate == "" {
+		return errors.BadRequest("cannot reclaim tuple %s - it has no timeout", key)
+	}
+	start, err := time.Parse(time.RFC3339, startDate)
+	if err != nil {
+		return errors.Internal(err, "invalid start date of tuple %s:", key)
+	}
+	now, err := getTxTime(db)
+	if err != nil {
+		return err
+	}
+	deadline := start.Add(time.Duration(timeout) * time.Second)
+	if !now.After(deadline) {
+		return errors.BadRequest("cannot reclaim tuple %s before its timeout at %s", key, deadline.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// reclaimedStatus returns the status of a reclaimed tuple depending on its number of attempts
+func reclaimedStatus(db LedgerDB, attempts int) (string, error) {
+	config, err := db.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	if config.MaxAttempts > 0 && attempts >= config.MaxAttempts {
+		return StatusFailed, nil
+	}
+	return StatusTodo, nil
+}
+
+// timeoutFailureReport is the failure report of the tuples failed by a reclaim, the stage
+// at which the worker stopped being unknown
+func timeoutFailureReport() *FailureReport {
+	return &FailureReport{ErrorType: "timeout"}
+}
+
+// reclaim updates the status of a stale traintuple. Going back from doing to todo is only
+// allowed here, once the timeout of the traintuple has been checked.
+func (traintuple *Traintuple) reclaim(db LedgerDB, key string, status string) error {
+	if status == StatusTodo {
+		// the progress of the previous attempt is kept in the history only
+		traintuple.Progress = nil
+		return traintuple.saveStatus(db, key, status)
+	}
+	return traintuple.commitStatusUpdate(db, key, status)
+}
+
+// reclaim updates the status of a stale testtuple, see the traintuple one
+func (testtuple *Testtuple) reclaim(db LedgerDB, key string, status string) error {
+	if status == StatusTodo {
+		return testtuple.saveStatus(db, key, status)
+	}
+	return testtuple.commitStatusUpdate(db, key, status)
+}
+
+// reclaim updates the status of a stale predicttuple, see the traintuple one
+func (predicttuple *Predicttuple) reclaim(db LedgerDB, key string, status string) error {
+	if status == StatusTodo {
+		return predicttuple.saveStatus(db, key, status)
+	}
+	return predicttuple.commitStatusUpdate(db, key, status)
+}
+
+// reclaimTraintuple moves a stale traintuple back to todo or to failed
+func reclaimTraintuple(db LedgerDB, key string) (out outputTraintuple, err error) {
+	traintuple, err := db.GetTraintuple(key)
+	if err != nil {
+		return
+	}
+	if err = checkStaleTuple(db, key, traintuple.Creator, traintuple.Status, traintuple.StartDate, traintuple.Timeout); err != nil {
+		return
+	}
+	status, err := reclaimedStatus(db, traintuple.Attempts)
+	if err != nil {
+		return
+	}
+	if status == StatusFailed {
+		traintuple.FailureReport = timeoutFailureReport()
+	}
+	if err = traintuple.reclaim(db, key, status); err != nil {
+		return
+	}
+	if err = out.Fill(db, traintuple, key); err != nil {
+		return
+	}
+
+	event := TuplesEvent{}
+	if status == StatusTodo {
+		// the worker has to start the traintuple again
+		event.SetTraintuples(out)
+		db.AddTuplesEvent(event)
+		return
+	}
+	testtuplesEvent, err := traintuple.updateTesttupleChildren(db, key)
+	if err != nil {
+		return
+	}
+	traintuplesEvent, err := traintuple.updateTraintupleChildren(db, key)
+	if err != nil {
+		return
+	}
+	if _, err = traintuple.updatePredicttupleChildren(db, key); err != nil {
+		return
+	}
+	event.SetTraintuples(traintuplesEvent...)
+	event.SetTesttuples(testtuplesEvent...)
+	db.AddTuplesEvent(event)
+	return
+}
+
+// reclaimTesttuple moves a stale testtuple back to todo or to failed
+func reclaimTesttuple(db LedgerDB, key string) (out outputTesttuple, err error) {
+	testtuple, err := db.GetTesttuple(key)
+	if err != nil {
+		return
+	}
+	if err = checkStaleTuple(db, key, testtuple.Creator, testtuple.Status, testtuple.StartDate, testtuple.Timeout); err != nil {
+		return
+	}
+	status, err := reclaimedStatus(db, testtuple.Attempts)
+	if err != nil {
+		return
+	}
+	if status == StatusFailed {
+		testtuple.FailureReport = timeoutFailureReport()
+	}
+	if err = testtuple.reclaim(db, key, status); err != nil {
+		return
+	}
+	if err = out.Fill(db, key, testtuple); err != nil {
+		return
+	}
+	if status == StatusTodo {
+		// the worker has to start the testtuple again
+		event := TuplesEvent{}
+		event.SetTesttuples(out)
+		db.AddTuplesEvent(event)
+	}
+	return
+}
+
+// reclaimPredicttuple moves a stale predicttuple back to todo or to failed
+func reclaimPredicttuple(db LedgerDB, key string) (out outputPredicttuple, err error) {
+	predicttuple, err := db.GetPredicttuple(key)
+	if err != nil {
+		return
+	}
+	if err = checkStaleTuple(db, key, predicttuple.Creator, predicttuple.Status, predicttuple.StartDate, predicttuple.Timeout); err != nil {
+		return
+	}
+	status, err := reclaimedStatus(db, predicttuple.Attempts)
+	if err != nil {
+		return
+	}
+	if status == StatusFailed {
+		predicttuple.FailureReport = timeoutFailureReport()
+	}
+	if err = predicttuple.reclaim(db, key, status); err != nil {
+		return
+	}
+	if err = out.Fill(db, key, predicttuple); err != nil {
+		return
+	}
+	if status == StatusTodo {
+		// the worker has to start the predicttuple again
+		event := TuplesEvent{}
+		event.SetPredicttuples(out)
+		db.AddTuplesEvent(event)
+	}
+	return
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to the stale tuples
+// -------------------------------------------------------------------------------------------
+
+// reclaimStaleTuples moves back to todo, or to failed, the doing tuples of the requester
+// whose timeout has passed
+func reclaimStaleTuples(db LedgerDB, args []string) (resp outputReclaimedTuples, err error) {
+	inp := inputReclaimStaleTuples{}
+	err = AssetFromJSON(db, args, &inp)
+	if err != nil {
+		return
+	}
+	if len(inp.TraintupleKeys) == 0 && len(inp.TesttupleKeys) == 0 && len(inp.PredicttupleKeys) == 0 {
+		err = errors.BadRequest(errors.CodeInvalidInput, "there is no tuple to reclaim")
+		return
+	}
+	resp.Traintuples = []outputTraintuple{}
+	resp.Testtuples = []outputTesttuple{}
+	resp.Predicttuples = []outputPredicttuple{}
+	for _, key := range inp.TraintupleKeys {
+		out, err := reclaimTraintuple(db, key)
+		if err != nil {
+			return resp, errors.Wrap(err).WithKey(key)
+		}
+		resp.Traintuples = append(resp.Traintuples, out)
+	}
+	for _, key := range inp.TesttupleKeys {
+		out, err := reclaimTesttuple(db, key)
+		if err != nil {
+			return resp, errors.Wrap(err).WithKey(key)
+		}
+		resp.Testtuples = append(resp.Testtuples, out)
+	}
+	for _, key := range inp.PredicttupleKeys {
+		out, err := reclaimPredicttuple(db, key)
+		if err != nil {
+			return resp, errors.Wrap(err).WithKey(key)
+		}
+		resp.Predicttuples = append(resp.Predicttuples, out)
+	}
+	return resp, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReclaimStaleTuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	setTime := func(seconds int64) {
+		mockStub.FixedTxTimestamp = &timestamp.Timestamp{Seconds: pipelineTimestamp + seconds}
+	}
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		payload, err := json.Marshal(inp)
+		require.NoError(t, err)
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), payload})
+		return resp.Status, resp.Message, resp.Payload
+	}
+
+	// The tuples of a compute plan get its timeout
+	inpComputePlan := defaultComputePlan
+	inpComputePlan.Timeout = 60
+	status, message, payload := invoke("createComputePlan", inpComputePlan)
+	require.EqualValues(t, 200, status, message)
+	plan := outputComputePlan{}
+	require.NoError(t, json.Unmarshal(payload, &plan))
+	staleKey := plan.TraintupleKeys[0]
+	reclaim := inputReclaimStaleTuples{TraintupleKeys: []string{staleKey}}
+
+	// The timeout starts when the traintuple is started
+	setTime(0)
+	status, message, _ = invoke("reclaimStaleTuples", reclaim)
+	assert.EqualValues(t, 400, status, message)
+	status, message, _ = invoke("logStartTrain", inputHash{staleKey})
+	require.EqualValues(t, 200, status, message)
+	setTime(60)
+	status, message, _ = invoke("reclaimStaleTuples", reclaim)
+	assert.EqualValues(t, 400, status, message)
+
+	// A progress report of the worker restarts the timeout
+	status, message, _ = invoke("logProgress", inputLogProgress{Key: staleKey, Step: 1, Percentage: 10})
+	require.EqualValues(t, 200, status, message)
+	setTime(61)
+	status, message, _ = invoke("reclaimStaleTuples", reclaim)
+	assert.EqualValues(t, 400, status, message)
+
+	// Only the creator of the traintuple can reclaim it
+	setTime(121)
+	mockStub.Creator = "OtherOrg"
+	status, message, _ = invoke("reclaimStaleTuples", reclaim)
+	assert.EqualValues(t, 403, status, message)
+	mockStub.Creator = ""
+
+	// The traintuple goes back to todo and is sent again to its worker
+	status, message, payload = invoke("reclaimStaleTuples", reclaim)
+	require.EqualValues(t, 200, status, message)
+	reclaimed := outputReclaimedTuples{}
+	require.NoError(t, json.Unmarshal(payload, &reclaimed))
+	require.Len(t, reclaimed.Traintuples, 1)
+	assert.Equal(t, StatusTodo, reclaimed.Traintuples[0].Status)
+	assert.Equal(t, 1, reclaimed.Traintuples[0].Attempts)
+	assert.Equal(t, 60, reclaimed.Traintuples[0].Timeout)
+	assert.Nil(t, reclaimed.Traintuples[0].Progress)
+	event := getLastEvent(t, mockStub)
+	require.Len(t, event.Traintuples, 1)
+	assert.Equal(t, staleKey, event.Traintuples[0].Key)
+	assert.Equal(t, []EventAsset{{
+		AssetType: "traintuple",
+		Key:       staleKey,
+		OldStatus: StatusDoing,
+		NewStatus: StatusTodo,
+		Function:  "reclaimStaleTuples",
+	}}, event.Assets.Traintuples)
+
+	// The traintuple fails once it has been started the maximum number of times
+	for attempt := 2; attempt <= defaultConfig.MaxAttempts; attempt++ {
+		setTime(int64(100 * attempt))
+		status, message, _ = invoke("logStartTrain", inputHash{staleKey})
+		require.EqualValues(t, 200, status, message)
+		if attempt == 2 {
+			// the reports of the new attempt follow the ones of the previous attempt
+			status, message, payload = invoke("logProgress", inputLogProgress{Key: staleKey, Step: 1, Percentage: 10})
+			require.EqualValues(t, 200, status, message)
+			restarted := outputTraintuple{}
+			require.NoError(t, json.Unmarshal(payload, &restarted))
+			assert.Equal(t, 2, restarted.Progress.Sequence)
+		}
+		setTime(int64(100*attempt + 61))
+		status, message, payload = invoke("reclaimStaleTuples", reclaim)
+		require.EqualValues(t, 200, status, message)
+	}
+	reclaimed = outputReclaimedTuples{}
+	require.NoError(t, json.Unmarshal(payload, &reclaimed))
+	require.Len(t, reclaimed.Traintuples, 1)
+	assert.Equal(t, StatusFailed, reclaimed.Traintuples[0].Status)
+	assert.Equal(t, &FailureReport{ErrorType: "timeout"}, reclaimed.Traintuples[0].FailureReport)
+
+	// The children of the failed traintuple fail too
+	status, message, payload = invoke("queryTraintuple", inputHash{plan.TraintupleKeys[1]})
+	require.EqualValues(t, 200, status, message)
+	child := outputTraintuple{}
+	require.NoError(t, json.Unmarshal(payload, &child))
+	assert.Equal(t, StatusFailed, child.Status)
+	assert.Equal(t, 60, child.Timeout)
+	status, message, payload = invoke("queryTesttuple", inputHash{plan.TesttupleKeys[0]})
+	require.EqualValues(t, 200, status, message)
+	testtuple := outputTesttuple{}
+	require.NoError(t, json.Unmarshal(payload, &testtuple))
+	assert.Equal(t, 60, testtuple.Timeout)
+}
+
+func TestReclaimOnlyMovesDoingTuplesBackToTodo(t *testing.T) {
+	db := NewLedgerDBWithStorage(NewMemoryContext(worker), NewMemoryStorage())
+
+	// Outside of a reclaim, a doing tuple cannot go back to todo
+	assert.Error(t, checkUpdateTuple(db, worker, StatusDoing, StatusTodo))
+	traintuple := Traintuple{Dataset: &Dataset{Worker: worker}, Status: StatusDoing}
+	assert.Error(t, traintuple.commitStatusUpdate(db, traintupleKey, StatusTodo))
+	assert.NoError(t, checkUpdateTuple(db, worker, StatusDoing, StatusFailed))
+}
@@ -0,0 +1,366 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// Storage is where the smart contracts store the assets and their indexes.
+// The assets are stored as json, and the indexes as composite keys whose last attribute
+// is the key of the indexed asset. Implementations read the writes of the transaction.
+type Storage interface {
+	// Get retrieves an object and sets the input object value, it fails with a not found
+	// error if there is none
+	Get(key string, object interface{}) error
+	// KeyExists checks if an object is stored
+	KeyExists(key string) (bool, error)
+	// Put stores an object, if the object already exists it is replaced
+	Put(key string, object interface{}) error
+	// Add stores an object, it fails with a conflict error if the object already exists
+	Add(key string, object interface{}) error
+	// Delete removes an object
+	Delete(key string) error
+	// CreateIndex adds a composite key to an index
+	CreateIndex(index string, attributes []string) error
+	// DeleteIndex removes a composite key from an index
+	DeleteIndex(index string, attributes []string) error
+	// UpdateIndex replaces a composite key of an index
+	UpdateIndex(index string, oldAttributes []string, newAttributes []string) error
+	// GetIndexKeys returns the keys of the assets matching the first attributes of an index
+	GetIndexKeys(index string, attributes []string) ([]string, error)
+	// ForEachIndexKey calls fn on the attributes of at most limit composite keys matching the
+	// first attributes of an index, after the cursor, or on all of them if limit is negative.
+	// It returns the last composite key processed if there are more keys after it, empty
+	// otherwise, and the number of keys processed.
+	ForEachIndexKey(index string, attributes []string, cursor string, limit int, fn func(attributes []string) error) (last string, count int, err error)
+}
+
+// The implementations of the storage
+var (
+	_ Storage = (*ledgerDB)(nil)
+	_ Storage = (*stubStorage)(nil)
+	_ Storage = (*privateDataStorage)(nil)
+	_ Storage = MemoryStorage{}
+	_ Storage = (*bufferedStorage)(nil)
+)
+
+// The implementations of the transaction context
+var (
+	_ TransactionContext = (shim.ChaincodeStubInterface)(nil)
+	_ TransactionContext = (*MemoryContext)(nil)
+	_ TransactionContext = (*bufferedContext)(nil)
+)
+
+// ----------------------------------------------
+// Helpers shared by the storage implementations
+// ----------------------------------------------
+
+// indexKeyNamespace prefixes the composite keys, as in Fabric
+const indexKeyNamespace = "\x00"
+
+// indexKeySeparator separates the attributes of a composite key, as in Fabric
+const indexKeySeparator = "\x00"
+
+// createIndexKey returns the composite key of an index, in the format used by Fabric
+func createIndexKey(index string, attributes []string) string {
+	key := indexKeyNamespace + index + indexKeySeparator
+	for _, attribute := range attributes {
+		key += attribute + indexKeySeparator
+	}
+	return key
+}
+
+// splitIndexKey returns the index and the attributes of a composite key
+func splitIndexKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, indexKeyNamespace) || !strings.HasSuffix(compositeKey, indexKeySeparator) {
+		return "", nil, errors.Internal("invalid composite key %q", compositeKey)
+	}
+	parts := strings.Split(compositeKey[len(indexKeyNamespace):len(compositeKey)-len(indexKeySeparator)], indexKeySeparator)
+	return parts[0], parts[1:], nil
+}
+
+// addObject stores an object if there is none with the same key
+func addObject(s Storage, key string, object interface{}) error {
+	exists, err := s.KeyExists(key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.Conflict("struct already exists (tkey: %s)", key).WithKey(key)
+	}
+	return s.Put(key, object)
+}
+
+// updateIndex replaces a composite key of an index
+func updateIndex(s Storage, index string, oldAttributes []string, newAttributes []string) error {
+	if err := s.DeleteIndex(index, oldAttributes); err != nil {
+		return err
+	}
+	return s.CreateIndex(index, newAttributes)
+}
+
+// indexKeys returns the keys of the assets of sorted composite keys
+func indexKeys(index string, compositeKeys []string) ([]string, error) {
+	keys := make([]string, 0, len(compositeKeys))
+	for _, compositeKey := range compositeKeys {
+		_, attributes, err := splitIndexKey(compositeKey)
+		if err != nil {
+			return nil, errors.Internal(err, "get index %s failed:", index)
+		}
+		keys = append(keys, attributes[len(attributes)-1])
+	}
+	return keys, nil
+}
+
+// forEachCompositeKey calls fn on the attributes of at most limit sorted composite keys after the cursor
+func forEachCompositeKey(compositeKeys []string, cursor string, limit int, fn func(attributes []string) error) (last string, count int, err error) {
+	for _, compositeKey := range compositeKeys {
+		if compositeKey <= cursor {
+			continue
+		}
+		if count == limit {
+			return last, count, nil
+		}
+		_, attributes, err := splitIndexKey(compositeKey)
+		if err != nil {
+			return "", count, err
+		}
+		if err := fn(attributes); err != nil {
+			return "", count, err
+		}
+		last = compositeKey
+		count++
+	}
+	return "", count, nil
+}
+
+// ----------------------------------------------
+// In-memory storage
+// ----------------------------------------------
+
+// MemoryStorage stores the assets in memory, to run the smart contracts outside of a peer
+type MemoryStorage struct {
+	mutex  *sync.RWMutex
+	states map[string][]byte
+}
+
+// NewMemoryStorage returns an empty in-memory storage
+func NewMemoryStorage() MemoryStorage {
+	return MemoryStorage{
+		mutex:  &sync.RWMutex{},
+		states: make(map[string][]byte),
+	}
+}
+
+// Get retrieves an object stored in memory and set the input object value
+func (s MemoryStorage) Get(key string, object interface{}) error {
+	s.mutex.RLock()
+	buff, ok := s.states[key]
+	s.mutex.RUnlock()
+	if !ok {
+		return errors.NotFound()
+	}
+	return json.Unmarshal(buff, &object)
+}
+
+// KeyExists checks if a key is stored in memory
+func (s MemoryStorage) KeyExists(key string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.states[key]
+	return ok, nil
+}
+
+// Put stores an object in memory, if the object already exists it is replaced
+func (s MemoryStorage) Put(key string, object interface{}) error {
+	buff, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[key] = buff
+	return nil
+}
+
+// Add stores an object in memory, it fails if the object already exists
+func (s MemoryStorage) Add(key string, object interface{}) error {
+	return addObject(s, key, object)
+}
+
+// Delete removes an object from memory
+func (s MemoryStorage) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// CreateIndex adds a new composite key in memory
+func (s MemoryStorage) CreateIndex(index string, attributes []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[createIndexKey(index, attributes)] = []byte{0x00}
+	return nil
+}
+
+// DeleteIndex deletes a composite key in memory
+func (s MemoryStorage) DeleteIndex(index string, attributes []string) error {
+	return s.Delete(createIndexKey(index, attributes))
+}
+
+// UpdateIndex updates an existing composite key in memory
+func (s MemoryStorage) UpdateIndex(index string, oldAttributes []string, newAttributes []string) error {
+	return updateIndex(s, index, oldAttributes, newAttributes)
+}
+
+// compositeKeys returns the sorted composite keys matching the first attributes of an index
+func (s MemoryStorage) compositeKeys(index string, attributes []string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	partialKey := createIndexKey(index, attributes)
+	compositeKeys := []string{}
+	for key := range s.states {
+		if strings.HasPrefix(key, partialKey) {
+			compositeKeys = append(compositeKeys, key)
+		}
+	}
+	sort.Strings(compositeKeys)
+	return compositeKeys
+}
+
+// GetIndexKeys returns keys matching composite key values stored in memory
+func (s MemoryStorage) GetIndexKeys(index string, attributes []string) ([]string, error) {
+	return indexKeys(index, s.compositeKeys(index, attributes))
+}
+
+// ForEachIndexKey calls fn on at most limit composite keys stored in memory after the cursor
+func (s MemoryStorage) ForEachIndexKey(index string, attributes []string, cursor string, limit int, fn func(attributes []string) error) (string, int, error) {
+	return forEachCompositeKey(s.compositeKeys(index, attributes), cursor, limit, fn)
+}
+
+// ----------------------------------------------
+// In-memory transaction context
+// ----------------------------------------------
+
+// MemoryContext is the context of a transaction run outside of a peer, with the endorsement
+// policies and the private data kept in memory
+type MemoryContext struct {
+	// Function and Args are the smart contract called by the transaction and its args
+	Function string
+	Args     []string
+	// Creator is the MSP ID of the creator of the transaction
+	Creator   string
+	TxID      string
+	Timestamp *timestamp.Timestamp
+	Transient map[string][]byte
+	// Events are the payloads of the events set by the transaction, by name
+	Events map[string][]byte
+	// CollectionMembers are the MSP IDs of the members of the private data collections, by
+	// collection. All the creators can read the collections not listed.
+	CollectionMembers    map[string][]string
+	validationParameters map[string][]byte
+	privateData          map[string]map[string][]byte
+}
+
+// NewMemoryContext returns the context of a transaction created by a node, at the current time
+func NewMemoryContext(creator string) *MemoryContext {
+	return &MemoryContext{
+		Creator:              creator,
+		Timestamp:            ptypes.TimestampNow(),
+		Transient:            make(map[string][]byte),
+		Events:               make(map[string][]byte),
+		CollectionMembers:    make(map[string][]string),
+		validationParameters: make(map[string][]byte),
+		privateData:          make(map[string]map[string][]byte),
+	}
+}
+
+// GetFunctionAndParameters returns the smart contract called by the transaction and its args
+func (ctx *MemoryContext) GetFunctionAndParameters() (string, []string) {
+	return ctx.Function, ctx.Args
+}
+
+// GetCreator returns the serialized identity of the creator of the transaction
+func (ctx *MemoryContext) GetCreator() ([]byte, error) {
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: ctx.Creator})
+}
+
+// GetTxID returns the id of the transaction
+func (ctx *MemoryContext) GetTxID() string {
+	return ctx.TxID
+}
+
+// GetTxTimestamp returns the timestamp of the transaction
+func (ctx *MemoryContext) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return ctx.Timestamp, nil
+}
+
+// GetTransient returns the transient map of the transaction
+func (ctx *MemoryContext) GetTransient() (map[string][]byte, error) {
+	return ctx.Transient, nil
+}
+
+// GetStateValidationParameter returns the endorsement policy of a key stored in memory
+func (ctx *MemoryContext) GetStateValidationParameter(key string) ([]byte, error) {
+	return ctx.validationParameters[key], nil
+}
+
+// SetStateValidationParameter stores the endorsement policy of a key in memory
+func (ctx *MemoryContext) SetStateValidationParameter(key string, ep []byte) error {
+	ctx.validationParameters[key] = ep
+	return nil
+}
+
+// GetPrivateData returns the value of a key of a private data collection stored in memory,
+// if the creator is a member of the collection
+func (ctx *MemoryContext) GetPrivateData(collection string, key string) ([]byte, error) {
+	if members, ok := ctx.CollectionMembers[collection]; ok && !stringInSlice(ctx.Creator, members) {
+		return nil, errors.Forbidden("tx creator does not have read access permission on privatedata in collectionName: %s", collection)
+	}
+	return ctx.privateData[collection][key], nil
+}
+
+// PutPrivateData stores the value of a key of a private data collection in memory
+func (ctx *MemoryContext) PutPrivateData(collection string, key string, value []byte) error {
+	if _, ok := ctx.privateData[collection]; !ok {
+		ctx.privateData[collection] = make(map[string][]byte)
+	}
+	ctx.privateData[collection][key] = value
+	return nil
+}
+
+// DelPrivateData removes a key of a private data collection from memory
+func (ctx *MemoryContext) DelPrivateData(collection string, key string) error {
+	delete(ctx.privateData[collection], key)
+	return nil
+}
+
+// SetEvent stores the event of the transaction in memory
+func (ctx *MemoryContext) SetEvent(name string, payload []byte) error {
+	ctx.Events[name] = payload
+	return nil
+}
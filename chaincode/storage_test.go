@@ -0,0 +1,126 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	for name, newStorage := range map[string]func(*MockStub) Storage{
+		"ledger": func(stub *MockStub) Storage { return newStubStorage(stub) },
+		"memory": func(stub *MockStub) Storage { return NewMemoryStorage() },
+		"private data": func(stub *MockStub) Storage {
+			return newPrivateDataStorage(stub, newStubStorage(stub), map[AssetType]string{NodeType: "nodes"})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockStub := NewMockStub("substra", new(SubstraChaincode))
+			mockStub.MockTransactionStart("42")
+			s := newStorage(mockStub)
+
+			require.NoError(t, s.Add("a", Node{ID: "a"}))
+			err := s.Add("a", Node{ID: "a"})
+			assert.True(t, errors.Is(err, errors.Conflict()))
+			node := Node{}
+			require.NoError(t, s.Get("a", &node))
+			assert.Equal(t, "a", node.ID)
+			require.NoError(t, s.Delete("a"))
+			exists, err := s.KeyExists("a")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+			assert.True(t, errors.Is(s.Get("a", &node), errors.NotFound()))
+
+			for _, key := range []string{"c", "a", "b"} {
+				require.NoError(t, s.CreateIndex("node~key", []string{"node", key}))
+			}
+			require.NoError(t, s.UpdateIndex("node~key", []string{"node", "c"}, []string{"node", "d"}))
+			keys, err := s.GetIndexKeys("node~key", []string{"node"})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a", "b", "d"}, keys)
+
+			visited := []string{}
+			visit := func(attributes []string) error {
+				visited = append(visited, attributes[1])
+				return nil
+			}
+			cursor, count, err := s.ForEachIndexKey("node~key", []string{"node"}, "", 2, visit)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, count)
+			_, count, err = s.ForEachIndexKey("node~key", []string{"node"}, cursor, 2, visit)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, count)
+			assert.Equal(t, []string{"a", "b", "d"}, visited)
+		})
+	}
+}
+
+func TestPrivateDataStorage(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.MockTransactionStart("42")
+	storage := newPrivateDataStorage(mockStub, newStubStorage(mockStub), map[AssetType]string{AlgoType: "algos"})
+	db := NewLedgerDBWithStorage(mockStub, storage)
+
+	inpAlgo := inputAlgo{}
+	_, err := registerAlgo(db, []string{string(inpAlgo.createDefault()[1])})
+	require.NoError(t, err)
+
+	// The algo is stored in its collection, the public state only references it
+	assert.NotNil(t, mockStub.PvtState["algos"][algoHash])
+	assert.JSONEq(t, `{"assetType": 3, "privateCollection": "algos"}`, string(mockStub.State[algoHash]))
+	algo, err := db.GetAlgo(algoHash)
+	assert.NoError(t, err)
+	assert.Equal(t, inpAlgo.Name, algo.Name)
+	algos, err := queryAlgos(db, []string{})
+	assert.NoError(t, err)
+	assert.Len(t, algos, 1)
+
+	// The other assets are stored in the public state
+	node, err := db.GetNode(worker)
+	assert.NoError(t, err)
+	assert.Equal(t, worker, node.ID)
+
+	require.NoError(t, db.Delete(algoHash))
+	assert.Nil(t, mockStub.PvtState["algos"][algoHash])
+	assert.Nil(t, mockStub.State[algoHash])
+}
+
+func TestMemoryStorageSmartContracts(t *testing.T) {
+	ctx := NewMemoryContext(worker)
+	db := NewLedgerDBWithStorage(ctx, NewMemoryStorage())
+
+	_, err := registerNode(db, []string{})
+	require.NoError(t, err)
+	inpAlgo := inputAlgo{}
+	args := inpAlgo.createDefault()
+	ctx.Transient = withPrivateInput(nil, args)
+	_, err = registerAlgo(db, []string{string(args[1])})
+	require.NoError(t, err)
+	require.NoError(t, db.SendEvent())
+	assert.NotEmpty(t, ctx.Events["tuples-updated"])
+
+	algos, err := queryAlgos(db, []string{})
+	assert.NoError(t, err)
+	assert.Len(t, algos, 1)
+	assert.Equal(t, algoStorageAddress, algos[0].Content.StorageAddress)
+	orgs, err := db.GetEndorsementPolicy(algoHash)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{worker}, orgs)
+}
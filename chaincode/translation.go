@@ -0,0 +1,266 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"gopkg.in/go-playground/validator.v9"
+	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+	fr_translations "gopkg.in/go-playground/validator.v9/translations/fr"
+)
+
+// defaultLocale is the locale of the messages when the requested one is not supported
+const defaultLocale = "en"
+
+// localeTransientKey is the key of the transient map holding the locale of the error messages
+const localeTransientKey = "locale"
+
+// universalTranslator holds the translators of all the supported locales
+var universalTranslator = ut.New(en.New(), en.New(), fr.New(), de.New())
+
+// validate is the validator of the inputs, shared by all smart contracts so that
+// the translations are only registered once
+var validate = newValidator()
+
+// validationFailedKey is the translation key of the validation errors' header
+const validationFailedKey = "inputsValidationFailed"
+
+// kindTranslation stores the messages of a validation rule, which depend on
+// the kind of the field for some rules. Only number is mandatory.
+type kindTranslation struct {
+	tag    string
+	number string
+	str    string
+	items  string
+}
+
+// enTranslations completes the english translations provided by the validator
+var enTranslations = []kindTranslation{
+	{tag: "storage_url", number: "{0} must be a valid URL with an allowed scheme"},
+	{tag: "data_manager_type", number: "{0} must be an allowed data manager type"},
+	{tag: "max_samples", number: "{0} contains too many data samples"},
+	{tag: "max_log", number: "{0} is too long"},
+}
+
+// frTranslations completes the french translations provided by the validator
+var frTranslations = []kindTranslation{
+	{tag: "unique", number: "{0} doit contenir des valeurs uniques"},
+	{tag: "storage_url", number: "{0} doit être une URL valide avec un schéma autorisé"},
+	{tag: "data_manager_type", number: "{0} doit être un type de data manager autorisé"},
+	{tag: "max_samples", number: "{0} contient trop d'échantillons de données"},
+	{tag: "max_log", number: "{0} est trop long"},
+}
+
+// deTranslations are the german messages of the validation rules used by the inputs,
+// since the validator does not provide german translations
+var deTranslations = []kindTranslation{
+	{tag: "required", number: "{0} ist ein Pflichtfeld"},
+	{
+		tag:    "len",
+		number: "{0} muss gleich {1} sein",
+		str:    "{0} muss genau {1} Zeichen lang sein",
+		items:  "{0} muss genau {1} Elemente enthalten",
+	},
+	{
+		tag:    "gte",
+		number: "{0} muss größer oder gleich {1} sein",
+		str:    "{0} muss mindestens {1} Zeichen lang sein",
+		items:  "{0} muss mindestens {1} Elemente enthalten",
+	},
+	{
+		tag:    "lte",
+		number: "{0} muss kleiner oder gleich {1} sein",
+		str:    "{0} darf höchstens {1} Zeichen lang sein",
+		items:  "{0} darf höchstens {1} Elemente enthalten",
+	},
+	{
+		tag:    "gt",
+		number: "{0} muss größer als {1} sein",
+		str:    "{0} muss länger als {1} Zeichen sein",
+		items:  "{0} muss mehr als {1} Elemente enthalten",
+	},
+	{tag: "hexadecimal", number: "{0} muss eine gültige Hexadezimalzahl sein"},
+	{tag: "url", number: "{0} muss eine gültige URL sein"},
+	{tag: "oneof", number: "{0} muss einer der folgenden Werte sein: [{1}]"},
+	{tag: "unique", number: "{0} muss eindeutige Werte enthalten"},
+	{tag: "storage_url", number: "{0} muss eine gültige URL mit einem erlaubten Schema sein"},
+	{tag: "data_manager_type", number: "{0} muss ein erlaubter Data-Manager-Typ sein"},
+	{tag: "max_samples", number: "{0} enthält zu viele Datenproben"},
+	{tag: "max_log", number: "{0} ist zu lang"},
+}
+
+// newValidator returns a validator naming the fields after their json keys, with the
+// validation rules depending on the configuration and the translations of all the
+// supported locales
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	for tag, fn := range configValidations {
+		if err := v.RegisterValidationCtx(tag, fn); err != nil {
+			logger.Errorf("failed to register validation %s: %s", tag, err)
+		}
+	}
+
+	if err := registerTranslations(v); err != nil {
+		logger.Errorf("failed to register validation translations: %s", err)
+	}
+	return v
+}
+
+// registerTranslations registers the validation messages of each supported locale
+func registerTranslations(v *validator.Validate) error {
+	headers := map[string]string{
+		"en": "inputs validation failed",
+		"fr": "la validation des entrées a échoué",
+		"de": "die Validierung der Eingaben ist fehlgeschlagen",
+	}
+	for locale, header := range headers {
+		trans, _ := universalTranslator.GetTranslator(locale)
+		if err := trans.Add(validationFailedKey, header, false); err != nil {
+			return err
+		}
+	}
+
+	trans, _ := universalTranslator.GetTranslator("en")
+	if err := en_translations.RegisterDefaultTranslations(v, trans); err != nil {
+		return err
+	}
+	if err := registerKindTranslations(v, trans, enTranslations); err != nil {
+		return err
+	}
+	trans, _ = universalTranslator.GetTranslator("fr")
+	if err := fr_translations.RegisterDefaultTranslations(v, trans); err != nil {
+		return err
+	}
+	if err := registerKindTranslations(v, trans, frTranslations); err != nil {
+		return err
+	}
+	trans, _ = universalTranslator.GetTranslator("de")
+	return registerKindTranslations(v, trans, deTranslations)
+}
+
+// registerKindTranslations registers validation messages depending on the kind of the field
+func registerKindTranslations(v *validator.Validate, trans ut.Translator, translations []kindTranslation) error {
+	for _, t := range translations {
+		t := t
+		register := func(trans ut.Translator) error {
+			if err := trans.Add(t.tag, t.number, true); err != nil {
+				return err
+			}
+			if t.str != "" {
+				if err := trans.Add(t.tag+"-string", t.str, true); err != nil {
+					return err
+				}
+			}
+			if t.items != "" {
+				if err := trans.Add(t.tag+"-items", t.items, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			key := t.tag
+			switch fe.Kind() {
+			case reflect.String:
+				if t.str != "" {
+					key = t.tag + "-string"
+				}
+			case reflect.Slice, reflect.Map, reflect.Array:
+				if t.items != "" {
+					key = t.tag + "-items"
+				}
+			}
+			msg, err := trans.T(key, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.(error).Error()
+			}
+			return msg
+		}
+		if err := v.RegisterTranslation(t.tag, trans, register, translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLocale returns the locale requested in the transient map of the transaction, if any
+func getLocale(ctx TransactionContext) string {
+	transient, err := ctx.GetTransient()
+	if err != nil {
+		return ""
+	}
+	return string(transient[localeTransientKey])
+}
+
+// getTranslator returns the translator of a locale such as fr, fr-FR or fr_FR,
+// the one of the default locale if it is not supported
+func getTranslator(locale string) ut.Translator {
+	locale = strings.Replace(locale, "-", "_", -1)
+	language := strings.ToLower(strings.SplitN(locale, "_", 2)[0])
+	trans, _ := universalTranslator.FindTranslator(locale, language)
+	return trans
+}
+
+// fieldErrors converts validation errors into field errors, the field being the
+// path of the invalid value in the json input
+func fieldErrors(validationErrors validator.ValidationErrors, trans ut.Translator) []errors.FieldError {
+	fields := []errors.FieldError{}
+	for _, fe := range validationErrors {
+		field := fe.Namespace()
+		// Remove the name of the input struct
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		fields = append(fields, errors.FieldError{
+			Field:   field,
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Value:   fe.Value(),
+			Message: fe.Translate(trans),
+		})
+	}
+	return fields
+}
+
+// translateError returns, for validation errors, the error message and the invalid
+// fields in the given locale. ok is false for other errors.
+func translateError(err error, locale string) (message string, fields []errors.FieldError, ok bool) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return "", nil, false
+	}
+	trans := getTranslator(locale)
+	fields = fieldErrors(validationErrors, trans)
+	messages := []string{}
+	for _, field := range fields {
+		messages = append(messages, field.Message)
+	}
+	header, _ := trans.T(validationFailedKey)
+	return header + ": " + strings.Join(messages, "; "), fields, true
+}
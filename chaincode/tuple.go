@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 
 	"encoding/json"
 )
@@ -51,19 +52,26 @@ func (traintuple *Traintuple) SetFromInput(db LedgerDB, inp inputTraintuple) err
 
 	// TODO later: check permissions
 	// find associated creator and check permissions (TODO later)
-	creator, err := GetTxCreator(db.cc)
+	creator, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
 	traintuple.AssetType = TraintupleType
 	traintuple.Creator = creator
 	traintuple.Tag = inp.Tag
+	traintuple.Priority = inp.Priority
+	if traintuple.CreationDate, err = getTxTimestamp(db); err != nil {
+		return err
+	}
 	algo, err := db.GetAlgo(inp.AlgoKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
 	}
 	if !algo.Permissions.CanProcess(algo.Owner, creator) {
-		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedAlgo, "not authorized to process algo %s", inp.AlgoKey))
+		if err != nil {
+			return err
+		}
 	}
 	traintuple.AlgoKey = inp.AlgoKey
 
@@ -73,9 +81,13 @@ func (traintuple *Traintuple) SetFromInput(db LedgerDB, inp inputTraintuple) err
 		return errors.BadRequest(err, "could not retrieve objective with key %s", inp.ObjectiveKey)
 	}
 	if !objective.Permissions.CanProcess(objective.Owner, creator) {
-		return errors.Forbidden("not authorized to process objective %s", inp.ObjectiveKey)
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedObjective, "not authorized to process objective %s", inp.ObjectiveKey))
+		if err != nil {
+			return err
+		}
 	}
 	traintuple.ObjectiveKey = inp.ObjectiveKey
+	traintuple.Timeout = objective.Timeout
 
 	// check if DataSampleKeys are from the same dataManager and if they are not test only dataSample
 	_, trainOnly, err := checkSameDataManager(db, inp.DataManagerKey, inp.DataSampleKeys)
@@ -83,7 +95,10 @@ func (traintuple *Traintuple) SetFromInput(db LedgerDB, inp inputTraintuple) err
 		return err
 	}
 	if !trainOnly {
-		return errors.BadRequest("not possible to create a traintuple with test only data")
+		err = db.Check(errors.BadRequest("not possible to create a traintuple with test only data"))
+		if err != nil {
+			return err
+		}
 	}
 
 	dataManager, err := db.GetDataManager(inp.DataManagerKey)
@@ -91,7 +106,10 @@ func (traintuple *Traintuple) SetFromInput(db LedgerDB, inp inputTraintuple) err
 		return errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
 	}
 	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
-		return errors.Forbidden("not authorized to process dataManager %s", inp.DataManagerKey)
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedDataManager, "not authorized to process dataManager %s", inp.DataManagerKey))
+		if err != nil {
+			return err
+		}
 	}
 
 	traintuple.Permissions = MergePermissions(dataManager.Permissions, algo.Permissions)
@@ -106,19 +124,29 @@ func (traintuple *Traintuple) SetFromInput(db LedgerDB, inp inputTraintuple) err
 }
 
 // SetFromParents set the status of the traintuple depending on its "parents",
-// i.e. the traintuples from which it received the outModels as inModels.
+// i.e. the traintuples from which it received the outModels as inModels, or the
+// registered models, which are always done.
 // Also it's InModelKeys are set.
 func (traintuple *Traintuple) SetFromParents(db LedgerDB, inModels []string) error {
 	status := StatusTodo
 	parentTraintupleKeys := inModels
 	for _, parentTraintupleKey := range parentTraintupleKeys {
-		parentTraintuple, err := db.GetTraintuple(parentTraintupleKey)
+		parent, err := getParentModel(db, parentTraintupleKey)
 		if err != nil {
-			err = errors.BadRequest(err, "could not retrieve parent traintuple with key %s %d", parentTraintupleKeys, len(parentTraintupleKeys))
+			err = errors.BadRequest(err, "could not retrieve parent traintuple or model with key %s", parentTraintupleKey)
 			return err
 		}
+		if parent.External {
+			if parent.AlgoKey != traintuple.AlgoKey {
+				return errors.BadRequest("model %s is not compatible with algo %s", parentTraintupleKey, traintuple.AlgoKey)
+			}
+			if err = parent.checkProcess(db, parentTraintupleKey, traintuple.Creator); err != nil {
+				return err
+			}
+			traintuple.Permissions = MergePermissions(traintuple.Permissions, parent.Permissions)
+		}
 		// set traintuple to waiting if one of the parent traintuples is not done
-		if parentTraintuple.OutModel == nil {
+		if parent.Hash == "" {
 			status = StatusWaiting
 		}
 		traintuple.InModelKeys = append(traintuple.InModelKeys, parentTraintupleKey)
@@ -178,6 +206,10 @@ func (traintuple *Traintuple) AddToComputePlan(db LedgerDB, inp inputTraintuple,
 		if FLTraintuple.AlgoKey != inp.AlgoKey {
 			return errors.BadRequest("previous traintuple for ComputePlanID %s does not have the same algo key %s", inp.ComputePlanID, inp.AlgoKey)
 		}
+		// the timeout of the compute plan is the one of its first traintuple
+		if ttKey == inp.ComputePlanID {
+			traintuple.Timeout = FLTraintuple.Timeout
+		}
 	}
 
 	ttKeys, err = db.GetIndexKeys("traintuple~computeplanid~worker~rank~key", []string{"traintuple", inp.ComputePlanID, traintuple.Dataset.Worker, inp.Rank})
@@ -241,51 +273,76 @@ func (traintuple *Traintuple) Save(db LedgerDB, traintupleKey string) error {
 //  - Dataset
 //  - Certified
 func (testtuple *Testtuple) SetFromInput(db LedgerDB, inp inputTesttuple) error {
-	creator, err := GetTxCreator(db.cc)
+	creator, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
 	testtuple.Creator = creator
 	testtuple.Tag = inp.Tag
 	testtuple.AssetType = TesttupleType
+	testtuple.Priority = inp.Priority
+	if testtuple.CreationDate, err = getTxTimestamp(db); err != nil {
+		return err
+	}
+
+	// the objective of a registered model is given by the input
+	if testtuple.ObjectiveKey == "" {
+		if inp.ObjectiveKey == "" {
+			return errors.BadRequest("invalid input: objectiveKey is required to evaluate the registered model %s", testtuple.Model.TraintupleKey)
+		}
+		testtuple.ObjectiveKey = inp.ObjectiveKey
+	}
 
-	// Get test dataset from objective
+	// Get test datasets from objective
 	objective, err := db.GetObjective(testtuple.ObjectiveKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve objective with key %s", testtuple.ObjectiveKey)
 	}
-	var objectiveDataManagerKey string
-	var objectiveDataSampleKeys []string
-	if objective.TestDataset != nil {
-		objectiveDataManagerKey = objective.TestDataset.DataManagerKey
-		objectiveDataSampleKeys = objective.TestDataset.DataSampleKeys
-	}
-	// For now we need to sort it but in fine it should be save sorted
-	// TODO
-	sort.Strings(objectiveDataSampleKeys)
+	testDatasets := objective.getTestDatasets()
 
-	var dataManagerKey string
-	var dataSampleKeys []string
 	if len(inp.DataManagerKey) > 0 && len(inp.DataSampleKeys) > 0 {
 		// non-certified testtuple
 		// test dataset are specified by the user
-		dataSampleKeys = inp.DataSampleKeys
-		_, _, err = checkSameDataManager(db, inp.DataManagerKey, dataSampleKeys)
+		_, _, err = checkSameDataManager(db, inp.DataManagerKey, inp.DataSampleKeys)
 		if err != nil {
 			return err
 		}
-		dataManagerKey = inp.DataManagerKey
+		dataSampleKeys := append([]string{}, inp.DataSampleKeys...)
 		sort.Strings(dataSampleKeys)
-		testtuple.Certified = objectiveDataManagerKey == dataManagerKey && reflect.DeepEqual(objectiveDataSampleKeys, dataSampleKeys)
+		if testDataset := getTestDataset(testDatasets, inp.DataManagerKey, dataSampleKeys); testDataset != nil {
+			testtuple.Certified = true
+			return testtuple.SetTestDataset(db, testDataset)
+		}
+		return testtuple.SetDataset(db, inp.DataManagerKey, dataSampleKeys)
 	} else if len(inp.DataManagerKey) > 0 || len(inp.DataSampleKeys) > 0 {
 		return errors.BadRequest("invalid input: dataManagerKey and dataSampleKey should be provided together")
-	} else if objective.TestDataset != nil {
-		dataSampleKeys = objectiveDataSampleKeys
-		dataManagerKey = objectiveDataManagerKey
-		testtuple.Certified = true
-	} else {
+	} else if len(testDatasets) == 0 {
 		return errors.BadRequest("can not create a certified testtuple, no data associated with objective %s", testtuple.ObjectiveKey)
 	}
+	// the certified testtuple on the first test dataset, the other ones are set by getEvaluation
+	testtuple.Certified = true
+	return testtuple.SetTestDataset(db, testDatasets[0])
+}
+
+// getTestDataset returns the test dataset of an objective made of the data samples of a data manager,
+// or nil if there is none
+func getTestDataset(testDatasets []*Dataset, dataManagerKey string, dataSampleKeys []string) *Dataset {
+	for _, dataset := range testDatasets {
+		// For now we need to sort it but in fine it should be save sorted
+		// TODO
+		objectiveDataSampleKeys := append([]string{}, dataset.DataSampleKeys...)
+		sort.Strings(objectiveDataSampleKeys)
+		if dataset.DataManagerKey == dataManagerKey && reflect.DeepEqual(objectiveDataSampleKeys, dataSampleKeys) {
+			return dataset
+		}
+	}
+	return nil
+}
+
+// SetDataset sets the dataset the testtuple is run on, its worker being the owner of the data manager
+func (testtuple *Testtuple) SetDataset(db LedgerDB, dataManagerKey string, dataSampleKeys []string) error {
+	dataSampleKeys = append([]string{}, dataSampleKeys...)
+	sort.Strings(dataSampleKeys)
 	// retrieve dataManager owner
 	dataManager, err := db.GetDataManager(dataManagerKey)
 	if err != nil {
@@ -299,34 +356,83 @@ func (testtuple *Testtuple) SetFromInput(db LedgerDB, inp inputTesttuple) error
 	return nil
 }
 
+// SetTestDataset sets a test dataset of the objective as the dataset of a certified testtuple, along
+// with its private partition
+func (testtuple *Testtuple) SetTestDataset(db LedgerDB, dataset *Dataset) error {
+	if err := testtuple.SetDataset(db, dataset.DataManagerKey, dataset.DataSampleKeys); err != nil {
+		return err
+	}
+	if len(dataset.PrivateDataSampleKeys) > 0 {
+		testtuple.Dataset.PrivateDataSampleKeys = append([]string{}, dataset.PrivateDataSampleKeys...)
+		sort.Strings(testtuple.Dataset.PrivateDataSampleKeys)
+	}
+	return nil
+}
+
+// checkChallenge verifies that a certified testtuple follows the rules of the challenge of its objective
+func (testtuple *Testtuple) checkChallenge(db LedgerDB) error {
+	if !testtuple.Certified {
+		return nil
+	}
+	objective, err := db.GetObjective(testtuple.ObjectiveKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve objective with key %s", testtuple.ObjectiveKey)
+	}
+	return objective.checkChallenge(db, testtuple.ObjectiveKey, testtuple.Creator)
+}
+
+// getEvaluation returns the testtuples of the certified evaluation of a testtuple: the testtuple
+// itself, and the same testtuple on each of the other test datasets of the objective
+func (testtuple *Testtuple) getEvaluation(db LedgerDB) ([]Testtuple, error) {
+	testtuples := []Testtuple{*testtuple}
+	if !testtuple.Certified {
+		return testtuples, nil
+	}
+	objective, err := db.GetObjective(testtuple.ObjectiveKey)
+	if err != nil {
+		return nil, errors.BadRequest(err, "could not retrieve objective with key %s", testtuple.ObjectiveKey)
+	}
+	for _, dataset := range objective.getTestDatasets() {
+		if dataset.DataManagerKey == testtuple.Dataset.OpenerHash {
+			continue
+		}
+		other := *testtuple
+		if err = other.SetTestDataset(db, dataset); err != nil {
+			return nil, err
+		}
+		testtuples = append(testtuples, other)
+	}
+	return testtuples, nil
+}
+
 // SetFromTraintuple set the parameters of the testuple depending on traintuple
-// it depends on. It sets:
+// it depends on, or on the registered model. It sets:
 //  - AlgoKey
-//  - ObjectiveKey
+//  - ObjectiveKey, except for a registered model
 //  - Model
+//  - Permissions
 //  - Status
 func (testtuple *Testtuple) SetFromTraintuple(db LedgerDB, traintupleKey string) error {
 
 	// check associated traintuple
-	traintuple, err := db.GetTraintuple(traintupleKey)
+	traintuple, err := getParentModel(db, traintupleKey)
 	if err != nil {
-		return errors.BadRequest(err, "could not retrieve traintuple with key %s", traintupleKey)
+		return errors.BadRequest(err, "could not retrieve traintuple or model with key %s", traintupleKey)
 	}
-	creator, err := GetTxCreator(db.cc)
+	creator, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
-	if !traintuple.Permissions.CanProcess(traintuple.Creator, creator) {
-		return errors.Forbidden("not authorized to process traintuple %s", traintupleKey)
+	if err = traintuple.checkProcess(db, traintupleKey, creator); err != nil {
+		return err
 	}
 	testtuple.ObjectiveKey = traintuple.ObjectiveKey
 	testtuple.AlgoKey = traintuple.AlgoKey
+	testtuple.Permissions = traintuple.Permissions
+	testtuple.Timeout = traintuple.Timeout
 	testtuple.Model = &Model{
 		TraintupleKey: traintupleKey,
-	}
-	if traintuple.OutModel != nil {
-		testtuple.Model.Hash = traintuple.OutModel.Hash
-		testtuple.Model.StorageAddress = traintuple.OutModel.StorageAddress
+		Hash:          traintuple.Hash,
 	}
 
 	switch status := traintuple.Status; status {
@@ -342,18 +448,180 @@ func (testtuple *Testtuple) SetFromTraintuple(db LedgerDB, traintupleKey string)
 	return nil
 }
 
+// SetFromTraintuples set the parameters of an ensemble testtuple depending on the traintuples,
+// or the registered models, it evaluates together. They must share the same algo and objective,
+// the permissions of the testtuple being the merge of their permissions. The testtuple waits
+// until all of them are done.
+func (testtuple *Testtuple) SetFromTraintuples(db LedgerDB, traintupleKeys []string) error {
+	creator, err := GetTxCreator(db)
+	if err != nil {
+		return err
+	}
+	testtuple.Status = StatusTodo
+	testtuple.Models = []*Model{}
+	for i, traintupleKey := range traintupleKeys {
+		traintuple, err := getParentModel(db, traintupleKey)
+		if err != nil {
+			return errors.BadRequest(err, "could not retrieve traintuple or model with key %s", traintupleKey)
+		}
+		if err = traintuple.checkProcess(db, traintupleKey, creator); err != nil {
+			return err
+		}
+		if i == 0 {
+			testtuple.AlgoKey = traintuple.AlgoKey
+			testtuple.Permissions = traintuple.Permissions
+			testtuple.Timeout = traintuple.Timeout
+		} else {
+			if traintuple.AlgoKey != testtuple.AlgoKey {
+				return errors.BadRequest("invalid input: the models of an ensemble should have the same algo, %s has algo %s instead of %s",
+					traintupleKey, traintuple.AlgoKey, testtuple.AlgoKey)
+			}
+			testtuple.Permissions = MergePermissions(testtuple.Permissions, traintuple.Permissions)
+			if traintuple.Timeout > testtuple.Timeout {
+				testtuple.Timeout = traintuple.Timeout
+			}
+		}
+		// registered models have no objective
+		if traintuple.ObjectiveKey != "" {
+			if testtuple.ObjectiveKey != "" && traintuple.ObjectiveKey != testtuple.ObjectiveKey {
+				return errors.BadRequest("invalid input: the models of an ensemble should have the same objective, %s has objective %s instead of %s",
+					traintupleKey, traintuple.ObjectiveKey, testtuple.ObjectiveKey)
+			}
+			testtuple.ObjectiveKey = traintuple.ObjectiveKey
+		}
+
+		switch status := traintuple.Status; status {
+		case StatusDone:
+		case StatusFailed:
+			return errors.BadRequest(
+				"could not register this testtuple, the traintuple %s has a failed status",
+				traintupleKey)
+		default:
+			testtuple.Status = StatusWaiting
+		}
+		testtuple.Models = append(testtuple.Models, &Model{
+			TraintupleKey: traintupleKey,
+			Hash:          traintuple.Hash,
+		})
+	}
+	first := *testtuple.Models[0]
+	testtuple.Model = &first
+	return nil
+}
+
+// getModels returns the models evaluated by the testtuple: the members of an ensemble, or its model
+func (testtuple *Testtuple) getModels() []*Model {
+	if len(testtuple.Models) > 0 {
+		return testtuple.Models
+	}
+	if testtuple.Model == nil {
+		return []*Model{}
+	}
+	return []*Model{testtuple.Model}
+}
+
+// setModelHash sets the hash of the model trained by one of the traintuples of the testtuple
+func (testtuple *Testtuple) setModelHash(traintupleKey string, hash string) {
+	for _, model := range testtuple.Models {
+		if model.TraintupleKey == traintupleKey {
+			model.Hash = hash
+		}
+	}
+	if testtuple.Model.TraintupleKey == traintupleKey {
+		testtuple.Model.Hash = hash
+	}
+}
+
+// hasAllModels checks if all the models evaluated by the testtuple have been trained
+func (testtuple *Testtuple) hasAllModels() bool {
+	for _, model := range testtuple.getModels() {
+		if model.Hash == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRegisteredModel checks if one of the models evaluated by the testtuple is a registered model
+func (testtuple *Testtuple) hasRegisteredModel() bool {
+	for _, model := range testtuple.getModels() {
+		if model.isRegistered() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAlgo sets the algo evaluating registered models, the one the models are compatible with
+// by default, and merges its permissions with the ones of the models. The algo of a traintuple
+// cannot be changed.
+func (testtuple *Testtuple) SetAlgo(db LedgerDB, algoKey string) error {
+	for _, model := range testtuple.getModels() {
+		if !model.isRegistered() && algoKey != "" && algoKey != testtuple.AlgoKey {
+			return errors.BadRequest("invalid input: the algo of traintuple %s cannot be changed", model.TraintupleKey)
+		}
+	}
+	if !testtuple.hasRegisteredModel() {
+		return nil
+	}
+	if algoKey == "" {
+		algoKey = testtuple.AlgoKey
+	}
+	algo, err := db.GetAlgo(algoKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve algo with key %s", algoKey)
+	}
+	creator, err := GetTxCreator(db)
+	if err != nil {
+		return err
+	}
+	if !algo.Permissions.CanProcess(algo.Owner, creator) {
+		err = db.Check(errors.Forbidden(errors.CodePermissionDeniedAlgo, "not authorized to process algo %s", algoKey))
+		if err != nil {
+			return err
+		}
+	}
+	testtuple.AlgoKey = algoKey
+	testtuple.Permissions = MergePermissions(testtuple.Permissions, algo.Permissions)
+	return nil
+}
+
 // GetKey return the key of the testuple depending on its key parameters.
 func (testtuple *Testtuple) GetKey() string {
 	// create testtuple key and check if it already exists
 	hashKeys := []string{
-		testtuple.Model.TraintupleKey,
 		testtuple.Dataset.OpenerHash,
 		testtuple.Creator,
 	}
+	for _, model := range testtuple.getModels() {
+		hashKeys = append(hashKeys, model.TraintupleKey)
+	}
 	hashKeys = append(hashKeys, testtuple.Dataset.DataSampleKeys...)
+	// a registered model can be evaluated with several algos
+	if testtuple.hasRegisteredModel() {
+		hashKeys = append(hashKeys, testtuple.AlgoKey)
+	}
 	return HashForKey("testtuple", hashKeys...)
 }
 
+// getEvaluationKey returns the key shared by the testtuples evaluating the same models with the
+// same algo, on the different test datasets of the objective
+func (testtuple *Testtuple) getEvaluationKey() string {
+	hashKeys := []string{testtuple.Creator, testtuple.AlgoKey}
+	for _, model := range testtuple.getModels() {
+		hashKeys = append(hashKeys, model.TraintupleKey)
+	}
+	return HashForKey("evaluation", hashKeys...)
+}
+
+// createEvaluationIndex indexes a certified testtuple by creator and UTC day of creation, to
+// count the daily submissions of a challenge. The testtuples of an evaluation on several test
+// datasets share the same composite key.
+func (testtuple *Testtuple) createEvaluationIndex(db LedgerDB) error {
+	day := strings.SplitN(testtuple.CreationDate, "T", 2)[0]
+	return db.CreateIndex(evaluationIndex, []string{"evaluation", testtuple.ObjectiveKey, testtuple.Creator, day, testtuple.getEvaluationKey()})
+}
+
 // Save will put in the legder interface both the testtuple with its key
 // and all the associated composite keys
 func (testtuple *Testtuple) Save(db LedgerDB, testtupleKey string) error {
@@ -375,8 +643,19 @@ func (testtuple *Testtuple) Save(db LedgerDB, testtupleKey string) error {
 	if err = db.CreateIndex("testtuple~traintuple~certified~key", []string{"testtuple", testtuple.Model.TraintupleKey, strconv.FormatBool(testtuple.Certified), testtupleKey}); err != nil {
 		return err
 	}
+	if testtuple.Certified {
+		if err = testtuple.createEvaluationIndex(db); err != nil {
+			return err
+		}
+	}
+	// the other members of an ensemble update the testtuple once they are done too
+	for _, model := range testtuple.getModels()[1:] {
+		if err = db.CreateIndex("testtuple~member~key", []string{"testtuple", model.TraintupleKey, testtupleKey}); err != nil {
+			return err
+		}
+	}
 	if testtuple.Tag != "" {
-		err = db.CreateIndex("testtuple~tag~key", []string{"traintuple", testtuple.Tag, testtupleKey})
+		err = db.CreateIndex("testtuple~tag~key", []string{"testtuple", testtuple.Tag, testtupleKey})
 		if err != nil {
 			return err
 		}
@@ -392,54 +671,35 @@ func (testtuple *Testtuple) Save(db LedgerDB, testtupleKey string) error {
 // createComputePlan is the wrapper for the substra smartcontract CreateComputePlan
 func createComputePlan(db LedgerDB, args []string) (resp outputComputePlan, err error) {
 	inp := inputComputePlan{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
 	traintupleKeysByID := map[string]string{}
+	// During a dry run, the tuples depending on a failed traintuple are skipped,
+	// their errors would only follow from the error of their parent
+	failedIDs := map[string]bool{}
 	resp.TraintupleKeys = []string{}
 	var traintuplesTodo []outputTraintuple
 	for i, computeTraintuple := range inp.Traintuples {
-		inpTraintuple := inputTraintuple{}
-		inpTraintuple.AlgoKey = inp.AlgoKey
-		inpTraintuple.ObjectiveKey = inp.ObjectiveKey
-		inpTraintuple.DataManagerKey = computeTraintuple.DataManagerKey
-		inpTraintuple.DataSampleKeys = computeTraintuple.DataSampleKeys
-		inpTraintuple.Tag = computeTraintuple.Tag
-		inpTraintuple.Rank = strconv.Itoa(i)
-
-		traintuple := Traintuple{}
-		err := traintuple.SetFromInput(db, inpTraintuple)
-		if err != nil {
-			return resp, err
+		if dependsOnFailedTraintuple(computeTraintuple, failedIDs) {
+			failedIDs[computeTraintuple.ID] = true
+			continue
 		}
-
-		// Set the inModels by matching the id to traintuples key previously
-		// encontered in this compute plan
-		for _, InModelID := range computeTraintuple.InModelsIDs {
-			inModelKey, ok := traintupleKeysByID[InModelID]
-			if !ok {
-				return resp, errors.BadRequest("traintuple ID %s: model ID %s not found, check traintuple list order", computeTraintuple.ID, InModelID)
+		traintuple, traintupleKey, err := createComputePlanTraintuple(db, inp, i, resp.ComputePlanID, traintupleKeysByID)
+		if err != nil {
+			// During a dry run, the next traintuples are checked anyway
+			if err = db.Check(err); err != nil {
+				return resp, err
 			}
-			traintuple.InModelKeys = append(traintuple.InModelKeys, inModelKey)
+			failedIDs[computeTraintuple.ID] = true
+			continue
 		}
-
-		traintupleKey := traintuple.GetKey()
-
-		// Set the ComputePlanID
 		if i == 0 {
-			traintuple.ComputePlanID = traintupleKey
 			resp.ComputePlanID = traintuple.ComputePlanID
-		} else {
-			traintuple.ComputePlanID = resp.ComputePlanID
 		}
-
-		// Set status: if it has parents it's waiting
-		// if not it's todo and it has to be included in the event
-		if len(computeTraintuple.InModelsIDs) > 0 {
-			traintuple.Status = StatusWaiting
-		} else {
-			traintuple.Status = StatusTodo
+		// The traintuples without parents have to be included in the event
+		if traintuple.Status == StatusTodo {
 			out := outputTraintuple{}
 			err = out.Fill(db, traintuple, traintupleKey)
 			if err != nil {
@@ -447,57 +707,152 @@ func createComputePlan(db LedgerDB, args []string) (resp outputComputePlan, err
 			}
 			traintuplesTodo = append(traintuplesTodo, out)
 		}
-
-		err = traintuple.Save(db, traintupleKey)
-		if err != nil {
-			return resp, err
-		}
 		traintupleKeysByID[computeTraintuple.ID] = traintupleKey
 		resp.TraintupleKeys = append(resp.TraintupleKeys, traintupleKey)
 	}
 
 	resp.TesttupleKeys = []string{}
 	for index, computeTesttuple := range inp.Testtuples {
-		traintupleKey, ok := traintupleKeysByID[computeTesttuple.TraintupleID]
-		if !ok {
-			return resp, errors.BadRequest("testtuple index %s: traintuple ID %s not found", index, computeTesttuple.TraintupleID)
-		}
-		testtuple := Testtuple{}
-		testtuple.Model = &Model{TraintupleKey: traintupleKey}
-		testtuple.ObjectiveKey = inp.ObjectiveKey
-		testtuple.AlgoKey = inp.AlgoKey
-
-		inputTesttuple := inputTesttuple{}
-		inputTesttuple.DataManagerKey = computeTesttuple.DataManagerKey
-		inputTesttuple.DataSampleKeys = computeTesttuple.DataSampleKeys
-		inputTesttuple.Tag = computeTesttuple.Tag
-		err = testtuple.SetFromInput(db, inputTesttuple)
-		if err != nil {
-			return resp, err
+		if failedIDs[computeTesttuple.TraintupleID] {
+			continue
 		}
-		testtuple.Status = StatusWaiting
-		testtupleKey := testtuple.GetKey()
-		err = testtuple.Save(db, testtupleKey)
+		testtupleKeys, err := createComputePlanTesttuple(db, inp, index, traintupleKeysByID)
 		if err != nil {
-			return resp, err
+			// During a dry run, the next testtuples are checked anyway
+			if err = db.Check(err); err != nil {
+				return resp, err
+			}
+			continue
 		}
-		resp.TesttupleKeys = append(resp.TesttupleKeys, testtupleKey)
+		resp.TesttupleKeys = append(resp.TesttupleKeys, testtupleKeys...)
 	}
 
 	event := TuplesEvent{}
 	event.SetTraintuples(traintuplesTodo...)
-	err = SendTuplesEvent(db.cc, event)
+	db.AddTuplesEvent(event)
+
+	return resp, nil
+}
+
+// dependsOnFailedTraintuple returns true if a traintuple of a compute plan uses the model
+// of a traintuple which could not be created
+func dependsOnFailedTraintuple(computeTraintuple inputComputePlanTraintuple, failedIDs map[string]bool) bool {
+	for _, inModelID := range computeTraintuple.InModelsIDs {
+		if failedIDs[inModelID] {
+			return true
+		}
+	}
+	return false
+}
+
+// createComputePlanTraintuple checks and stores the traintuple of index i of a compute plan,
+// the traintuples it depends on being already stored
+func createComputePlanTraintuple(db LedgerDB, inp inputComputePlan, i int, computePlanID string, traintupleKeysByID map[string]string) (traintuple Traintuple, traintupleKey string, err error) {
+	computeTraintuple := inp.Traintuples[i]
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.AlgoKey = inp.AlgoKey
+	inpTraintuple.ObjectiveKey = inp.ObjectiveKey
+	inpTraintuple.DataManagerKey = computeTraintuple.DataManagerKey
+	inpTraintuple.DataSampleKeys = computeTraintuple.DataSampleKeys
+	inpTraintuple.Tag = computeTraintuple.Tag
+	inpTraintuple.Rank = strconv.Itoa(i)
+	inpTraintuple.Priority = inp.Priority
+
+	err = traintuple.SetFromInput(db, inpTraintuple)
 	if err != nil {
-		return resp, err
+		return
+	}
+	if inp.Timeout > 0 {
+		traintuple.Timeout = inp.Timeout
+	}
+	traintuple.SkipEvaluation = inp.SkipEvaluation
+
+	// Set the inModels by matching the id to traintuples key previously
+	// encontered in this compute plan
+	for _, InModelID := range computeTraintuple.InModelsIDs {
+		inModelKey, ok := traintupleKeysByID[InModelID]
+		if !ok {
+			err = errors.BadRequest("traintuple ID %s: model ID %s not found, check traintuple list order", computeTraintuple.ID, InModelID)
+			return
+		}
+		traintuple.InModelKeys = append(traintuple.InModelKeys, inModelKey)
 	}
 
-	return resp, err
+	traintupleKey = traintuple.GetKey()
+
+	// Set the ComputePlanID
+	if i == 0 {
+		traintuple.ComputePlanID = traintupleKey
+	} else {
+		traintuple.ComputePlanID = computePlanID
+	}
+
+	// Set status: if it has parents it's waiting
+	// if not it's todo
+	if len(computeTraintuple.InModelsIDs) > 0 {
+		traintuple.Status = StatusWaiting
+	} else {
+		traintuple.Status = StatusTodo
+	}
+
+	err = traintuple.Save(db, traintupleKey)
+	return
+}
+
+// createComputePlanTesttuple checks and stores the testtuple of a given index of a compute plan,
+// one per test dataset of the objective for a certified evaluation
+func createComputePlanTesttuple(db LedgerDB, inp inputComputePlan, index int, traintupleKeysByID map[string]string) ([]string, error) {
+	computeTesttuple := inp.Testtuples[index]
+	traintupleKey, ok := traintupleKeysByID[computeTesttuple.TraintupleID]
+	if !ok {
+		return nil, errors.BadRequest("testtuple index %d: traintuple ID %s not found", index, computeTesttuple.TraintupleID)
+	}
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	if err != nil {
+		return nil, err
+	}
+	testtuple := Testtuple{}
+	testtuple.Model = &Model{TraintupleKey: traintupleKey}
+	testtuple.ObjectiveKey = inp.ObjectiveKey
+	testtuple.AlgoKey = inp.AlgoKey
+	testtuple.Permissions = traintuple.Permissions
+	testtuple.Timeout = traintuple.Timeout
+
+	inputTesttuple := inputTesttuple{}
+	inputTesttuple.DataManagerKey = computeTesttuple.DataManagerKey
+	inputTesttuple.DataSampleKeys = computeTesttuple.DataSampleKeys
+	inputTesttuple.Tag = computeTesttuple.Tag
+	inputTesttuple.Priority = inp.Priority
+	err = testtuple.SetFromInput(db, inputTesttuple)
+	if err != nil {
+		return nil, err
+	}
+	testtuple.Status = StatusWaiting
+	if err = testtuple.checkChallenge(db); err != nil {
+		return nil, err
+	}
+	testtuples := []Testtuple{testtuple}
+	if computeTesttuple.DataManagerKey == "" {
+		if testtuples, err = testtuple.getEvaluation(db); err != nil {
+			return nil, err
+		}
+	}
+	testtupleKeys := []string{}
+	for _, testtuple := range testtuples {
+		testtupleKey := testtuple.GetKey()
+		err = testtuple.Save(db, testtupleKey)
+		if err != nil {
+			return nil, err
+		}
+		testtupleKeys = append(testtupleKeys, testtupleKey)
+	}
+	return testtupleKeys, nil
 }
 
 // createTraintuple adds a Traintuple in the ledger
 func createTraintuple(db LedgerDB, args []string) (map[string]string, error) {
 	inp := inputTraintuple{}
-	err := AssetFromJSON(args, &inp)
+	err := AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return nil, err
 	}
@@ -536,57 +891,96 @@ func createTraintuple(db LedgerDB, args []string) (map[string]string, error) {
 
 	event := TuplesEvent{}
 	event.SetTraintuples(out)
-	err = SendTuplesEvent(db.cc, event)
-	if err != nil {
-		return nil, err
-	}
+	db.AddTuplesEvent(event)
 
 	return map[string]string{"key": traintupleKey}, nil
 }
 
 // createTesttuple adds a Testtuple in the ledger
-func createTesttuple(db LedgerDB, args []string) (map[string]string, error) {
+func createTesttuple(db LedgerDB, args []string) (outputCreatedTesttuple, error) {
 	inp := inputTesttuple{}
-	err := AssetFromJSON(args, &inp)
+	err := AssetFromJSON(db, args, &inp)
 	if err != nil {
-		return nil, err
+		return outputCreatedTesttuple{}, err
 	}
 
 	// check validity of input arg and set testtuple
+	modelKey := inp.TraintupleKey
+	provided := 0
+	for _, isSet := range []bool{inp.TraintupleKey != "", inp.ModelKey != "", len(inp.TraintupleKeys) > 0} {
+		if isSet {
+			provided++
+		}
+	}
+	if provided != 1 {
+		return outputCreatedTesttuple{}, errors.BadRequest(errors.CodeInvalidInput, "invalid input: either traintupleKey, modelKey or traintupleKeys should be provided")
+	}
+	if inp.ModelKey != "" {
+		if _, err = db.GetExternalModel(inp.ModelKey); err != nil {
+			return outputCreatedTesttuple{}, errors.BadRequest(err, "could not retrieve model with key %s", inp.ModelKey)
+		}
+		modelKey = inp.ModelKey
+	}
 	testtuple := Testtuple{}
-	err = testtuple.SetFromTraintuple(db, inp.TraintupleKey)
+	if len(inp.TraintupleKeys) > 0 {
+		err = testtuple.SetFromTraintuples(db, inp.TraintupleKeys)
+	} else {
+		err = testtuple.SetFromTraintuple(db, modelKey)
+	}
 	if err != nil {
-		return nil, err
+		return outputCreatedTesttuple{}, err
 	}
-	err = testtuple.SetFromInput(db, inp)
+	err = testtuple.SetAlgo(db, inp.AlgoKey)
 	if err != nil {
-		return nil, err
+		return outputCreatedTesttuple{}, err
 	}
-	testtupleKey := testtuple.GetKey()
-	err = testtuple.Save(db, testtupleKey)
+	err = testtuple.SetFromInput(db, inp)
 	if err != nil {
-		return nil, err
+		return outputCreatedTesttuple{}, err
 	}
-	out := outputTesttuple{}
-	err = out.Fill(db, testtupleKey, testtuple)
+	err = testtuple.checkChallenge(db)
 	if err != nil {
-		return nil, err
+		return outputCreatedTesttuple{}, err
+	}
+	// a certified evaluation runs a testtuple on each test dataset of the objective
+	testtuples := []Testtuple{testtuple}
+	if inp.DataManagerKey == "" {
+		if testtuples, err = testtuple.getEvaluation(db); err != nil {
+			return outputCreatedTesttuple{}, err
+		}
+	}
+	resp := outputCreatedTesttuple{}
+	outs := []outputTesttuple{}
+	for _, testtuple := range testtuples {
+		testtupleKey := testtuple.GetKey()
+		err = testtuple.Save(db, testtupleKey)
+		if err != nil {
+			return outputCreatedTesttuple{}, err
+		}
+		out := outputTesttuple{}
+		err = out.Fill(db, testtupleKey, testtuple)
+		if err != nil {
+			return outputCreatedTesttuple{}, err
+		}
+		outs = append(outs, out)
+		resp.Keys = append(resp.Keys, testtupleKey)
+	}
+	resp.Key = resp.Keys[0]
+	if len(resp.Keys) == 1 {
+		resp.Keys = nil
 	}
 
 	event := TuplesEvent{}
-	event.SetTesttuples(out)
-	err = SendTuplesEvent(db.cc, event)
-	if err != nil {
-		return nil, err
-	}
+	event.SetTesttuples(outs...)
+	db.AddTuplesEvent(event)
 
-	return map[string]string{"key": testtupleKey}, nil
+	return resp, nil
 }
 
 // logStartTrain modifies a traintuple by changing its status from todo to doing
 func logStartTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -599,6 +993,10 @@ func logStartTrain(db LedgerDB, args []string) (outputTraintuple outputTraintupl
 	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
 		return
 	}
+	if traintuple.StartDate, err = getTxTimestamp(db); err != nil {
+		return
+	}
+	traintuple.Attempts++
 	if err = traintuple.commitStatusUpdate(db, inp.Key, StatusDoing); err != nil {
 		return
 	}
@@ -609,7 +1007,7 @@ func logStartTrain(db LedgerDB, args []string) (outputTraintuple outputTraintupl
 // logStartTest modifies a testtuple by changing its status from todo to doing
 func logStartTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -622,6 +1020,10 @@ func logStartTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple,
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	if testtuple.StartDate, err = getTxTimestamp(db); err != nil {
+		return
+	}
+	testtuple.Attempts++
 	if err = testtuple.commitStatusUpdate(db, inp.Key, StatusDoing); err != nil {
 		return
 	}
@@ -636,7 +1038,7 @@ func logStartTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple,
 // reports logs and associated performances
 func logSuccessTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple, err error) {
 	inp := inputLogSuccessTrain{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -647,13 +1049,21 @@ func logSuccessTrain(db LedgerDB, args []string) (outputTraintuple outputTraintu
 	if err != nil {
 		return
 	}
+	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+		return
+	}
 	traintuple.Perf = inp.Perf
+	storageAddressHash, err := db.PutPrivateField(modelAddressesCollection, traintupleKey, inp.OutModel.StorageAddress)
+	if err != nil {
+		return
+	}
 	traintuple.OutModel = &HashDress{
-		Hash:           inp.OutModel.Hash,
-		StorageAddress: inp.OutModel.StorageAddress}
-	traintuple.Log += inp.Log
-
-	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+		Hash:               inp.OutModel.Hash,
+		StorageAddressHash: storageAddressHash}
+	if traintuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, traintupleKey, traintuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if traintuple.FullLog, err = putFullLog(db, traintupleKey, inp.FullLog); err != nil {
 		return
 	}
 	if err = traintuple.commitStatusUpdate(db, traintupleKey, StatusDone); err != nil {
@@ -671,15 +1081,24 @@ func logSuccessTrain(db LedgerDB, args []string) (outputTraintuple outputTraintu
 		return
 	}
 
+	predicttuplesEvent, err := traintuple.updatePredicttupleChildren(db, traintupleKey)
+	if err != nil {
+		return
+	}
+
+	evaluationEvent, err := traintuple.autoEvaluate(db, traintupleKey)
+	if err != nil {
+		return
+	}
+	testtuplesEvent = append(testtuplesEvent, evaluationEvent...)
+
 	outputTraintuple.Fill(db, traintuple, inp.Key)
 
 	event := TuplesEvent{}
 	event.SetTraintuples(traintuplesEvent...)
 	event.SetTesttuples(testtuplesEvent...)
-	err = SendTuplesEvent(db.cc, event)
-	if err != nil {
-		return
-	}
+	event.SetPredicttuples(predicttuplesEvent...)
+	db.AddTuplesEvent(event)
 
 	return
 }
@@ -687,7 +1106,7 @@ func logSuccessTrain(db LedgerDB, args []string) (outputTraintuple outputTraintu
 // logSuccessTest modifies a testtuple by changing its status to done, reports perf and logs
 func logSuccessTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple, err error) {
 	inp := inputLogSuccessTest{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -697,12 +1116,31 @@ func logSuccessTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple
 		return
 	}
 
-	testtuple.Dataset.Perf = inp.Perf
-	testtuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	if testtuple.Certified {
+		if err = checkLeaderboardNotFrozen(db, testtuple.ObjectiveKey); err != nil {
+			return
+		}
+	}
+	testtuple.Dataset.Perf = inp.Perf
+	if len(testtuple.Dataset.PrivateDataSampleKeys) > 0 {
+		if inp.PrivatePerfSalt == "" {
+			err = errors.BadRequest("the private perf of testtuple %s must be salted with a privatePerfSalt", inp.Key)
+			return
+		}
+		testtuple.Dataset.PrivatePerfHash, err = db.PutPrivateField(privatePerfsCollection, inp.Key, formatPrivatePerf(inp.PrivatePerfSalt, inp.PrivatePerf))
+		if err != nil {
+			return
+		}
+	}
+	if testtuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, inp.Key, testtuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if testtuple.FullLog, err = putFullLog(db, inp.Key, inp.FullLog); err != nil {
+		return
+	}
 	if err = testtuple.commitStatusUpdate(db, inp.Key, StatusDone); err != nil {
 		return
 	}
@@ -713,7 +1151,7 @@ func logSuccessTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple
 // logFailTrain modifies a traintuple by changing its status to fail and reports associated logs
 func logFailTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple, err error) {
 	inp := inputLogFailTrain{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -723,11 +1161,16 @@ func logFailTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple
 	if err != nil {
 		return
 	}
-	traintuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
 		return
 	}
+	if traintuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, inp.Key, traintuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if traintuple.FullLog, err = putFullLog(db, inp.Key, inp.FullLog); err != nil {
+		return
+	}
+	traintuple.FailureReport = newFailureReport(inp.FailureReport)
 	if err = traintuple.commitStatusUpdate(db, inp.Key, StatusFailed); err != nil {
 		return
 	}
@@ -745,13 +1188,14 @@ func logFailTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple
 		return
 	}
 
+	if _, err = traintuple.updatePredicttupleChildren(db, inp.Key); err != nil {
+		return
+	}
+
 	event := TuplesEvent{}
 	event.SetTraintuples(traintuplesEvent...)
 	event.SetTesttuples(testtuplesEvent...)
-	err = SendTuplesEvent(db.cc, event)
-	if err != nil {
-		return
-	}
+	db.AddTuplesEvent(event)
 
 	return
 }
@@ -759,7 +1203,7 @@ func logFailTrain(db LedgerDB, args []string) (outputTraintuple outputTraintuple
 // logFailTest modifies a testtuple by changing its status to fail and reports associated logs
 func logFailTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple, err error) {
 	inp := inputLogFailTest{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -770,11 +1214,16 @@ func logFailTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple, e
 		return
 	}
 
-	testtuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	if testtuple.LogHash, err = db.AppendPrivateField(tupleLogsCollection, inp.Key, testtuple.LogHash, inp.Log); err != nil {
+		return
+	}
+	if testtuple.FullLog, err = putFullLog(db, inp.Key, inp.FullLog); err != nil {
+		return
+	}
+	testtuple.FailureReport = newFailureReport(inp.FailureReport)
 	if err = testtuple.commitStatusUpdate(db, inp.Key, StatusFailed); err != nil {
 		return
 	}
@@ -785,7 +1234,7 @@ func logFailTest(db LedgerDB, args []string) (outputTesttuple outputTesttuple, e
 // queryTraintuple returns info about a traintuple given its key
 func queryTraintuple(db LedgerDB, args []string) (outputTraintuple outputTraintuple, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -798,10 +1247,12 @@ func queryTraintuple(db LedgerDB, args []string) (outputTraintuple outputTraintu
 		return
 	}
 	outputTraintuple.Fill(db, traintuple, inp.Key)
+	err = outputTraintuple.fillPrivateFields(db)
 	return
 }
 
 // queryTraintuples returns all traintuples
+// Storage addresses of traintuples the requester cannot process are redacted
 func queryTraintuples(db LedgerDB, args []string) ([]outputTraintuple, error) {
 	outTraintuples := []outputTraintuple{}
 
@@ -809,12 +1260,16 @@ func queryTraintuples(db LedgerDB, args []string) ([]outputTraintuple, error) {
 		err := errors.BadRequest("incorrect number of arguments, expecting nothing")
 		return outTraintuples, err
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outTraintuples, err
+	}
 	elementsKeys, err := db.GetIndexKeys("traintuple~algo~key", []string{"traintuple"})
 	if err != nil {
 		return outTraintuples, err
 	}
 	for _, key := range elementsKeys {
-		outputTraintuple, err := getOutputTraintuple(db, key)
+		outputTraintuple, err := getOutputTraintupleFor(db, key, requester)
 		if err != nil {
 			return outTraintuples, err
 		}
@@ -826,7 +1281,7 @@ func queryTraintuples(db LedgerDB, args []string) ([]outputTraintuple, error) {
 // queryTesttuple returns a testtuple of the ledger given its key
 func queryTesttuple(db LedgerDB, args []string) (out outputTesttuple, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -838,11 +1293,15 @@ func queryTesttuple(db LedgerDB, args []string) (out outputTesttuple, err error)
 		err = errors.NotFound("no element with key %s", inp.Key)
 		return
 	}
-	err = out.Fill(db, inp.Key, testtuple)
+	if err = out.Fill(db, inp.Key, testtuple); err != nil {
+		return
+	}
+	err = out.fillPrivateFields(db)
 	return
 }
 
 // queryTesttuples returns all testtuples of the ledger
+// Storage addresses of testtuples the requester cannot process are redacted
 func queryTesttuples(db LedgerDB, args []string) ([]outputTesttuple, error) {
 	outTesttuples := []outputTesttuple{}
 
@@ -850,13 +1309,17 @@ func queryTesttuples(db LedgerDB, args []string) ([]outputTesttuple, error) {
 		err := errors.BadRequest("incorrect number of arguments, expecting nothing")
 		return outTesttuples, err
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return outTesttuples, err
+	}
 	elementsKeys, err := db.GetIndexKeys("testtuple~traintuple~certified~key", []string{"testtuple"})
 	if err != nil {
 		return outTesttuples, err
 	}
 	for _, key := range elementsKeys {
 		var out outputTesttuple
-		out, err = getOutputTesttuple(db, key)
+		out, err = getOutputTesttupleFor(db, key, requester)
 		if err != nil {
 			return outTesttuples, err
 		}
@@ -868,7 +1331,7 @@ func queryTesttuples(db LedgerDB, args []string) ([]outputTesttuple, error) {
 // queryModelDetails returns info about the testtuple and algo related to a traintuple
 func queryModelDetails(db LedgerDB, args []string) (outModelDetails outputModelDetails, err error) {
 	inp := inputHash{}
-	err = AssetFromJSON(args, &inp)
+	err = AssetFromJSON(db, args, &inp)
 	if err != nil {
 		return
 	}
@@ -880,6 +1343,7 @@ func queryModelDetails(db LedgerDB, args []string) (outModelDetails outputModelD
 	}
 
 	// get certified and non-certified testtuples related to traintuple
+	outModelDetails.Testtuples = []outputTesttuple{}
 	testtupleKeys, err := db.GetIndexKeys("testtuple~traintuple~certified~key", []string{"testtuple", inp.Key})
 	if err != nil {
 		return
@@ -893,15 +1357,42 @@ func queryModelDetails(db LedgerDB, args []string) (outModelDetails outputModelD
 		}
 
 		if outputTesttuple.Certified {
-			outModelDetails.Testtuple = outputTesttuple
+			outModelDetails.Testtuples = append(outModelDetails.Testtuples, outputTesttuple)
 		} else {
 			outModelDetails.NonCertifiedTesttuples = append(outModelDetails.NonCertifiedTesttuples, outputTesttuple)
 		}
 	}
+	if err = sortCertifiedTesttuples(db, outModelDetails.Testtuples); err != nil {
+		return
+	}
+	if len(outModelDetails.Testtuples) > 0 {
+		outModelDetails.Testtuple = outModelDetails.Testtuples[0]
+	}
 	return
 }
 
+// sortCertifiedTesttuples orders the certified testtuples of a model like the test datasets
+// of their objective, so that the first one is evaluated on the objective test dataset
+func sortCertifiedTesttuples(db LedgerDB, testtuples []outputTesttuple) error {
+	if len(testtuples) < 2 {
+		return nil
+	}
+	objective, err := db.GetObjective(testtuples[0].Objective.Key)
+	if err != nil {
+		return err
+	}
+	positions := map[string]int{}
+	for i, dataset := range objective.getTestDatasets() {
+		positions[dataset.DataManagerKey] = i
+	}
+	sort.SliceStable(testtuples, func(i, j int) bool {
+		return positions[testtuples[i].Dataset.OpenerHash] < positions[testtuples[j].Dataset.OpenerHash]
+	})
+	return nil
+}
+
 // queryModels returns all traintuples and associated testuples
+// Storage addresses of tuples the requester cannot process are redacted
 func queryModels(db LedgerDB, args []string) (outModels []outputModel, err error) {
 	outModels = []outputModel{}
 
@@ -909,6 +1400,10 @@ func queryModels(db LedgerDB, args []string) (outModels []outputModel, err error
 		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
 		return
 	}
+	requester, err := GetTxCreator(db)
+	if err != nil {
+		return
+	}
 
 	traintupleKeys, err := db.GetIndexKeys("traintuple~algo~key", []string{"traintuple"})
 	if err != nil {
@@ -918,24 +1413,31 @@ func queryModels(db LedgerDB, args []string) (outModels []outputModel, err error
 		var outputModel outputModel
 
 		// get traintuple
-		outputModel.Traintuple, err = getOutputTraintuple(db, traintupleKey)
+		outputModel.Traintuple, err = getOutputTraintupleFor(db, traintupleKey, requester)
 		if err != nil {
 			return
 		}
 
-		// get associated testtuple
+		// get associated certified testtuples, one per test dataset of the objective
 		var testtupleKeys []string
 		testtupleKeys, err = db.GetIndexKeys("testtuple~traintuple~certified~key", []string{"testtuple", traintupleKey, "true"})
 		if err != nil {
 			return
 		}
-		if len(testtupleKeys) == 1 {
-			// get testtuple and serialize it
-			testtupleKey := testtupleKeys[0]
-			outputModel.Testtuple, err = getOutputTesttuple(db, testtupleKey)
+		outputModel.Testtuples = []outputTesttuple{}
+		for _, testtupleKey := range testtupleKeys {
+			var outputTesttuple outputTesttuple
+			outputTesttuple, err = getOutputTesttupleFor(db, testtupleKey, requester)
 			if err != nil {
 				return
 			}
+			outputModel.Testtuples = append(outputModel.Testtuples, outputTesttuple)
+		}
+		if err = sortCertifiedTesttuples(db, outputModel.Testtuples); err != nil {
+			return
+		}
+		if len(outputModel.Testtuples) > 0 {
+			outputModel.Testtuple = outputModel.Testtuples[0]
 		}
 		outModels = append(outModels, outputModel)
 	}
@@ -946,13 +1448,32 @@ func queryModels(db LedgerDB, args []string) (outModels []outputModel, err error
 // Utils for smartcontracts related to traintuples and testtuples
 // --------------------------------------------------------------
 
-// getOutputTraintuple takes as input a traintuple key and returns the outputTraintuple
+// getOutputTraintuple takes as input a traintuple key and returns the outputTraintuple,
+// with its private fields if the requester is a member of their collections
 func getOutputTraintuple(db LedgerDB, traintupleKey string) (outTraintuple outputTraintuple, err error) {
 	traintuple, err := db.GetTraintuple(traintupleKey)
 	if err != nil {
 		return
 	}
 	outTraintuple.Fill(db, traintuple, traintupleKey)
+	err = outTraintuple.fillPrivateFields(db)
+	return
+}
+
+// getOutputTraintupleFor takes as input a traintuple key and returns the outputTraintuple,
+// redacted if the requester is not allowed to process the traintuple
+func getOutputTraintupleFor(db LedgerDB, traintupleKey string, requester string) (outTraintuple outputTraintuple, err error) {
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	if err != nil {
+		return
+	}
+	outTraintuple.Fill(db, traintuple, traintupleKey)
+	if err = outTraintuple.fillPrivateFields(db); err != nil {
+		return
+	}
+	if !traintuple.Permissions.CanProcess(traintuple.Creator, requester) {
+		outTraintuple.redact()
+	}
 	return
 }
 
@@ -969,13 +1490,36 @@ func getOutputTraintuples(db LedgerDB, traintupleKeys []string) (outTraintuples
 	return
 }
 
-// getOutputTesttuple takes as input a testtuple key and returns the outputTesttuple
+// getOutputTesttuple takes as input a testtuple key and returns the outputTesttuple,
+// with its private fields if the requester is a member of their collections
 func getOutputTesttuple(db LedgerDB, testtupleKey string) (outTesttuple outputTesttuple, err error) {
 	testtuple, err := db.GetTesttuple(testtupleKey)
 	if err != nil {
 		return
 	}
-	err = outTesttuple.Fill(db, testtupleKey, testtuple)
+	if err = outTesttuple.Fill(db, testtupleKey, testtuple); err != nil {
+		return
+	}
+	err = outTesttuple.fillPrivateFields(db)
+	return
+}
+
+// getOutputTesttupleFor takes as input a testtuple key and returns the outputTesttuple,
+// redacted if the requester is not allowed to process the testtuple
+func getOutputTesttupleFor(db LedgerDB, testtupleKey string, requester string) (outTesttuple outputTesttuple, err error) {
+	testtuple, err := db.GetTesttuple(testtupleKey)
+	if err != nil {
+		return
+	}
+	if err = outTesttuple.Fill(db, testtupleKey, testtuple); err != nil {
+		return
+	}
+	if err = outTesttuple.fillPrivateFields(db); err != nil {
+		return
+	}
+	if !testtuple.Permissions.CanProcess(testtuple.Creator, requester) {
+		outTesttuple.redact()
+	}
 	return
 }
 
@@ -992,22 +1536,44 @@ func getOutputTesttuples(db LedgerDB, testtupleKeys []string) (outTesttuples []o
 	return
 }
 
-// checkLog checks the validity of logs
-func checkLog(log string) (err error) {
-	maxLength := 200
-	if length := len(log); length > maxLength {
-		err = fmt.Errorf("too long log, is %d and should be %d ", length, maxLength)
+// fullLogKey returns the key of the storage address of a tuple full log in the private
+// data collection of the logs
+func fullLogKey(tupleKey string) string {
+	return tupleKey + "~fullLog"
+}
+
+// putFullLog returns the reference of the full log of a tuple, if any, its storage address
+// being stored in the private data collection of the logs
+func putFullLog(db LedgerDB, tupleKey string, inp *inputHashDress) (*HashDress, error) {
+	if inp == nil {
+		return nil, nil
+	}
+	storageAddressHash, err := db.PutPrivateField(tupleLogsCollection, fullLogKey(tupleKey), inp.StorageAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &HashDress{Hash: inp.Hash, StorageAddressHash: storageAddressHash}, nil
+}
+
+// newFailureReport returns the failure report of a tuple, if any
+func newFailureReport(inp *inputFailureReport) *FailureReport {
+	if inp == nil {
+		return nil
+	}
+	return &FailureReport{
+		ErrorType: inp.ErrorType,
+		Stage:     inp.Stage,
+		Retryable: inp.Retryable,
 	}
-	return
 }
 
 func validateTupleOwner(db LedgerDB, worker string) error {
-	txCreator, err := GetTxCreator(db.cc)
+	txCreator, err := GetTxCreator(db)
 	if err != nil {
 		return err
 	}
 	if txCreator != worker {
-		return fmt.Errorf("%s is not allowed to update tuple (%s)", txCreator, worker)
+		return errors.Forbidden(errors.CodePermissionDeniedTupleUpdate, "%s is not allowed to update tuple (%s)", txCreator, worker)
 	}
 	return nil
 }
@@ -1019,7 +1585,7 @@ func checkUpdateTuple(db LedgerDB, worker string, oldStatus string, newStatus st
 		StatusTodo:    StatusDoing,
 		StatusDoing:   StatusDone}
 	if statusPossibilities[oldStatus] != newStatus && newStatus != StatusFailed {
-		return errors.BadRequest("cannot change status from %s to %s", oldStatus, newStatus)
+		return errors.BadRequest(errors.CodeTupleInvalidStatusTransition, "cannot change status from %s to %s", oldStatus, newStatus)
 	}
 	return nil
 }
@@ -1080,7 +1646,7 @@ func (traintuple *Traintuple) updateTraintupleChildren(db LedgerDB, traintupleKe
 		if traintuple.Status == StatusFailed {
 			newStatus = StatusFailed
 		} else if traintuple.Status == StatusDone {
-			ready, err := childTraintuple.isReady(db, traintupleKey)
+			ready, err := childTraintuple.isReady(db)
 			if err != nil {
 				return otuples, err
 			}
@@ -1108,19 +1674,14 @@ func (traintuple *Traintuple) updateTraintupleChildren(db LedgerDB, traintupleKe
 	return otuples, nil
 }
 
-// isReady checks if inModels of a traintuple have been trained, except the newDoneTraintupleKey (since the transaction is not commited)
-// and updates the traintuple status if necessary
-func (traintuple *Traintuple) isReady(db LedgerDB, newDoneTraintupleKey string) (ready bool, err error) {
+// isReady checks if inModels of a traintuple have been trained
+func (traintuple *Traintuple) isReady(db LedgerDB) (ready bool, err error) {
 	for _, key := range traintuple.InModelKeys {
-		// don't check newly done traintuple
-		if key == newDoneTraintupleKey {
-			continue
-		}
-		tt, err := db.GetTraintuple(key)
+		parent, err := getParentModel(db, key)
 		if err != nil {
 			return false, err
 		}
-		if tt.Status != StatusDone {
+		if parent.Status != StatusDone {
 			return false, nil
 		}
 	}
@@ -1130,13 +1691,18 @@ func (traintuple *Traintuple) isReady(db LedgerDB, newDoneTraintupleKey string)
 // commitStatusUpdate update the traintuple status in the ledger
 func (traintuple *Traintuple) commitStatusUpdate(db LedgerDB, traintupleKey string, newStatus string) error {
 	if traintuple.Status == newStatus {
-		return fmt.Errorf("cannot update traintuple %s - status already %s", traintupleKey, newStatus)
+		return errors.BadRequest(errors.CodeTupleInvalidStatusTransition, "cannot update traintuple %s - status already %s", traintupleKey, newStatus)
 	}
 
 	if err := traintuple.validateNewStatus(db, newStatus); err != nil {
-		return fmt.Errorf("update traintuple %s failed: %s", traintupleKey, err.Error())
+		return errors.BadRequest(err, "update traintuple %s failed:", traintupleKey)
 	}
+	return traintuple.saveStatus(db, traintupleKey, newStatus)
+}
 
+// saveStatus stores the new status of a traintuple and updates its index, the status
+// transition being already checked
+func (traintuple *Traintuple) saveStatus(db LedgerDB, traintupleKey string, newStatus string) error {
 	oldStatus := traintuple.Status
 	traintuple.Status = newStatus
 	if err := db.Put(traintupleKey, traintuple); err != nil {
@@ -1174,19 +1740,32 @@ func (traintuple *Traintuple) updateTesttupleChildren(db LedgerDB, traintupleKey
 	if err != nil {
 		return otuples, err
 	}
+	// and the ensemble testtuples it is a member of
+	memberKeys, err := db.GetIndexKeys("testtuple~member~key", []string{"testtuple", traintupleKey})
+	if err != nil {
+		return otuples, err
+	}
+	testtupleKeys = append(testtupleKeys, memberKeys...)
 	for _, testtupleKey := range testtupleKeys {
 		// get and update testtuple
 		testtuple, err := db.GetTesttuple(testtupleKey)
 		if err != nil {
 			return otuples, err
 		}
-		testtuple.Model = &Model{
-			TraintupleKey: traintupleKey,
+		// an ensemble testtuple may have already failed with another of its traintuples
+		if testtuple.Status != StatusWaiting {
+			continue
 		}
 
 		if newStatus == StatusTodo {
-			testtuple.Model.Hash = traintuple.OutModel.Hash
-			testtuple.Model.StorageAddress = traintuple.OutModel.StorageAddress
+			testtuple.setModelHash(traintupleKey, traintuple.OutModel.Hash)
+			// an ensemble testtuple still waits for its other traintuples
+			if !testtuple.hasAllModels() {
+				if err := db.Put(testtupleKey, testtuple); err != nil {
+					return otuples, err
+				}
+				continue
+			}
 		}
 
 		if err := testtuple.commitStatusUpdate(db, testtupleKey, newStatus); err != nil {
@@ -1205,12 +1784,86 @@ func (traintuple *Traintuple) updateTesttupleChildren(db LedgerDB, traintupleKey
 	return otuples, nil
 }
 
+// autoEvaluate creates the certified testtuples of a done traintuple whose objective is evaluated
+// automatically, unless its compute plan or its tag opted out. The testtuples are created on behalf
+// of the creator of the traintuple, and the ones it already created are kept.
+func (traintuple *Traintuple) autoEvaluate(db LedgerDB, traintupleKey string) ([]outputTesttuple, error) {
+	otuples := []outputTesttuple{}
+	if traintuple.SkipEvaluation || traintuple.ObjectiveKey == "" {
+		return otuples, nil
+	}
+	objective, err := db.GetObjective(traintuple.ObjectiveKey)
+	if err != nil {
+		return otuples, err
+	}
+	testDatasets := objective.getTestDatasets()
+	if !objective.AutoEvaluate || len(testDatasets) == 0 || stringInSlice(traintuple.Tag, objective.SkipEvaluationTags) {
+		return otuples, nil
+	}
+
+	testtuple := Testtuple{
+		AssetType:    TesttupleType,
+		AlgoKey:      traintuple.AlgoKey,
+		Certified:    true,
+		Creator:      traintuple.Creator,
+		Model:        &Model{TraintupleKey: traintupleKey, Hash: traintuple.OutModel.Hash},
+		ObjectiveKey: traintuple.ObjectiveKey,
+		Permissions:  traintuple.Permissions,
+		Priority:     traintuple.Priority,
+		Status:       StatusTodo,
+		Tag:          traintuple.Tag,
+		Timeout:      traintuple.Timeout,
+	}
+	if testtuple.CreationDate, err = getTxTimestamp(db); err != nil {
+		return otuples, err
+	}
+	if err = testtuple.SetTestDataset(db, testDatasets[0]); err != nil {
+		return otuples, err
+	}
+	// the traintuples done outside the rules of the challenge of the objective are not evaluated
+	if err = objective.checkChallenge(db, traintuple.ObjectiveKey, traintuple.Creator); err != nil {
+		switch errors.Wrap(err).Code() {
+		case errors.CodeChallengeClosed, errors.CodeChallengeQuotaExceeded:
+			return otuples, nil
+		}
+		return otuples, err
+	}
+	testtuples, err := testtuple.getEvaluation(db)
+	if err != nil {
+		return otuples, err
+	}
+	for _, testtuple := range testtuples {
+		testtupleKey := testtuple.GetKey()
+		exists, err := db.KeyExists(testtupleKey)
+		if err != nil {
+			return otuples, err
+		}
+		if exists {
+			continue
+		}
+		if err = testtuple.Save(db, testtupleKey); err != nil {
+			return otuples, err
+		}
+		out := outputTesttuple{}
+		if err = out.Fill(db, testtupleKey, testtuple); err != nil {
+			return otuples, err
+		}
+		otuples = append(otuples, out)
+	}
+	return otuples, nil
+}
+
 // commitStatusUpdate update the testtuple status in the ledger
 func (testtuple *Testtuple) commitStatusUpdate(db LedgerDB, testtupleKey string, newStatus string) error {
 	if err := testtuple.validateNewStatus(db, newStatus); err != nil {
-		return fmt.Errorf("update testtuple %s failed: %s", testtupleKey, err.Error())
+		return errors.BadRequest(err, "update testtuple %s failed:", testtupleKey)
 	}
+	return testtuple.saveStatus(db, testtupleKey, newStatus)
+}
 
+// saveStatus stores the new status of a testtuple and updates its index, the status
+// transition being already checked
+func (testtuple *Testtuple) saveStatus(db LedgerDB, testtupleKey string, newStatus string) error {
 	oldStatus := testtuple.Status
 	testtuple.Status = newStatus
 
@@ -41,7 +41,15 @@ func (stub *myMockStub) PutState(key string, value []byte) error {
 		stub.writtenState[key] = value
 		return nil
 	}
-	return stub.PutState(key, value)
+	return stub.MockStub.PutState(key, value)
+}
+
+// DelState stores the deletion as a nil value in the written state
+func (stub *myMockStub) DelState(key string) error {
+	if !stub.saveWhenWriting {
+		return stub.PutState(key, nil)
+	}
+	return stub.MockStub.DelState(key)
 }
 
 func (stub *myMockStub) saveWrittenState(t *testing.T) {
@@ -49,7 +57,12 @@ func (stub *myMockStub) saveWrittenState(t *testing.T) {
 		return
 	}
 	for k, v := range stub.writtenState {
-		err := stub.MockStub.PutState(k, v)
+		var err error
+		if v == nil {
+			err = stub.MockStub.DelState(k)
+		} else {
+			err = stub.MockStub.PutState(k, v)
+		}
 		if err != nil {
 			t.Fatalf("unable to `PutState` in saveWrittenState %s", err)
 		}
@@ -441,6 +454,44 @@ func TestTesttupleOnFailedTraintuple(t *testing.T) {
 	assert.Contains(t, resp.Message, "could not register this testtuple")
 }
 
+func TestLogFailureReport(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	// The failure report is validated
+	fail := inputLogFailTrain{FailureReport: &inputFailureReport{ErrorType: "unknown", Stage: "train"}}
+	fail.Key = traintupleKey
+	resp := mockStub.MockInvoke("42", fail.createDefault())
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+
+	// The storage address of the full log must be passed in the transient map
+	fail.FailureReport = &inputFailureReport{ErrorType: "resources", Stage: "train", Retryable: true}
+	fail.FullLog = &inputHashDress{Hash: fullLogHash, StorageAddress: fullLogStorageAddress}
+	payload, err := json.Marshal(fail)
+	require.NoError(t, err)
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("logFailTrain"), payload})
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "fullLog.storageAddress must be passed in the transient map")
+
+	resp = mockStub.MockInvoke("42", fail.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	// The queries return the failure report and the full log with the short log
+	resp = mockStub.MockInvoke("42", [][]byte{[]byte("queryTraintuple"), keyToJSON(traintupleKey)})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	traintuple := outputTraintuple{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &traintuple))
+	assert.Equal(t, fail.Log, traintuple.Log)
+	assert.Equal(t, &FailureReport{ErrorType: "resources", Stage: "train", Retryable: true}, traintuple.FailureReport)
+	assert.Equal(t, &HashDress{
+		Hash:               fullLogHash,
+		StorageAddress:     fullLogStorageAddress,
+		StorageAddressHash: hashPrivateField(fullLogStorageAddress),
+	}, traintuple.FullLog)
+	assert.NotContains(t, string(mockStub.State[traintupleKey]), fullLogStorageAddress)
+}
+
 func TestCertifiedExplicitTesttuple(t *testing.T) {
 	scc := new(SubstraChaincode)
 	mockStub := NewMockStubWithRegisterNode("substra", scc)
@@ -564,6 +615,9 @@ func TestTraintuple(t *testing.T) {
 		},
 		Status: StatusTodo,
 	}
+	// the creation date is the time of the transaction
+	assert.NotEmpty(t, out.CreationDate)
+	expected.CreationDate = out.CreationDate
 	assert.Exactly(t, expected, out, "the traintuple queried from the ledger differ from expected")
 
 	// Query all traintuples and check consistency
@@ -632,10 +686,14 @@ func TestTraintuple(t *testing.T) {
 	assert.NoError(t, json.Unmarshal(resp.Payload, &endTraintuple))
 	expected.Dataset.Perf = success.Perf
 	expected.Log = success.Log
+	expected.LogHash = hashPrivateField(success.Log)
 	expected.OutModel = &HashDress{
-		Hash:           modelHash,
-		StorageAddress: modelAddress}
+		Hash:               modelHash,
+		StorageAddress:     modelAddress,
+		StorageAddressHash: hashPrivateField(modelAddress)}
 	expected.Status = traintupleStatus[1]
+	expected.Attempts = 1
+	expected.StartDate = endTraintuple.StartDate
 	assert.Exactly(t, expected, endTraintuple, "retreived Traintuple does not correspond to what is expected")
 
 	// query all traintuples related to a traintuple with the same algo
@@ -699,3 +757,123 @@ func TestInsertTraintupleTwice(t *testing.T) {
 	assert.EqualValues(t, http.StatusConflict, resp.Status)
 
 }
+
+func TestEnsembleTesttuple(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	invoke := func(fn string, inp interface{}) (int32, string, []byte) {
+		resp := mockStub.MockInvoke("42", [][]byte{[]byte(fn), assetToJSON(inp)})
+		return resp.Status, resp.Message, resp.Payload
+	}
+	createKey := func(fn string, inp interface{}) string {
+		status, message, payload := invoke(fn, inp)
+		require.EqualValues(t, 200, status, message)
+		res := map[string]string{}
+		require.NoError(t, json.Unmarshal(payload, &res))
+		return res["key"]
+	}
+	queryTesttuple := func(key string) outputTesttuple {
+		status, message, payload := invoke("queryTesttuple", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		out := outputTesttuple{}
+		require.NoError(t, json.Unmarshal(payload, &out))
+		return out
+	}
+	logTrain := func(key string, success bool) {
+		status, message, _ := invoke("logStartTrain", inputHash{key})
+		require.EqualValues(t, 200, status, message)
+		if success {
+			inp := inputLogSuccessTrain{}
+			inp.Key = key
+			inp.createDefault()
+			status, message, _ = invoke("logSuccessTrain", inp)
+		} else {
+			inp := inputLogFailTrain{}
+			inp.Key = key
+			status, message, _ = invoke("logFailTrain", inp)
+		}
+		require.EqualValues(t, 200, status, message)
+	}
+
+	// Three traintuples of the same algo and objective
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	firstKey := createKey("createTraintuple", inpTraintuple)
+	inpTraintuple.DataSampleKeys = []string{trainDataSampleHash1}
+	secondKey := createKey("createTraintuple", inpTraintuple)
+	inpTraintuple.DataSampleKeys = []string{trainDataSampleHash2}
+	failedKey := createKey("createTraintuple", inpTraintuple)
+
+	status, message, _ := invoke("createTesttuple", inputTesttuple{TraintupleKeys: []string{firstKey}})
+	assert.EqualValues(t, 400, status, "an ensemble has several models: %s", message)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKey: firstKey, TraintupleKeys: []string{firstKey, secondKey}})
+	assert.EqualValues(t, 400, status, message)
+
+	// The ensembles wait for all their traintuples
+	ensembleKey := createKey("createTesttuple", inputTesttuple{TraintupleKeys: []string{firstKey, secondKey}})
+	failedEnsembleKey := createKey("createTesttuple", inputTesttuple{TraintupleKeys: []string{firstKey, failedKey}})
+	out := queryTesttuple(ensembleKey)
+	assert.Equal(t, StatusWaiting, out.Status)
+	assert.True(t, out.Certified)
+	assert.Equal(t, []*Model{{TraintupleKey: firstKey}, {TraintupleKey: secondKey}}, out.Models)
+	assert.Equal(t, firstKey, out.Model.TraintupleKey)
+
+	logTrain(firstKey, true)
+	out = queryTesttuple(ensembleKey)
+	assert.Equal(t, StatusWaiting, out.Status)
+	assert.Equal(t, modelHash, out.Models[0].Hash)
+	assert.Equal(t, "", out.Models[1].Hash)
+
+	logTrain(secondKey, true)
+	event := getLastEvent(t, mockStub)
+	require.Len(t, event.Testtuples, 1)
+	assert.Equal(t, ensembleKey, event.Testtuples[0].Key)
+	assert.Equal(t, StatusTodo, event.Testtuples[0].Status)
+	require.Len(t, event.Testtuples[0].Models, 2)
+	for _, model := range event.Testtuples[0].Models {
+		assert.Equal(t, modelHash, model.Hash)
+	}
+	out = queryTesttuple(ensembleKey)
+	assert.Equal(t, modelAddress, out.Models[1].StorageAddress)
+
+	// An ensemble fails with any of its traintuples, and cannot be created on a failed one
+	logTrain(failedKey, false)
+	assert.Equal(t, StatusFailed, queryTesttuple(failedEnsembleKey).Status)
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKeys: []string{secondKey, failedKey}})
+	assert.EqualValues(t, 400, status, message)
+
+	// The models of an ensemble must share the same algo
+	otherAlgoHash := "fd2bb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482dcc"
+	inpAlgo := inputAlgo{Name: "baseline", Hash: otherAlgoHash}
+	resp := mockStub.MockInvoke("42", inpAlgo.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	createKey("registerModel", inputModel{
+		Name:           "pre-trained svm",
+		Hash:           externalModelHash,
+		StorageAddress: "https://toto/model/42/model",
+		AlgoKey:        otherAlgoHash,
+		Permissions:    OpenPermissions,
+	})
+	status, message, _ = invoke("createTesttuple", inputTesttuple{TraintupleKeys: []string{firstKey, externalModelHash}})
+	assert.EqualValues(t, 400, status, message)
+
+	// The leaderboard shows the ensemble and its members
+	status, message, _ = invoke("logStartTest", inputHash{ensembleKey})
+	require.EqualValues(t, 200, status, message)
+	success := inputLogSuccessTest{}
+	success.Key = ensembleKey
+	success.createDefault()
+	status, message, _ = invoke("logSuccessTest", success)
+	require.EqualValues(t, 200, status, message)
+	status, message, payload := invoke("queryObjectiveLeaderboard", inputLeaderboard{ObjectiveKey: objectiveDescriptionHash})
+	require.EqualValues(t, 200, status, message)
+	leaderboard := outputLeaderboard{}
+	require.NoError(t, json.Unmarshal(payload, &leaderboard))
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, ensembleKey, leaderboard.Testtuples[0].Key)
+	require.Len(t, leaderboard.Testtuples[0].Models, 2)
+	assert.Equal(t, secondKey, leaderboard.Testtuples[0].Models[1].TraintupleKey)
+	assert.Equal(t, modelAddress, leaderboard.Testtuples[0].Models[1].StorageAddress)
+}
@@ -16,12 +16,14 @@ package main
 
 import (
 	"chaincode/errors"
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric/protos/msp"
 	"gopkg.in/go-playground/validator.v9"
 )
@@ -71,31 +73,43 @@ func checkHashes(hashes []string) (err error) {
 }
 
 // AssetFromJSON unmarshal a stringify json into the passed interface
-func AssetFromJSON(args []string, asset interface{}) error {
+func AssetFromJSON(db LedgerDB, args []string, asset interface{}) error {
 	if len(args) != 1 {
-		return errors.BadRequest("arguments should only contains 1 json string, received: %s", args)
+		return errors.BadRequest(errors.CodeInvalidInput, "arguments should only contains 1 json string, received: %s", args)
 	}
 	arg := args[0]
 	err := json.Unmarshal([]byte(arg), &asset)
 	if err != nil {
-		return errors.BadRequest(err, "problem when reading json arg: %s, error is:", arg)
+		return errors.BadRequest(err, errors.CodeInvalidJSON, "problem when reading json arg: %s, error is:", arg)
 	}
-	v := validator.New()
-	err = v.Struct(asset)
+	if err := transientInput(db, asset); err != nil {
+		return err
+	}
+	// Some validation rules depend on the configuration of the channel
+	config, err := db.GetConfig()
 	if err != nil {
-		return errors.BadRequest(err, "inputs validation failed: %s, error is:", arg)
+		return err
+	}
+	ctx := context.WithValue(context.Background(), configContextKey{}, config)
+	err = validate.StructCtx(ctx, asset)
+	if err != nil {
+		e := errors.BadRequest(err, errors.CodeInvalidInput, "inputs validation failed: %s, error is:", arg)
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			e = e.WithFields(fieldErrors(validationErrors, getTranslator(defaultLocale)))
+		}
+		return e
 	}
 	return nil
 }
 
-// SendTuplesEvent sends an event with updated traintuples and testtuples
+// SendTuplesEvent sends an event with updated traintuples, testtuples and assets
 // Only one event can be sent per transaction
-func SendTuplesEvent(stub shim.ChaincodeStubInterface, event interface{}) error {
+func SendTuplesEvent(ctx TransactionContext, event interface{}) error {
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
-	err = stub.SetEvent("tuples-updated", payload)
+	err = ctx.SetEvent("tuples-updated", payload)
 	if err != nil {
 		return err
 	}
@@ -103,8 +117,8 @@ func SendTuplesEvent(stub shim.ChaincodeStubInterface, event interface{}) error
 }
 
 // GetTxCreator returns the transaction creator
-func GetTxCreator(stub shim.ChaincodeStubInterface) (string, error) {
-	creator, err := stub.GetCreator()
+func GetTxCreator(ctx TransactionContext) (string, error) {
+	creator, err := ctx.GetCreator()
 
 	if err != nil {
 		return "", err
@@ -118,3 +132,25 @@ func GetTxCreator(stub shim.ChaincodeStubInterface) (string, error) {
 
 	return sID.GetMspid(), nil
 }
+
+// getTxTime returns the time of the transaction
+func getTxTime(db LedgerDB) (time.Time, error) {
+	txTimestamp, err := db.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errors.Internal(err, "cannot get the transaction timestamp:")
+	}
+	t, err := ptypes.Timestamp(txTimestamp)
+	if err != nil {
+		return time.Time{}, errors.Internal(err, "invalid transaction timestamp:")
+	}
+	return t.UTC(), nil
+}
+
+// getTxTimestamp returns the time of the transaction in RFC 3339 format
+func getTxTimestamp(db LedgerDB) (string, error) {
+	t, err := getTxTime(db)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(time.RFC3339), nil
+}